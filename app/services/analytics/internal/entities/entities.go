@@ -3,6 +3,7 @@
 package entities
 
 import (
+	"errors"
 	"time"
 
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
@@ -21,3 +22,55 @@ type ProductFullUpdate struct {
 	Product   *myproto.ProductAction `json:"product"`
 	Timestamp time.Time              `json:"timestamp"`
 }
+
+// тип события о продукте, у старых сообщений без actionType тип определяется по строковому action
+func ProductActionType(product *myproto.ProductAction) myproto.Action {
+	if product.ActionType != myproto.Action_ACTION_UNSPECIFIED {
+		return product.ActionType
+	}
+
+	switch product.Action {
+	case "add":
+		return myproto.Action_CREATED
+	case "update":
+		return myproto.Action_UPDATED
+	case "delete":
+		return myproto.Action_DELETED
+	case "snapshot":
+		return myproto.Action_SNAPSHOT
+	}
+
+	return myproto.Action_ACTION_UNSPECIFIED
+}
+
+// вес сигнала по типу, если продюсер не задал вес
+var interactionWeights = map[myproto.InteractionType]float64{
+	myproto.InteractionType_VIEW:     1,
+	myproto.InteractionType_CLICK:    2,
+	myproto.InteractionType_PURCHASE: 5,
+}
+
+func ValidateInteraction(interaction *myproto.Interaction) error {
+
+	if interaction.UserId <= 0 || interaction.ProductId <= 0 {
+		return errors.New("invalid interaction: user id and product id must be greater than 0")
+	}
+
+	if _, ok := interactionWeights[interaction.Type]; !ok {
+		return errors.New("invalid interaction: unknown type " + interaction.Type.String())
+	}
+
+	if interaction.Weight < 0 {
+		return errors.New("invalid interaction: weight can`t be less than 0")
+	}
+
+	return nil
+}
+
+// вес взаимодействия, у события без веса - вес его типа
+func InteractionWeight(interaction *myproto.Interaction) float64 {
+	if interaction.Weight == 0 {
+		return interactionWeights[interaction.Type]
+	}
+	return interaction.Weight
+}
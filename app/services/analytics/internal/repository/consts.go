@@ -25,6 +25,22 @@ const (
 	//таблица
 	productUpdatesTable = "product_updates"
 	//её поля
-	productIdField = "product_id"
-	kwField        = "keywords"
+	productIdField  = "product_id"
+	kwField         = "keywords"
+	actionField     = "action"
+	statusField     = "prd_status"
+	categoryIdField = "category_id"
+	versionField    = "prd_version"
+	//время события у продюсера, timestamp_column - время получения события
+	eventTimeField = "event_time"
+)
+
+const (
+	//таблица
+	interactionFactsTable = "interaction_facts"
+	//её поля
+	interactionTypeField = "interaction_type"
+	weightField          = "weight"
+	sessionIdField       = "session_id"
+	eventIdField         = "event_id"
 )
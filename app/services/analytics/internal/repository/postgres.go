@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/entities"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/pkg/config"
 	"github.com/jmoiron/sqlx"
@@ -16,12 +18,15 @@ import (
 type RelationalDataBase interface {
 	AddProductUpdate(ctx context.Context, product *myproto.ProductAction) (time.Time, error)
 	AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) (time.Time, error)
+	AddUserSnapshot(ctx context.Context, user *myproto.UserUpdate) (time.Time, error)
+	AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error
 }
 
 // имплементация RelationalDataBase интерфейса
 type PostgresDB struct {
-	DB  *sqlx.DB
-	log *slog.Logger
+	DB      *sqlx.DB
+	log     *slog.Logger
+	timeout time.Duration
 }
 
 // установка соединения с базой, паника в случае ошиби
@@ -32,85 +37,200 @@ func NewPostgresDB(cfg config.DBConfig, log *slog.Logger) *PostgresDB {
 		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Dbname, cfg.Sslmode))
 
 	return &PostgresDB{
-		DB:  db,
-		log: log,
+		DB:      db,
+		log:     log,
+		timeout: cfg.QueryTimeout,
 	}
 }
 
 func (p *PostgresDB) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) (time.Time, error) {
-	fi := "repository.postgresDB.AddUserUpdate"
+	return p.addUserUpdate(ctx, user, false)
+}
 
-	tgx, err := p.DB.Begin()
-	if err != nil {
-		return time.Time{}, fmt.Errorf("%s: %w", fi, err)
-	}
+// снимок пользователя записывается, только если интересы отличаются от последних сохраненных,
+// иначе возвращается время последнего обновления - повторная публикация идемпотентна
+func (p *PostgresDB) AddUserSnapshot(ctx context.Context, user *myproto.UserUpdate) (time.Time, error) {
+	return p.addUserUpdate(ctx, user, true)
+}
 
-	//добавление id пользователя в табблицу users
-	query := fmt.Sprintf(
-		`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
-		usersTable, idField,
-	)
-	if _, err := tgx.Exec(query, user.UserId); err != nil {
-		tgx.Rollback()
-		return time.Time{}, fmt.Errorf("%s: %w", fi, err)
-	}
-	//Добавление информации о обновлении
-	query = fmt.Sprintf(
-		`INSERT INTO %s (%s, %s) VALUES ($1, $2) RETURNING %s`,
-		userUpdatesTable, userIdField, userInterestsField, timestampField,
-	)
-	str := strings.Join(user.UserInterests, ",")
-	row := tgx.QueryRow(query, user.UserId, str)
+func (p *PostgresDB) addUserUpdate(ctx context.Context, user *myproto.UserUpdate, snapshot bool) (time.Time, error) {
+	fi := "repository.postgresDB.AddUserUpdate"
 
 	var timestamp time.Time
-	if err := row.Scan(&timestamp); err != nil {
-		tgx.Rollback()
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//добавление id пользователя в табблицу users
+		query := fmt.Sprintf(
+			`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
+			usersTable, idField,
+		)
+		if _, err := tgx.ExecContext(ctx, query, user.UserId); err != nil {
+			return err
+		}
+
+		str := strings.Join(user.UserInterests, ",")
+
+		if snapshot {
+			query = fmt.Sprintf(
+				`SELECT %s, %s FROM %s WHERE %s = $1 ORDER BY %s DESC, %s DESC LIMIT 1`,
+				timestampField, userInterestsField, userUpdatesTable, userIdField, timestampField, idField,
+			)
+			var interests sql.NullString
+			err := tgx.QueryRowContext(ctx, query, user.UserId).Scan(&timestamp, &interests)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if err == nil && interests.String == str {
+				p.log.Info(fmt.Sprintf("%s: user %d snapshot is already recorded", fi, user.UserId))
+				return nil
+			}
+		}
+
+		//Добавление информации о обновлении
+		query = fmt.Sprintf(
+			`INSERT INTO %s (%s, %s) VALUES ($1, $2) RETURNING %s`,
+			userUpdatesTable, userIdField, userInterestsField, timestampField,
+		)
+		if err := tgx.QueryRowContext(ctx, query, user.UserId, str).Scan(&timestamp); err != nil {
+			return err
+		}
+
+		p.log.Info(fmt.Sprintf("%s: SUCCESS added user update at %s", fi, timestamp))
+		return nil
+	})
+	if err != nil {
 		return time.Time{}, fmt.Errorf("%s: %w", fi, err)
 	}
 
-	p.log.Info(fmt.Sprintf("%s: SUCCESS added user update at %s", fi, timestamp))
-	tgx.Commit()
 	return timestamp, nil
 }
 
 func (p *PostgresDB) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) (time.Time, error) {
 	fi := "repository.postgresDB.AddProductUpdate"
 
-	tgx, err := p.DB.Begin()
-	if err != nil {
-		return time.Time{}, fmt.Errorf("%s: %w", fi, err)
-	}
+	var timestamp time.Time
 
-	//добавление id пользователя в табблицу users
-	query := fmt.Sprintf(
-		`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
-		productsTable, idField,
-	)
-	if _, err := tgx.Exec(query, product.ProductId); err != nil {
-		tgx.Rollback()
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//добавление id пользователя в табблицу users
+		query := fmt.Sprintf(
+			`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
+			productsTable, idField,
+		)
+		if _, err := tgx.ExecContext(ctx, query, product.ProductId); err != nil {
+			return err
+		}
+
+		actionType := entities.ProductActionType(product)
+
+		//снимок продукта записывается, только если эта версия еще не сохранена
+		if actionType == myproto.Action_SNAPSHOT {
+			query = fmt.Sprintf(
+				`SELECT %s FROM %s WHERE %s = $1 AND %s = $2 ORDER BY %s DESC LIMIT 1`,
+				timestampField, productUpdatesTable, productIdField, versionField, timestampField,
+			)
+			err := tgx.QueryRowContext(ctx, query, product.ProductId, product.Version).Scan(&timestamp)
+			if err == nil {
+				p.log.Info(fmt.Sprintf("%s: product %d version %d is already recorded", fi, product.ProductId, product.Version))
+				return nil
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		//Добавление информации о обновлении
+		query = fmt.Sprintf(
+			`INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) 
+			 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING %s`,
+			productUpdatesTable,
+			productIdField, kwField, actionField, statusField, categoryIdField, versionField, eventTimeField,
+			timestampField,
+		)
+		var str string
+		if actionType == myproto.Action_DELETED {
+			str = "DELETED"
+		} else {
+			str = strings.Join(product.ProductKeyWords, ",")
+		}
+		//у старых сообщений нет времени события
+		var eventTime sql.NullTime
+		if product.EventTime != nil {
+			eventTime = sql.NullTime{Time: product.EventTime.AsTime(), Valid: true}
+		}
+		row := tgx.QueryRowContext(ctx, query,
+			product.ProductId, str, actionType.String(), product.Status,
+			product.CategoryId, product.Version, eventTime,
+		)
+		if err := row.Scan(&timestamp); err != nil {
+			return err
+		}
+		p.log.Info(fmt.Sprintf("%s: SUCCESS added product update at %s", fi, timestamp))
+		return nil
+	})
+	if err != nil {
 		return time.Time{}, fmt.Errorf("%s: %w", fi, err)
 	}
 
-	//Добавление информации о обновлении
-	query = fmt.Sprintf(
-		`INSERT INTO %s (%s, %s) VALUES ($1, $2) RETURNING %s`,
-		productUpdatesTable, productIdField, kwField, timestampField,
-	)
-	var str string
-	if product.Action == "delete" {
-		str = "DELETED"
-	} else {
-		str = strings.Join(product.ProductKeyWords, ",")
-	}
-	row := tgx.QueryRow(query, product.ProductId, str)
+	return timestamp, nil
+}
 
-	var timestamp time.Time
-	if err := row.Scan(&timestamp); err != nil {
-		tgx.Rollback()
-		return time.Time{}, fmt.Errorf("%s: %w", fi, err)
+// сохранение факта взаимодействия, факт с уже сохраненным eventId (событие, повторно прочитанное
+// из топика) пропускается, пустой eventId не проверяется
+func (p *PostgresDB) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error {
+	fi := "repository.postgresDB.AddInteraction"
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//добавление id пользователя и продукта в таблицы users и products
+		query := fmt.Sprintf(
+			`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
+			usersTable, idField,
+		)
+		if _, err := tgx.ExecContext(ctx, query, interaction.UserId); err != nil {
+			return err
+		}
+		query = fmt.Sprintf(
+			`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
+			productsTable, idField,
+		)
+		if _, err := tgx.ExecContext(ctx, query, interaction.ProductId); err != nil {
+			return err
+		}
+
+		//Добавление факта взаимодействия
+		query = fmt.Sprintf(
+			`INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (%s) DO NOTHING`,
+			interactionFactsTable,
+			userIdField, productIdField, interactionTypeField, weightField, sessionIdField, eventTimeField, eventIdField,
+			eventIdField,
+		)
+		var eventTime sql.NullTime
+		if interaction.Timestamp != nil {
+			eventTime = sql.NullTime{Time: interaction.Timestamp.AsTime(), Valid: true}
+		}
+		if _, err := tgx.ExecContext(ctx, query,
+			interaction.UserId, interaction.ProductId, interaction.Type.String(),
+			entities.InteractionWeight(interaction), interaction.SessionId, eventTime,
+			sql.NullString{String: eventId, Valid: eventId != ""},
+		); err != nil {
+			return err
+		}
+
+		p.log.Info(fmt.Sprintf("%s: SUCCESS added interaction of user %d with product %d", fi, interaction.UserId, interaction.ProductId))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fi, err)
 	}
-	p.log.Info(fmt.Sprintf("%s: SUCCESS added product update at %s", fi, timestamp))
 
-	tgx.Commit()
-	return timestamp, nil
+	return nil
 }
@@ -10,6 +10,7 @@ import (
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func loadConf() config.DBConfig {
@@ -125,3 +126,112 @@ func TestPostgreDB_AddProductUpdate_Incorrect(t *testing.T) {
 	assert.Error(t, err)
 	assert.Empty(t, timestamp)
 }
+
+func TestPostgreDB_AddProductUpdate_TypedEvent(t *testing.T) {
+	cfg := loadConf()
+
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	product := myproto.ProductAction{
+		ProductId:       1,
+		ActionType:      myproto.Action_DELETED,
+		Status:          "archived",
+		CategoryId:      2,
+		Version:         3,
+		EventTime:       timestamppb.Now(),
+		ProductKeyWords: []string{"test"},
+	}
+
+	timestamp, err := dbConn.AddProductUpdate(context.Background(), &product)
+	assert.NoError(t, err)
+
+	// для удаленного продукта вместо ключевых слов записывается DELETED
+	var keyWords, action string
+	err = dbConn.DB.QueryRow(
+		"SELECT keywords, action FROM product_updates WHERE product_id = $1 AND timestamp_column = $2",
+		product.ProductId, timestamp,
+	).Scan(&keyWords, &action)
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETED", keyWords)
+	assert.Equal(t, "DELETED", action)
+}
+
+func TestPostgreDB_AddProductUpdate_SnapshotIsIdempotent(t *testing.T) {
+	cfg := loadConf()
+
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	product := myproto.ProductAction{
+		ProductId:       1,
+		ActionType:      myproto.Action_SNAPSHOT,
+		Version:         1000,
+		ProductKeyWords: []string{"test"},
+	}
+
+	// повторный снимок той же версии не добавляет новую запись
+	first, err := dbConn.AddProductUpdate(context.Background(), &product)
+	assert.NoError(t, err)
+	second, err := dbConn.AddProductUpdate(context.Background(), &product)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestPostgreDB_AddUserSnapshot_Idempotent(t *testing.T) {
+	cfg := loadConf()
+
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	user := myproto.UserUpdate{
+		UserId:        1,
+		UserInterests: []string{"snapshot"},
+	}
+
+	first, err := dbConn.AddUserSnapshot(context.Background(), &user)
+	assert.NoError(t, err)
+	second, err := dbConn.AddUserSnapshot(context.Background(), &user)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestPostgreDB_AddInteraction_Correct(t *testing.T) {
+	cfg := loadConf()
+
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	err := dbConn.AddInteraction(context.Background(), &myproto.Interaction{
+		UserId:    1,
+		ProductId: 1,
+		Type:      myproto.InteractionType_CLICK,
+		Timestamp: timestamppb.Now(),
+		SessionId: "s1",
+	}, "")
+	assert.NoError(t, err)
+}
+
+func TestPostgreDB_AddInteraction_CorrectDuplicateEvent(t *testing.T) {
+	cfg := loadConf()
+
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	// событие, повторно прочитанное из топика, сохраняется один раз
+	interaction := &myproto.Interaction{UserId: 1, ProductId: 1, Type: myproto.InteractionType_VIEW}
+	for range 2 {
+		err := dbConn.AddInteraction(context.Background(), interaction, "event-1")
+		assert.NoError(t, err)
+	}
+
+	var count int
+	err := dbConn.DB.Get(&count, `SELECT COUNT(*) FROM interaction_facts WHERE event_id = 'event-1'`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
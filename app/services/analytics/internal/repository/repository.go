@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
 )
@@ -10,6 +11,8 @@ import (
 type Repository interface {
 	AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error
 	AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error
+	AddUserSnapshot(ctx context.Context, user *myproto.UserUpdate) error
+	AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error
 }
 
 // имплементация Repository интерфейса
@@ -47,10 +50,20 @@ func (r *AnalyticsRepository) AddProductUpdate(ctx context.Context, product *myp
 }
 
 func (r *AnalyticsRepository) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
+	return r.addUserUpdate(ctx, user, r.relDB.AddUserUpdate)
+}
+
+// снимок пользователя из повторной публикации, неизменившиеся интересы повторно не сохраняются
+func (r *AnalyticsRepository) AddUserSnapshot(ctx context.Context, user *myproto.UserUpdate) error {
+	return r.addUserUpdate(ctx, user, r.relDB.AddUserSnapshot)
+}
+
+func (r *AnalyticsRepository) addUserUpdate(ctx context.Context, user *myproto.UserUpdate,
+	add func(ctx context.Context, user *myproto.UserUpdate) (time.Time, error)) error {
 	fi := "analytics.AnalyticsRepository.AddUserUpdate"
 
 	user.UserInterests = removeDuplicates(user.UserInterests)
-	timestamp, err := r.relDB.AddUserUpdate(ctx, user)
+	timestamp, err := add(ctx, user)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return err
@@ -64,6 +77,18 @@ func (r *AnalyticsRepository) AddUserUpdate(ctx context.Context, user *myproto.U
 	return nil
 }
 
+// факт взаимодействия пишется только в реляционную базу
+func (r *AnalyticsRepository) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error {
+	fi := "analytics.AnalyticsRepository.AddInteraction"
+
+	if err := r.relDB.AddInteraction(ctx, interaction, eventId); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
 func removeDuplicates(slice []string) []string {
 	keys := make(map[string]bool)
 	var result []string
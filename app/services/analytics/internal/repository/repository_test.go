@@ -31,6 +31,20 @@ func (m *MockRelDB) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate)
 
 }
 
+func (m *MockRelDB) AddUserSnapshot(ctx context.Context, user *myproto.UserUpdate) (time.Time, error) {
+	if user.UserId < 0 {
+		return time.Time{}, errors.New("some rel error")
+	}
+	return time.Now(), nil
+}
+
+func (m *MockRelDB) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error {
+	if interaction.UserId == 2 {
+		return errors.New("some rel error")
+	}
+	return nil
+}
+
 type MockKVDB struct{}
 
 func (m *MockKVDB) SetUserUpdate(ctx context.Context, user *myproto.UserUpdate, time time.Time) error {
@@ -136,3 +150,49 @@ func TestRecomRepository_AddUserUpdate_IncorrectKVError(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestRecomRepository_AddUserSnapshot_Correct(t *testing.T) {
+	r := NewAnalyticsRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	err := r.AddUserSnapshot(context.Background(), &myproto.UserUpdate{
+		UserId:        1,
+		UserInterests: []string{"ok", "ok"},
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_AddUserSnapshot_Incorrect(t *testing.T) {
+	r := NewAnalyticsRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	err := r.AddUserSnapshot(context.Background(), &myproto.UserUpdate{
+		UserId:        -1,
+		UserInterests: []string{"ok"},
+	})
+
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_AddInteraction_Correct(t *testing.T) {
+	r := NewAnalyticsRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 1, ProductId: 1}, "1")
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_AddInteraction_Incorrect(t *testing.T) {
+	r := NewAnalyticsRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 2, ProductId: 1}, "")
+
+	assert.Error(t, err)
+}
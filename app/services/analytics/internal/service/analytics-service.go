@@ -4,7 +4,9 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
 	"github.com/IBM/sarama"
 	"google.golang.org/protobuf/proto"
@@ -52,11 +54,42 @@ func (s *AnalyticsService) AddUserData(ctx context.Context, msg *sarama.Consumer
 		return err
 	}
 
+	//снимки из повторной публикации не дублируют неизменившиеся данные
+	add := s.repo.AddUserUpdate
+	if envelope.FromMessage(msg).EventType == envelope.EventUserSnapshot {
+		add = s.repo.AddUserSnapshot
+	}
+
 	//отправляем структуру в бд
-	if err := s.repo.AddUserUpdate(ctx, &user); err != nil {
+	if err := add(ctx, &user); err != nil {
 		s.log.Error(fi, ": ", "Error adding user entity: ", err.Error(), err)
 		return err
 	}
 
 	return nil
 }
+
+func (s *AnalyticsService) AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	fi := "service.AnalyticsService.AddInteractionData"
+	var interaction myproto.Interaction
+
+	//из слайса байт в структуру
+	if err := proto.Unmarshal(msg.Value, &interaction); err != nil {
+		s.log.Error(fi, ": ", "Error unmarshaling interaction entity: ", err.Error(), err)
+		return err
+	}
+
+	//некорректное событие пропускается, чтобы не останавливать чтение топика
+	if err := entities.ValidateInteraction(&interaction); err != nil {
+		s.log.Error(fi, ": ", "Skip invalid interaction entity: ", err.Error(), err)
+		return nil
+	}
+
+	//отправляем структуру в бд вместе с id события - повторно прочитанное событие не сохраняется
+	if err := s.repo.AddInteraction(ctx, &interaction, envelope.FromMessage(msg).EventId); err != nil {
+		s.log.Error(fi, ": ", "Error adding interaction entity: ", err.Error(), err)
+		return err
+	}
+
+	return nil
+}
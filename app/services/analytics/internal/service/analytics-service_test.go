@@ -8,6 +8,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
 	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,23 @@ func (m *MockRepository) AddUserUpdate(ctx context.Context, user *myproto.UserUp
 	return nil
 }
 
+func (m *MockRepository) AddUserSnapshot(ctx context.Context, user *myproto.UserUpdate) error {
+	if user.UserInterests[0] == "error" {
+		return errors.New("some error")
+	}
+	if user.UserInterests[0] == "update" {
+		return errors.New("update written as snapshot")
+	}
+	return nil
+}
+
+func (m *MockRepository) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error {
+	if interaction.SessionId == "error" || eventId == "error" {
+		return errors.New("some error")
+	}
+	return nil
+}
+
 func TestAnalyticsService_AddProductUpdate_Correct(t *testing.T) {
 	service := NewAnalyticsService(
 		&MockRepository{},
@@ -180,3 +198,111 @@ func TestAnalyticsService_AddUserUpdate_IncorrectErrorONRepoLevel(t *testing.T)
 
 	assert.Error(t, err1)
 }
+
+func TestAnalyticsService_AddUserUpdate_Snapshot(t *testing.T) {
+	service := NewAnalyticsService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	var message myproto.UserUpdate = myproto.UserUpdate{
+		UserId:        1,
+		UserInterests: []string{"snapshot"},
+	}
+
+	marshalledMessage, err := proto.Marshal(&message)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err1 := service.AddUserData(context.Background(), &sarama.ConsumerMessage{
+		Topic: "test",
+		Value: sarama.ByteEncoder(marshalledMessage),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(envelope.HeaderEventType), Value: []byte(envelope.EventUserSnapshot)},
+		},
+	})
+
+	assert.NoError(t, err1)
+}
+
+func TestAnalyticsService_AddUserUpdate_UpdateIsNotSnapshot(t *testing.T) {
+	service := NewAnalyticsService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// обычное обновление с теми же интересами записывается через AddUserUpdate
+	var message myproto.UserUpdate = myproto.UserUpdate{
+		UserId:        1,
+		UserInterests: []string{"update"},
+	}
+
+	marshalledMessage, err := proto.Marshal(&message)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err1 := service.AddUserData(context.Background(), &sarama.ConsumerMessage{
+		Topic: "test",
+		Value: sarama.ByteEncoder(marshalledMessage),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(envelope.HeaderEventType), Value: []byte(envelope.EventUserUpdated)},
+		},
+	})
+
+	assert.NoError(t, err1)
+}
+
+func TestAnalyticsService_AddInteractionData(t *testing.T) {
+	service := NewAnalyticsService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	tests := []struct {
+		name        string
+		interaction *myproto.Interaction
+		wantErr     bool
+	}{
+		{"Correct", &myproto.Interaction{UserId: 1, ProductId: 2, Type: myproto.InteractionType_PURCHASE}, false},
+		// некорректное событие пропускается без ошибки
+		{"CorrectSkipInvalid", &myproto.Interaction{UserId: 1, Type: myproto.InteractionType_VIEW}, false},
+		{"IncorrectErrorONRepoLevel", &myproto.Interaction{UserId: 1, ProductId: 2, Type: myproto.InteractionType_VIEW, SessionId: "error"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marshalledMessage, err := proto.Marshal(tt.interaction)
+			if err != nil {
+				t.Error(err)
+			}
+
+			err = service.AddInteractionData(context.Background(), &sarama.ConsumerMessage{
+				Topic: "user_interactions",
+				Value: sarama.ByteEncoder(marshalledMessage),
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// id события из заголовка конверта передается в репозиторий
+	marshalledMessage, err := proto.Marshal(&myproto.Interaction{UserId: 1, ProductId: 2, Type: myproto.InteractionType_CLICK})
+	assert.NoError(t, err)
+	err = service.AddInteractionData(context.Background(), &sarama.ConsumerMessage{
+		Topic:   "user_interactions",
+		Value:   marshalledMessage,
+		Headers: []*sarama.RecordHeader{{Key: []byte(envelope.HeaderEventId), Value: []byte("error")}},
+	})
+	assert.Error(t, err)
+}
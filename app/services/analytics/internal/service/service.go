@@ -13,4 +13,5 @@ type Service interface {
 type KafkaHandler interface {
 	AddProductData(ctx context.Context, msg *sarama.ConsumerMessage) error
 	AddUserData(ctx context.Context, msg *sarama.ConsumerMessage) error
+	AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error
 }
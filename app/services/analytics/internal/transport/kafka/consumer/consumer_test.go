@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/analytics/internal/transport/kafka/pb"
 	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/assert"
@@ -45,6 +46,10 @@ func (m *MockTopicHandler) AddUserData(ctx context.Context, msg *sarama.Consumer
 	return nil
 }
 
+func (m *MockTopicHandler) AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	return nil
+}
+
 func NewProducer(t *testing.T, addr []string, topics []string) sarama.SyncProducer {
 	producer, err := sarama.NewSyncProducer(addr, InitConfigProducer(addr))
 	if err != nil {
@@ -263,3 +268,107 @@ func TestKafka_Consume_IncorrectUser(t *testing.T) {
 	time.Sleep(2 * time.Second)
 
 }
+
+// мок хэндлера, считающий вызовы
+type CountingTopicHandler struct {
+	users        int
+	products     int
+	interactions int
+}
+
+func (m *CountingTopicHandler) AddProductData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	m.products++
+	return nil
+}
+
+func (m *CountingTopicHandler) AddUserData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	m.users++
+	return nil
+}
+
+func (m *CountingTopicHandler) AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	m.interactions++
+	return nil
+}
+
+func newTestConsumer() *Consumer {
+	return &Consumer{
+		log: slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		seen: newSeenEvents(2),
+	}
+}
+
+func envelopeMessage(topic, eventId, eventType string) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{
+		Topic: topic,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(envelope.HeaderEventId), Value: []byte(eventId)},
+			{Key: []byte(envelope.HeaderEventType), Value: []byte(eventType)},
+		},
+	}
+}
+
+func TestKafka_HandleMessage_RouteByEventType(t *testing.T) {
+	consumer := newTestConsumer()
+	handler := &CountingTopicHandler{}
+
+	err := consumer.handleMessage(context.Background(), handler,
+		envelopeMessage("user_updates", "1", envelope.EventProductCreated))
+	assert.NoError(t, err)
+
+	err = consumer.handleMessage(context.Background(), handler,
+		envelopeMessage("product_updates", "2", envelope.EventUserUpdated))
+	assert.NoError(t, err)
+
+	err = consumer.handleMessage(context.Background(), handler,
+		envelopeMessage("product_updates", "3", "order.created"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, handler.products)
+	assert.Equal(t, 1, handler.users)
+}
+
+func TestKafka_HandleMessage_LegacyRouteByTopic(t *testing.T) {
+	consumer := newTestConsumer()
+	handler := &CountingTopicHandler{}
+
+	err := consumer.handleMessage(context.Background(), handler, &sarama.ConsumerMessage{Topic: "user_updates"})
+	assert.NoError(t, err)
+
+	err = consumer.handleMessage(context.Background(), handler, &sarama.ConsumerMessage{Topic: "user_updates"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, handler.users)
+	assert.Equal(t, 0, handler.products)
+}
+
+func TestKafka_HandleMessage_RouteInteraction(t *testing.T) {
+	consumer := newTestConsumer()
+	handler := &CountingTopicHandler{}
+
+	err := consumer.handleMessage(context.Background(), handler,
+		envelopeMessage("user_interactions", "1", envelope.EventInteractionCreated))
+	assert.NoError(t, err)
+
+	err = consumer.handleMessage(context.Background(), handler, &sarama.ConsumerMessage{Topic: "user_interactions"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, handler.interactions)
+	assert.Equal(t, 0, handler.users)
+}
+
+func TestKafka_HandleMessage_SkipDuplicate(t *testing.T) {
+	consumer := newTestConsumer()
+	handler := &CountingTopicHandler{}
+
+	for _, eventId := range []string{"1", "1", "2", "3", "1"} {
+		err := consumer.handleMessage(context.Background(), handler,
+			envelopeMessage("product_updates", eventId, envelope.EventProductUpdated))
+		assert.NoError(t, err)
+	}
+
+	//"1" вытеснен из множества после "3" и обрабатывается повторно
+	assert.Equal(t, 4, handler.products)
+}
@@ -0,0 +1,6 @@
+ALTER TABLE product_updates
+    DROP COLUMN IF EXISTS event_time,
+    DROP COLUMN IF EXISTS prd_version,
+    DROP COLUMN IF EXISTS category_id,
+    DROP COLUMN IF EXISTS prd_status,
+    DROP COLUMN IF EXISTS action;
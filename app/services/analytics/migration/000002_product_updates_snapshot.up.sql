@@ -0,0 +1,7 @@
+-- поля типизированного события продукта, у ранее сохраненных обновлений остаются пустыми
+ALTER TABLE product_updates
+    ADD COLUMN IF NOT EXISTS action VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS prd_status VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS category_id INTEGER,
+    ADD COLUMN IF NOT EXISTS prd_version INTEGER,
+    ADD COLUMN IF NOT EXISTS event_time TIMESTAMP;
@@ -0,0 +1,13 @@
+-- факты взаимодействий пользователей с продуктами: просмотры, клики и покупки
+CREATE TABLE IF NOT EXISTS interaction_facts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER,
+    product_id INTEGER,
+    timestamp_column TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    interaction_type VARCHAR(32),
+    weight DOUBLE PRECISION,
+    session_id VARCHAR(64),
+    event_time TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
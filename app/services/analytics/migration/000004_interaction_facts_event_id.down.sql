@@ -0,0 +1 @@
+ALTER TABLE interaction_facts DROP COLUMN IF EXISTS event_id;
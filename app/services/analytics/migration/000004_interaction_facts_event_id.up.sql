@@ -0,0 +1,4 @@
+-- id события из конверта kafka: событие, повторно прочитанное из топика после перезапуска,
+-- не сохраняется второй раз. У сообщений без заголовков и ранее сохраненных фактов id нет,
+-- NULL уникальность не нарушает
+ALTER TABLE interaction_facts ADD COLUMN IF NOT EXISTS event_id VARCHAR(64) UNIQUE;
@@ -0,0 +1,74 @@
+// пакетный поиск почти одинаковых продуктов: пересчет MinHash-сигнатур и дубликатов
+// всех продуктов каталога (например, после импорта или для продуктов, добавленных
+// до появления поиска дубликатов). При обрыве поиск можно продолжить с флагом -from
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/service"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/config"
+	mylog "github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/log"
+)
+
+func main() {
+	var fromId int
+
+	//флаги разбираются вместе с флагами конфигурации в config.MustLoadConfig
+	flag.IntVar(&fromId, "from", 0, "id of last checked product, detection continues after it")
+
+	//загрузка переменных окружения
+	env := config.LoadEnv()
+
+	// логгер
+	logger := mylog.MustNewLogger(env)
+
+	// конфига
+	cfg := config.MustLoadConfig()
+
+	if fromId < 0 {
+		fmt.Fprintln(os.Stderr, "invalid from: can`t be less than 0")
+		os.Exit(2)
+	}
+
+	//код выхода выставляется после закрытия соединений (defer выполняется последним)
+	exitCode := 0
+	defer func() { os.Exit(exitCode) }()
+
+	// коннект к бд (Маст)
+	dbConn := repository.NewPostgresDB(cfg.DBConf, logger)
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			logger.Error("main : " + err.Error())
+		}
+	}()
+
+	service := service.NewProductService(repository.NewProductRepository(dbConn, logger), logger, cfg.KWConf)
+
+	// остановка по сигналу, прогресс до остановки сохраняется в выводе
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM,
+	)
+	defer stop()
+
+	lastId := fromId
+	err := service.DetectDuplicates(ctx, fromId, func(detection *entities.DuplicateDetection) error {
+		lastId = detection.LastId
+		fmt.Printf("checked %d, duplicates %d, last id %d\n", detection.Checked, detection.Duplicates, detection.LastId)
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "detection stopped: %v, resume with -from=%d\n", err, lastId)
+		exitCode = 1
+		return
+	}
+	fmt.Println("done")
+}
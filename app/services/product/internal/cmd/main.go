@@ -41,7 +41,7 @@ func main() {
 	repository := repository.NewProductRepository(dbConn, logger)
 
 	// слой сервиса
-	service := service.NewProductService(repository, logger)
+	service := service.NewProductService(repository, logger, cfg.KWConf)
 
 	//коннект к кафке
 	kafkaConn := kafka.ConnectToKafka(logger)
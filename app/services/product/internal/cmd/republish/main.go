@@ -0,0 +1,89 @@
+// повторная публикация снимков всех продуктов в кафку (событие product.snapshot)
+// для восстановления состояния сервисов рекомендаций и аналитики.
+// При обрыве публикацию можно продолжить с флагом -from, равным последнему lastId
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/service"
+	kafka "github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/transport/kafka/producer"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/config"
+	mylog "github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/log"
+)
+
+func main() {
+	var republish entities.Republish
+
+	//флаги разбираются вместе с флагами конфигурации в config.MustLoadConfig
+	flag.IntVar(&republish.FromId, "from", 0, "id of last published product, publishing continues after it")
+	flag.IntVar(&republish.Rate, "rate", entities.DefaultRepublishRate, "max products per second")
+
+	//загрузка переменных окружения
+	env := config.LoadEnv()
+
+	// логгер
+	logger := mylog.MustNewLogger(env)
+
+	// конфига
+	cfg := config.MustLoadConfig()
+
+	if err := republish.ValidateRepublish(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	//код выхода выставляется после закрытия соединений (defer выполняется последним)
+	exitCode := 0
+	defer func() { os.Exit(exitCode) }()
+
+	// коннект к бд (Маст)
+	dbConn := repository.NewPostgresDB(cfg.DBConf, logger)
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			logger.Error("main : " + err.Error())
+		}
+	}()
+
+	service := service.NewProductService(repository.NewProductRepository(dbConn, logger), logger, cfg.KWConf)
+
+	//коннект к кафке
+	kafkaConn := kafka.ConnectToKafka(logger)
+	defer func() {
+		if err := kafkaConn.Producer.Close(); err != nil {
+			logger.Error("main : " + err.Error())
+		}
+	}()
+
+	// остановка по сигналу, прогресс до остановки сохраняется в выводе
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM,
+	)
+	defer stop()
+
+	progress := entities.RepublishProgress{LastId: republish.FromId}
+	err := service.RepublishProducts(ctx, &republish, func(products []entities.ProductInfo) error {
+		if err := kafkaConn.SendMessages(ctx, products, "snapshot"); err != nil {
+			return err
+		}
+
+		progress.Published += len(products)
+		progress.LastId = products[len(products)-1].ProductId
+		fmt.Printf("published %d, last id %d\n", progress.Published, progress.LastId)
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "republish stopped: %v, resume with -from=%d\n", err, progress.LastId)
+		exitCode = 1
+		return
+	}
+	fmt.Printf("done: published %d products\n", progress.Published)
+}
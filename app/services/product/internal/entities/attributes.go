@@ -0,0 +1,142 @@
+// типизированные атрибуты продукта (цена, бренд, размер) и их схемы в категориях
+
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// типы значений атрибутов
+const (
+	AttributeString = "string"
+	AttributeNumber = "number"
+	AttributeEnum   = "enum"
+	AttributeBool   = "bool"
+)
+
+const (
+	attributeNameMaxLenth = 32
+	attributeNamePattern  = `^[a-zA-Zа-яА-Я][a-zA-Zа-яА-Я0-9_]*$`
+
+	attributeStringMaxLenth = 255
+)
+
+var ErrInvalidAttributes = errors.New("invalid product attributes")
+
+// описание атрибута в схеме категории, Values - допустимые значения атрибута типа enum
+type AttributeSchema struct {
+	Name     string   `json:"name" binding:"required"`
+	Type     string   `json:"type" binding:"required"`
+	Required bool     `json:"required"`
+	Values   []string `json:"values,omitempty"`
+}
+
+func (a *AttributeSchema) ValidateAttributeSchema() error {
+	re := regexp.MustCompile(attributeNamePattern)
+
+	if len(a.Name) > attributeNameMaxLenth {
+		return errors.New("invalid attribute name: too long, max length is " + strconv.Itoa(attributeNameMaxLenth))
+	}
+
+	if !re.MatchString(a.Name) {
+		return fmt.Errorf("invalid attribute name: %s does not match regexp", a.Name)
+	}
+
+	switch a.Type {
+	case AttributeString, AttributeNumber, AttributeBool:
+		if len(a.Values) != 0 {
+			return fmt.Errorf("invalid attribute %s: values are allowed only for %s", a.Name, AttributeEnum)
+		}
+	case AttributeEnum:
+		if len(a.Values) == 0 {
+			return fmt.Errorf("invalid attribute %s: %s must have values", a.Name, AttributeEnum)
+		}
+	default:
+		return fmt.Errorf("invalid attribute %s: type %s, must be one of %s, %s, %s, %s",
+			a.Name, a.Type, AttributeString, AttributeNumber, AttributeEnum, AttributeBool)
+	}
+
+	return nil
+}
+
+// схема атрибутов категории с учетом родительских категорий: schemas - схемы категорий
+// от корня до текущей, атрибут подкатегории заменяет одноименный атрибут родителя
+func MergeAttributeSchemas(schemas [][]AttributeSchema) []AttributeSchema {
+	merged := make([]AttributeSchema, 0)
+
+	for _, schema := range schemas {
+		for _, attribute := range schema {
+			i := slices.IndexFunc(merged, func(a AttributeSchema) bool { return a.Name == attribute.Name })
+			if i >= 0 {
+				merged[i] = attribute
+				continue
+			}
+			merged = append(merged, attribute)
+		}
+	}
+
+	return merged
+}
+
+// проверка атрибутов продукта по схеме его категории: неизвестные атрибуты запрещены,
+// обязательные должны быть заданы, значения должны соответствовать типу
+func ValidateAttributes(schema []AttributeSchema, attributes map[string]any) error {
+
+	for name := range attributes {
+		if !slices.ContainsFunc(schema, func(a AttributeSchema) bool { return a.Name == name }) {
+			return fmt.Errorf("%w: unknown attribute %s", ErrInvalidAttributes, name)
+		}
+	}
+
+	for _, attribute := range schema {
+		value, ok := attributes[attribute.Name]
+		if !ok || value == nil {
+			if attribute.Required {
+				return fmt.Errorf("%w: attribute %s is required", ErrInvalidAttributes, attribute.Name)
+			}
+			continue
+		}
+
+		if err := validateAttributeValue(attribute, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateAttributeValue(attribute AttributeSchema, value any) error {
+
+	switch attribute.Type {
+	case AttributeString:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%w: attribute %s must be %s", ErrInvalidAttributes, attribute.Name, attribute.Type)
+		}
+		if len(str) > attributeStringMaxLenth {
+			return fmt.Errorf("%w: attribute %s is too long, max length is %d",
+				ErrInvalidAttributes, attribute.Name, attributeStringMaxLenth)
+		}
+	case AttributeNumber:
+		switch value.(type) {
+		case float64, int:
+		default:
+			return fmt.Errorf("%w: attribute %s must be %s", ErrInvalidAttributes, attribute.Name, attribute.Type)
+		}
+	case AttributeBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%w: attribute %s must be %s", ErrInvalidAttributes, attribute.Name, attribute.Type)
+		}
+	case AttributeEnum:
+		str, ok := value.(string)
+		if !ok || !slices.Contains(attribute.Values, str) {
+			return fmt.Errorf("%w: attribute %s must be one of %v", ErrInvalidAttributes, attribute.Name, attribute.Values)
+		}
+	}
+
+	return nil
+}
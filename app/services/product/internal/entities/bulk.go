@@ -0,0 +1,39 @@
+// пакетный импорт и экспорт каталога продуктов
+
+package entities
+
+import "errors"
+
+const (
+	// число продуктов, добавляемых в одной транзакции и отправляемых в кафку одной пачкой
+	BulkBatchSize = 100
+
+	BulkFormatNDJSON = "ndjson"
+	BulkFormatCSV    = "csv"
+
+	// разделитель ключевых слов внутри одной ячейки csv
+	CSVKeyWordsSeparator = "|"
+)
+
+// колонки csv, при импорте колонки ищутся по заголовку, лишние колонки игнорируются,
+// attributes - json объект с атрибутами продукта, merchantId при импорте мерчантом игнорируется
+var CSVColumns = []string{
+	"productId", "categoryId", "category", "categoryPath", "description", "status", "productKeyWords", "attributes",
+	"merchantId",
+}
+
+// результат импорта одной строки, строки нумеруются с 1 без учета заголовка csv
+type BulkRowResult struct {
+	Row       int    `json:"row"`
+	ProductId int    `json:"productId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func ValidateBulkFormat(format string) error {
+
+	if format != BulkFormatNDJSON && format != BulkFormatCSV {
+		return errors.New("invalid format: must be ndjson or csv")
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+// иерархия категорий продуктов
+
+package entities
+
+import (
+	"errors"
+	"fmt"
+)
+
+// категория продукта, Path - имена категорий от корня до текущей включительно,
+// Attributes - схема атрибутов продуктов категории (дополняет схемы родительских категорий)
+type Category struct {
+	CategoryId int               `json:"categoryId"`
+	Name       string            `json:"name" binding:"required"`
+	ParentId   int               `json:"parentId"`
+	Path       []string          `json:"path"`
+	Attributes []AttributeSchema `json:"attributes"`
+}
+
+func ValidateCategoryId(categoryId int) error {
+
+	if categoryId <= 0 {
+		return errors.New("invalid category id: can`t be less or equal 0")
+	}
+
+	return nil
+}
+
+func (c *Category) ValidateCategoryInfo() error {
+
+	if err := ValidateCategory(c.Name); err != nil {
+		return err
+	}
+
+	//0 - корневая категория
+	if c.ParentId < 0 {
+		return errors.New("invalid parent id: can`t be less than 0")
+	}
+
+	if c.ParentId != 0 && c.ParentId == c.CategoryId {
+		return errors.New("invalid parent id: category can`t be its own parent")
+	}
+
+	names := make(map[string]struct{}, len(c.Attributes))
+	for i := range c.Attributes {
+		if err := c.Attributes[i].ValidateAttributeSchema(); err != nil {
+			return err
+		}
+		if _, ok := names[c.Attributes[i].Name]; ok {
+			return fmt.Errorf("invalid attribute %s: duplicate name", c.Attributes[i].Name)
+		}
+		names[c.Attributes[i].Name] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+// почти одинаковые продукты (одни и те же товары с немного разными описаниями)
+
+package entities
+
+import "errors"
+
+// продукт нельзя слить с самим собой
+var ErrSelfMerge = errors.New("invalid duplicateId: product can`t be merged with itself")
+
+// MinHash-сигнатура описания продукта и хэши ее полос (LSH), продукты с общей
+// полосой - кандидаты в дубликаты
+type ProductSignature struct {
+	ProductId int
+	Signature []int64
+	Bands     []int64
+}
+
+// дубликат продукта и оценка сходства описаний (доля совпавших позиций сигнатур)
+type ProductDuplicate struct {
+	ProductId  int     `json:"productId"`
+	Similarity float64 `json:"similarity"`
+}
+
+// тело запроса на слияние: дубликат удаляется, его ключевые слова переходят к продукту
+type ProductMerge struct {
+	DuplicateId int `json:"duplicateId" binding:"required"`
+}
+
+// результат слияния: продукт после слияния и снимок удаленного дубликата,
+// Updated - у продукта появились новые ключевые слова
+type ProductMergeResult struct {
+	Product   *ProductInfo
+	Duplicate *ProductInfo
+	Updated   bool
+}
+
+// прогресс поиска дубликатов по всему каталогу
+type DuplicateDetection struct {
+	Checked    int `json:"checked"`
+	Duplicates int `json:"duplicates"`
+	LastId     int `json:"lastId"`
+}
+
+func (m *ProductMerge) ValidateProductMerge(productId int) error {
+
+	if err := ValidateProductId(m.DuplicateId); err != nil {
+		return err
+	}
+
+	if m.DuplicateId == productId {
+		return ErrSelfMerge
+	}
+
+	return nil
+}
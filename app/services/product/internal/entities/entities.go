@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 type ErrorResponse struct {
@@ -24,14 +26,74 @@ const (
 	descriptionMinLenth = 1
 
 	pattern = `^[a-zA-Zа-яА-Я]+$`
+
+	searchQueryMaxLenth = 255
+
+	SearchLangRu = "ru"
+	SearchLangEn = "en"
+
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
 )
 
+// MerchantId - владелец продукта, для мерчанта берется из вызывающей стороны,
+// Category и CategoryPath заполняются сервисом по CategoryId,
+// Attributes проверяются по схеме атрибутов категории,
+// Version - номер версии продукта, увеличивается при каждом изменении,
+// SuggestedKeyWords - ключевые слова из описания, которые мерчант может подтвердить
 type ProductInfo struct {
-	ProductId       int      `json:"userId" db:"productId"`
-	Category        string   `json:"category" binding:"required"`
-	Description     string   `json:"description" binding:"required"`
-	Status          string   `json:"status" binding:"required"`
-	ProductKeyWords []string `json:"productKeyWords" binding:"required"`
+	ProductId         int            `json:"userId" db:"productId"`
+	MerchantId        int            `json:"merchantId"`
+	CategoryId        int            `json:"categoryId" binding:"required"`
+	Category          string         `json:"category"`
+	CategoryPath      []string       `json:"categoryPath"`
+	Description       string         `json:"description" binding:"required"`
+	Status            string         `json:"status" binding:"required"`
+	ProductKeyWords   []string       `json:"productKeyWords" binding:"required"`
+	SuggestedKeyWords []string       `json:"suggestedKeyWords"`
+	Attributes        map[string]any `json:"attributes"`
+	Version           int            `json:"version"`
+}
+
+// параметры выборки списка продуктов, Cursor - id последнего продукта предыдущей страницы,
+// CategoryId выбирает продукты категории и всех ее подкатегорий
+type ProductFilter struct {
+	MerchantId int
+	CategoryId int
+	Status     string
+	Keyword    string
+	Cursor     int
+	Limit      int
+}
+
+// страница списка продуктов, NextCursor равен 0 если страница последняя
+type ProductList struct {
+	Products   []ProductInfo `json:"products"`
+	NextCursor int           `json:"nextCursor"`
+}
+
+// параметры полнотекстового поиска, Lang - язык запроса (определяется по тексту, если пуст)
+type ProductSearch struct {
+	Query      string
+	Lang       string
+	MerchantId int
+	CategoryId int
+	Status     string
+	Offset     int
+	Limit      int
+}
+
+// найденный продукт с рангом и подсвеченным фрагментом описания
+type ProductSearchHit struct {
+	ProductInfo
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// страница результатов поиска, NextOffset равен 0 если страница последняя
+type ProductSearchResult struct {
+	Products   []ProductSearchHit `json:"products"`
+	NextOffset int                `json:"nextOffset"`
 }
 
 func ValidateProductId(prId int) error {
@@ -97,7 +159,21 @@ func ValidateProductKeyWord(keyword string) error {
 
 func (pr *ProductInfo) ValidateProductInfo() error {
 
-	if err := ValidateCategory(pr.Category); err != nil {
+	if err := pr.ValidateNewProductInfo(); err != nil {
+		return err
+	}
+
+	if err := ValidateProductId(pr.ProductId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// проверка нового продукта: id назначается при добавлении, поэтому не проверяется
+func (pr *ProductInfo) ValidateNewProductInfo() error {
+
+	if err := ValidateCategoryId(pr.CategoryId); err != nil {
 		return err
 	}
 
@@ -105,7 +181,7 @@ func (pr *ProductInfo) ValidateProductInfo() error {
 		return err
 	}
 
-	if err := ValidateProductId(pr.ProductId); err != nil {
+	if err := ValidateStatus(pr.Status); err != nil {
 		return err
 	}
 
@@ -117,3 +193,103 @@ func (pr *ProductInfo) ValidateProductInfo() error {
 
 	return nil
 }
+
+func (f *ProductFilter) ValidateProductFilter() error {
+
+	if f.MerchantId < 0 {
+		return errors.New("invalid merchant id: can`t be less than 0")
+	}
+
+	if f.CategoryId < 0 {
+		return errors.New("invalid category id: can`t be less than 0")
+	}
+
+	if f.Status != "" {
+		if err := ValidateStatus(f.Status); err != nil {
+			return err
+		}
+	}
+
+	if f.Keyword != "" {
+		if err := ValidateProductKeyWord(f.Keyword); err != nil {
+			return err
+		}
+	}
+
+	if f.Cursor < 0 {
+		return errors.New("invalid cursor: can`t be less than 0")
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultPageLimit
+	}
+
+	if f.Limit < 0 || f.Limit > MaxPageLimit {
+		return errors.New("invalid limit: must be between 1 and " + strconv.Itoa(MaxPageLimit))
+	}
+
+	return nil
+}
+
+func (ps *ProductSearch) ValidateProductSearch() error {
+
+	if len(strings.TrimSpace(ps.Query)) == 0 {
+		return errors.New("invalid search query: can`t be empty")
+	}
+
+	if len(ps.Query) > searchQueryMaxLenth {
+		return errors.New("invalid search query: too long, max length is " + strconv.Itoa(searchQueryMaxLenth))
+	}
+
+	if ps.Lang != "" && ps.Lang != SearchLangRu && ps.Lang != SearchLangEn {
+		return fmt.Errorf("invalid lang: %s, must be %s or %s", ps.Lang, SearchLangRu, SearchLangEn)
+	}
+
+	if ps.MerchantId < 0 {
+		return errors.New("invalid merchant id: can`t be less than 0")
+	}
+
+	if ps.CategoryId < 0 {
+		return errors.New("invalid category id: can`t be less than 0")
+	}
+
+	if ps.Status != "" {
+		if err := ValidateStatus(ps.Status); err != nil {
+			return err
+		}
+	}
+
+	if ps.Offset < 0 {
+		return errors.New("invalid offset: can`t be less than 0")
+	}
+
+	if ps.Limit == 0 {
+		ps.Limit = DefaultPageLimit
+	}
+
+	if ps.Limit < 0 || ps.Limit > MaxPageLimit {
+		return errors.New("invalid limit: must be between 1 and " + strconv.Itoa(MaxPageLimit))
+	}
+
+	return nil
+}
+
+// конфигурация текстового поиска postgres для языка запроса,
+// если язык не указан - запрос с кириллицей считается русским
+func (ps *ProductSearch) Config() string {
+
+	switch ps.Lang {
+	case SearchLangRu:
+		return "russian"
+	case SearchLangEn:
+		return "english"
+	}
+
+	for _, r := range ps.Query {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "russian"
+		}
+	}
+
+	return "english"
+}
@@ -0,0 +1,19 @@
+// ключевые слова продуктов
+
+package entities
+
+// тело запроса на добавление ключевого слова продукту
+type ProductKeyWord struct {
+	KeyWord string `json:"keyWord" binding:"required"`
+}
+
+// тело запроса на подтверждение предложенных ключевых слов, пустой список - подтверждение всех
+type ConfirmKeyWords struct {
+	KeyWords []string `json:"keyWords"`
+}
+
+// ключевое слово и число продуктов, в которых оно используется
+type KeyWordUsage struct {
+	KeyWord  string `json:"keyWord"`
+	Products int    `json:"products"`
+}
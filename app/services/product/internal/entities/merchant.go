@@ -0,0 +1,81 @@
+// продавцы (мерчанты) - владельцы продуктов, и вызывающая сторона запроса
+
+package entities
+
+import (
+	"context"
+	"errors"
+)
+
+// квота продуктов мерчанта, если администратор не задал другую
+const DefaultMerchantQuota = 1000
+
+// ключ вызывающей стороны в контексте запроса (gin.Context.Set)
+const CallerKey = "caller"
+
+// вызывающая сторона: администратор или мерчант, от имени которого выполняется запрос
+type Caller struct {
+	MerchantId int
+	Admin      bool
+}
+
+// мерчант с числом его продуктов и квотой
+type Merchant struct {
+	MerchantId   int `json:"merchantId"`
+	ProductCount int `json:"productCount"`
+	MaxProducts  int `json:"maxProducts"`
+}
+
+// тело запроса на изменение квоты мерчанта
+type MerchantQuota struct {
+	MaxProducts int `json:"maxProducts" binding:"required"`
+}
+
+func ValidateMerchantId(merchantId int) error {
+
+	if merchantId <= 0 {
+		return errors.New("invalid merchant id: can`t be less or equal 0")
+	}
+
+	return nil
+}
+
+func (q *MerchantQuota) ValidateMerchantQuota() error {
+
+	if q.MaxProducts <= 0 {
+		return errors.New("invalid max products: can`t be less or equal 0")
+	}
+
+	return nil
+}
+
+// вызывающая сторона из контекста запроса
+func CallerFrom(ctx context.Context) (Caller, bool) {
+	if ctx == nil {
+		return Caller{}, false
+	}
+
+	caller, ok := ctx.Value(CallerKey).(Caller)
+	return caller, ok
+}
+
+// мерчант, которым ограничены чтение и изменение продуктов, 0 - без ограничения
+// (администратор или внутренний вызов без вызывающей стороны)
+func MerchantScope(ctx context.Context) int {
+	caller, ok := CallerFrom(ctx)
+	if !ok || caller.Admin {
+		return 0
+	}
+
+	return caller.MerchantId
+}
+
+// мерчант для выборки списка продуктов: мерчант видит только свои продукты,
+// администратор - продукты мерчанта из фильтра или всех мерчантов
+func ScopedMerchantId(ctx context.Context, merchantId int) int {
+	if scope := MerchantScope(ctx); scope != 0 {
+		return scope
+	}
+
+	return merchantId
+}
@@ -0,0 +1,60 @@
+// жизненный цикл продукта: статусы и допустимые переходы между ними
+
+package entities
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	StatusDraft      = "draft"
+	StatusActive     = "active"
+	StatusOutOfStock = "out_of_stock"
+	StatusArchived   = "archived"
+)
+
+var ErrInvalidStatusTransition = errors.New("invalid product status transition")
+
+// из какого статуса в какие можно перейти, переход в тот же статус разрешен всегда
+var statusTransitions = map[string][]string{
+	StatusDraft:      {StatusActive, StatusArchived},
+	StatusActive:     {StatusOutOfStock, StatusArchived},
+	StatusOutOfStock: {StatusActive, StatusArchived},
+	StatusArchived:   {StatusDraft},
+}
+
+// тело запроса на смену статуса продукта
+type StatusTransition struct {
+	Status string `json:"status" binding:"required"`
+}
+
+func ValidateStatus(status string) error {
+
+	if _, ok := statusTransitions[status]; !ok {
+		return fmt.Errorf("invalid status: %s, must be one of %s, %s, %s, %s",
+			status, StatusDraft, StatusActive, StatusOutOfStock, StatusArchived)
+	}
+
+	return nil
+}
+
+// проверка, что продукт может перейти из статуса from в статус to
+func ValidateStatusTransition(from, to string) error {
+
+	if err := ValidateStatus(to); err != nil {
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, from, to)
+}
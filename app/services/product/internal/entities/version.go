@@ -0,0 +1,39 @@
+// история изменений продукта: полные снимки продукта после каждого изменения
+
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// действие, после которого сохранен снимок
+const (
+	VersionActionCreate  = "create"
+	VersionActionUpdate  = "update"
+	VersionActionDelete  = "delete"
+	VersionActionRestore = "restore"
+)
+
+// снимок продукта, у снимка удаления Product - состояние продукта перед удалением
+type ProductVersion struct {
+	ProductId int         `json:"productId"`
+	Version   int         `json:"version"`
+	Action    string      `json:"action"`
+	ChangedAt time.Time   `json:"changedAt"`
+	Product   ProductInfo `json:"product"`
+}
+
+// тело запроса на восстановление версии продукта
+type VersionRestore struct {
+	Version int `json:"version" binding:"required"`
+}
+
+func ValidateVersion(version int) error {
+
+	if version <= 0 {
+		return errors.New("invalid version: can`t be less or equal 0")
+	}
+
+	return nil
+}
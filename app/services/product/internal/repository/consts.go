@@ -9,9 +9,19 @@ const (
 	productsTable = "products"
 	//её поля
 	id               = "id" //PK
-	categoryField    = "category"
+	merchantIdField  = "merchant_id"
+	categoryIdField  = "category_id"
 	describtionField = "prd_description"
 	statusField      = "prd_status"
+	//номер версии продукта, увеличивается при каждом изменении
+	versionField = "prd_version"
+	//атрибуты продукта (jsonb), проверяются по схеме категории
+	attributesField = "prd_attributes"
+	//ключевые слова через пробел, из них и описания строится search_vector
+	keywordsField     = "prd_keywords"
+	searchVectorField = "search_vector"
+	//предложенные по описанию ключевые слова, еще не подтвержденные мерчантом
+	suggestedKeywordsField = "prd_suggested_keywords"
 )
 
 const (
@@ -22,9 +32,55 @@ const (
 	kwIdField      = "kw_id"
 )
 
+const (
+	//таблица
+	categoriesTable = "categories"
+	//её поля
+	catNameField  = "cat_name"
+	parentIdField = "parent_id"
+	catPathField  = "cat_path"
+	//схема атрибутов продуктов категории (jsonb)
+	catAttributesField = "cat_attributes"
+)
+
+const (
+	//таблица (квоты мерчантов, строка создается при первом продукте мерчанта)
+	merchantsTable = "merchants"
+	//её поля
+	maxProductsField = "max_products"
+)
+
+const (
+	//таблица
+	productVersionsTable = "product_versions"
+	//её поля (product_id и prd_version - составной PK)
+	versionActionField = "action"
+	snapshotField      = "snapshot"
+	changedAtField     = "changed_at"
+)
+
+const (
+	//таблица (MinHash-сигнатуры описаний продуктов)
+	productSignaturesTable = "product_signatures"
+	//её поля (product_id - PK)
+	signatureField = "signature"
+	bandsField     = "bands"
+)
+
+const (
+	//таблица (пары дубликатов, каждая пара в обе стороны)
+	productDuplicatesTable = "product_duplicates"
+	//её поля (product_id и duplicate_id - составной PK)
+	duplicateIdField = "duplicate_id"
+	similarityField  = "similarity"
+)
+
 const (
 	//таблица
 	kwTable = "keyWords"
 	//её поля
 	kwNameField = "kw_name"
 )
+
+// параметры подсветки совпадений в ts_headline
+const headlineOptions = "StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=20, MinWords=5"
@@ -3,5 +3,17 @@ package repository
 import "errors"
 
 var (
-	ErrNotFound = errors.New("product not found")
+	ErrNotFound        = errors.New("product not found")
+	ErrVersionNotFound = errors.New("product version not found")
+	ErrQuotaExceeded   = errors.New("merchant product quota exceeded")
+
+	ErrKeyWordNotFound      = errors.New("product has no such keyword")
+	ErrKeyWordAlreadyExists = errors.New("product already has such keyword")
+	ErrKeyWordNotSuggested  = errors.New("keyword is not suggested for product")
+	ErrLastKeyWord          = errors.New("product must keep at least one keyword")
+
+	ErrCategoryNotFound      = errors.New("category not found")
+	ErrCategoryAlreadyExists = errors.New("category with such name already exists in parent category")
+	ErrCategoryInUse         = errors.New("category has subcategories or products")
+	ErrCategoryCycle         = errors.New("category can`t be moved into its own subcategory")
 )
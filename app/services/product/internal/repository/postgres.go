@@ -3,26 +3,96 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/config"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type RelationalDataBase interface {
 	AddNewProduct(ctx context.Context, product *entities.ProductInfo) (int, error)
+	ProductBulkAdder
 	UpdateProduct(ctx context.Context, productId int, uproduct *entities.ProductInfo) error
-	DeleteProduct(ctx context.Context, productId int) error
+	DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error)
+	ProductGetter
+	ProductSearcher
+	ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error)
+	ProductHistory
+	CategoryStore
+	MerchantStore
+	KeyWordStore
+	DuplicateStore
+}
+
+// MinHash-сигнатуры описаний продуктов, найденные по ним дубликаты и слияние дубликатов
+type DuplicateStore interface {
+	GetDuplicateCandidates(ctx context.Context, signature *entities.ProductSignature) ([]entities.ProductSignature, error)
+	SetProductSignature(ctx context.Context, signature *entities.ProductSignature, duplicates []entities.ProductDuplicate) error
+	GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error)
+	MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error)
+}
+
+// изменение ключевых слов продукта по одному, статистика их использования
+// и частоты терминов для извлечения ключевых слов из описаний
+type KeyWordStore interface {
+	AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error)
+	DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error)
+	ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error)
+	GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error)
+	GetDocumentFrequencies(ctx context.Context, terms []string) (int, map[string]int, error)
+}
+
+// квоты продуктов мерчантов
+type MerchantStore interface {
+	GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error)
+	SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error
+}
+
+// история изменений продукта
+type ProductHistory interface {
+	GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error)
+	GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error)
+	RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error)
+}
+
+// управление деревом категорий
+type CategoryStore interface {
+	AddCategory(ctx context.Context, category *entities.Category) (int, error)
+	GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error)
+	GetCategories(ctx context.Context) ([]entities.Category, error)
+	UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error
+	DeleteCategory(ctx context.Context, categoryId int) error
+}
+
+// пакетное добавление продуктов
+type ProductBulkAdder interface {
+	AddNewProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error)
+}
+
+// чтение продуктов вместе с их ключевыми словами
+type ProductGetter interface {
+	GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error)
+	GetProducts(ctx context.Context, filter *entities.ProductFilter) ([]entities.ProductInfo, error)
+}
+
+// полнотекстовый поиск по описанию и ключевым словам продуктов
+type ProductSearcher interface {
+	SearchProducts(ctx context.Context, search *entities.ProductSearch) ([]entities.ProductSearchHit, error)
 }
 
 // имплементация RelationalDataBase интерфейса
 type PostgresDB struct {
-	DB  *sqlx.DB
-	log *slog.Logger
+	DB      *sqlx.DB
+	log     *slog.Logger
+	timeout time.Duration
 }
 
 // установка соединения с базой, паника в случае ошиби
@@ -33,8 +103,9 @@ func NewPostgresDB(cfg config.DBConfig, log *slog.Logger) *PostgresDB {
 		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Dbname, cfg.Sslmode))
 
 	return &PostgresDB{
-		DB:  db,
-		log: log,
+		DB:      db,
+		log:     log,
+		timeout: cfg.QueryTimeout,
 	}
 }
 
@@ -42,150 +113,1494 @@ func (p *PostgresDB) AddNewProduct(ctx context.Context, product *entities.Produc
 
 	var productId int
 
-	//начинаем транзакцию
-	trx, err := p.DB.Begin()
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(trx *sql.Tx) error {
+		//проверка что категория существует, заполнение пути категории продукта
+		if err := fillProductCategory(ctx, trx, product); err != nil {
+			return err
+		}
+
+		//проверка что у мерчанта не исчерпана квота продуктов
+		if err := reserveMerchantQuota(ctx, trx, product.MerchantId); err != nil {
+			return err
+		}
+
+		attributes, err := attributesJSON(product.Attributes)
+		if err != nil {
+			return err
+		}
+
+		//формируем запрос для добавления новой записи в таблицу users
+		query := fmt.Sprintf(
+			`INSERT INTO %s 
+			 (%s, %s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6, $7) 
+			 RETURNING %s, %s`,
+			productsTable,
+			categoryIdField, describtionField, statusField, keywordsField, attributesField, merchantIdField,
+			suggestedKeywordsField,
+			id, versionField,
+		)
+
+		//выполняем запрос по добавлению нового продукта в той же транзакции
+		row := trx.QueryRowContext(ctx, query,
+			product.CategoryId, product.Description, product.Status,
+			strings.Join(product.ProductKeyWords, " "), attributes, product.MerchantId,
+			suggestedKeyWords(product))
+
+		//вычитываем полученный id
+		if err := row.Scan(&productId, &product.Version); err != nil {
+			p.log.Info(fmt.Sprintf("error scanning productID: %s", err.Error()))
+			return err
+		}
+
+		//добавление ключевых слов продукта
+		if err = addProductKeyWords(ctx, product, trx, productId, p.log); err != nil {
+			p.log.Info(fmt.Sprintf("error while addProductKeyWords: %s", err.Error()))
+			return err
+		}
+
+		//первая версия в истории продукта
+		product.ProductId = productId
+		return addProductVersion(ctx, trx, product, entities.VersionActionCreate)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	//формируем запрос для добавления новой записи в таблицу users
+	return productId, nil
+}
+
+// пакетное добавление продуктов в одной транзакции, каждый продукт добавляется под своей
+// точкой сохранения: ошибка одного продукта не откатывает остальные. Ошибки продуктов
+// возвращаются по индексам, id добавленных продуктов записываются в сами продукты
+func (p *PostgresDB) AddNewProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error) {
+
+	rowErrs := make([]error, len(products))
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(trx *sql.Tx) error {
+		query := fmt.Sprintf(
+			`INSERT INTO %s 
+			 (%s, %s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6, $7) 
+			 RETURNING %s, %s`,
+			productsTable,
+			categoryIdField, describtionField, statusField, keywordsField, attributesField, merchantIdField,
+			suggestedKeywordsField,
+			id, versionField,
+		)
+
+		for i := range products {
+			product := &products[i]
+
+			if _, err := trx.ExecContext(ctx, `SAVEPOINT bulk_row`); err != nil {
+				return err
+			}
+
+			rowErrs[i] = func() error {
+				if err := fillProductCategory(ctx, trx, product); err != nil {
+					return err
+				}
+
+				if err := reserveMerchantQuota(ctx, trx, product.MerchantId); err != nil {
+					return err
+				}
+
+				attributes, err := attributesJSON(product.Attributes)
+				if err != nil {
+					return err
+				}
+
+				if err := trx.QueryRowContext(ctx, query,
+					product.CategoryId, product.Description, product.Status,
+					strings.Join(product.ProductKeyWords, " "), attributes, product.MerchantId,
+					suggestedKeyWords(product),
+				).Scan(&product.ProductId, &product.Version); err != nil {
+					return err
+				}
+
+				if err := addProductKeyWords(ctx, product, trx, product.ProductId, p.log); err != nil {
+					return err
+				}
+
+				return addProductVersion(ctx, trx, product, entities.VersionActionCreate)
+			}()
+
+			//откат только текущего продукта, транзакция продолжается
+			if rowErrs[i] != nil {
+				product.ProductId = 0
+				if _, err := trx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT bulk_row`); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rowErrs, nil
+}
+
+func (p *PostgresDB) UpdateProduct(ctx context.Context, productId int, product *entities.ProductInfo) error {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка что продукт существует и принадлежит мерчанту, строка блокируется до конца транзакции
+		var currentStatus string
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s FOR UPDATE`,
+			statusField, productsTable, id, merchantScope("", 2),
+		)
+		rowCheck := tgx.QueryRowContext(ctx, queryCheck, productId, entities.MerchantScope(ctx))
+		if err := rowCheck.Scan(&currentStatus); err != nil {
+			if err == sql.ErrNoRows {
+				err = ErrNotFound
+			}
+			return err
+		}
+
+		//проверка что смена статуса допустима
+		if err := entities.ValidateStatusTransition(currentStatus, product.Status); err != nil {
+			return err
+		}
+
+		//проверка что категория существует, заполнение пути категории продукта
+		if err := fillProductCategory(ctx, tgx, product); err != nil {
+			return err
+		}
+
+		if err := updateProductRow(ctx, tgx, productId, product, p.log); err != nil {
+			return err
+		}
+
+		product.ProductId = productId
+		return addProductVersion(ctx, tgx, product, entities.VersionActionUpdate)
+	})
+}
+
+func (p *PostgresDB) DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	var product *entities.ProductInfo
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка что продукт существует и принадлежит мерчанту, строка блокируется до конца транзакции
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s FOR UPDATE`,
+			id, productsTable, id, merchantScope("", 2),
+		)
+		if err := tgx.QueryRowContext(ctx, queryCheck, productId, entities.MerchantScope(ctx)).Scan(&productId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
+
+		//снимок удаленного продукта с версией удаления уходит в событие
+		deleted, err := deleteLockedProduct(ctx, tgx, productId)
+		if err != nil {
+			return err
+		}
+		product = deleted
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// удаление заблокированного в транзакции продукта, возвращает снимок продукта перед удалением
+func deleteLockedProduct(ctx context.Context, trx *sql.Tx, productId int) (*entities.ProductInfo, error) {
+
+	//снимок продукта перед удалением - последняя версия в его истории
+	queryGet := selectProductsWithKeyWords(fmt.Sprintf(`p.%s = $1`, id))
+	product, err := scanProduct(trx.QueryRowContext(ctx, queryGet, productId))
+	if err != nil {
+		return nil, err
+	}
+	product.Version++
+	if err := addProductVersion(ctx, trx, product, entities.VersionActionDelete); err != nil {
+		return nil, err
+	}
+
+	//удание ключевых слов продукта
+	queryDeleteInterests := fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = $1`,
+		productKwTable, productIdField,
+	)
+	if _, err := trx.ExecContext(ctx, queryDeleteInterests, productId); err != nil {
+		return nil, err
+	}
+
+	//сигнатура и пары дубликатов продукта удаляются каскадно
 	query := fmt.Sprintf(
-		`INSERT INTO %s 
-		 (%s, %s, %s) VALUES ($1, $2, $3) 
-		 RETURNING %s`,
+		`DELETE FROM %s WHERE %s = $1`,
 		productsTable,
-		categoryField, describtionField, statusField,
 		id,
 	)
+	if _, err := trx.ExecContext(ctx, query, productId); err != nil {
+		return nil, err
+	}
 
-	//выполняем запрос по добавлению нового продукта
-	row := p.DB.QueryRow(query,
-		product.Category, product.Description, product.Status)
+	return product, nil
+}
 
-	//вычитываем полученный id
-	if err := row.Scan(&productId); err != nil {
-		trx.Rollback()
-		p.log.Info(fmt.Sprintf("error scanning productID: %s", err.Error()))
-		return 0, err
-	}
+// смена статуса продукта, возвращает продукт в новом статусе
+func (p *PostgresDB) ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error) {
 
-	//добавление ключевых слов продукта
-	if err = addProductKeyWords(product, trx, productId, p.log); err != nil {
-		trx.Rollback()
-		p.log.Info(fmt.Sprintf("error while addProductKeyWords: %s", err.Error()))
-		return 0, err
+	var product *entities.ProductInfo
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка что продукт существует и принадлежит мерчанту, строка блокируется до конца транзакции
+		var currentStatus string
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s FOR UPDATE`,
+			statusField, productsTable, id, merchantScope("", 2),
+		)
+		if err := tgx.QueryRowContext(ctx, queryCheck, productId, entities.MerchantScope(ctx)).Scan(&currentStatus); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
+
+		if err := entities.ValidateStatusTransition(currentStatus, status); err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`UPDATE %s SET %s = $1, %s = %s + 1 WHERE %s = $2`,
+			productsTable, statusField, versionField, versionField, id,
+		)
+		if _, err := tgx.ExecContext(ctx, query, status, productId); err != nil {
+			return err
+		}
+
+		queryGet := selectProductsWithKeyWords(fmt.Sprintf(`p.%s = $1`, id))
+		var err error
+		if product, err = scanProduct(tgx.QueryRowContext(ctx, queryGet, productId)); err != nil {
+			return err
+		}
+
+		return addProductVersion(ctx, tgx, product, entities.VersionActionUpdate)
+	})
+	if err != nil {
+		return nil, err
 	}
-	//ураа все получилось, коммит
-	trx.Commit()
 
-	return productId, nil
+	return product, nil
 }
 
-func (p *PostgresDB) UpdateProduct(ctx context.Context, productId int, product *entities.ProductInfo) error {
+// все версии продукта в порядке возрастания, в том числе версия удаления
+func (p *PostgresDB) GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error) {
 
-	tgx, err := p.DB.Begin()
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(
+		`SELECT %s, %s, %s, %s FROM %s 
+		 WHERE %s = $1 AND ($2::int = 0 OR (%s->>'merchantId')::int = $2) 
+		 ORDER BY %s`,
+		versionField, versionActionField, snapshotField, changedAtField, productVersionsTable,
+		productIdField, snapshotField,
+		versionField,
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query, productId, entities.MerchantScope(ctx))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	//проверка что продукт существует
-	rowCheck := tgx.QueryRow(`SELECT id FROM products WHERE id = $1`, productId)
-	if err := rowCheck.Scan(&productId); err != nil {
-		tgx.Rollback()
-		if err == sql.ErrNoRows {
-			err = ErrNotFound
+	history := make([]entities.ProductVersion, 0)
+	for rows.Next() {
+		var (
+			version  = entities.ProductVersion{ProductId: productId}
+			snapshot []byte
+		)
+		if err := rows.Scan(&version.Version, &version.Action, &snapshot, &version.ChangedAt); err != nil {
+			return nil, err
 		}
-		return err
+		if err := json.Unmarshal(snapshot, &version.Product); err != nil {
+			return nil, err
+		}
+		history = append(history, version)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return history, nil
+}
+
+// состояние продукта на момент asOf - последний снимок, сделанный не позже asOf,
+// если продукт к этому моменту еще не создан или уже удален - ErrNotFound
+func (p *PostgresDB) GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
 	query := fmt.Sprintf(
-		`UPDATE %s 
-		 SET %s = $1, %s = $2, %s = $3 
-		 WHERE %s = $4`,
-		productsTable,
-		categoryField, describtionField, statusField,
-		id,
+		`SELECT %s, %s FROM %s 
+		 WHERE %s = $1 AND %s <= $2 AND ($3::int = 0 OR (%s->>'merchantId')::int = $3) 
+		 ORDER BY %s DESC 
+		 LIMIT 1`,
+		versionActionField, snapshotField, productVersionsTable,
+		productIdField, changedAtField, snapshotField,
+		versionField,
 	)
 
-	if _, err := tgx.Exec(query,
-		product.Category, product.Description, product.Status,
-		productId,
-	); err != nil {
-		tgx.Rollback()
-		return err
+	var (
+		action   string
+		snapshot []byte
+	)
+	if err := p.DB.QueryRowContext(ctx, query, productId, asOf, entities.MerchantScope(ctx)).Scan(&action, &snapshot); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
 	}
 
-	//удание старых ключевых слов продукта
-	queryDeleteInterests := fmt.Sprintf(
-		`DELETE FROM %s WHERE %s = $1`,
-		productKwTable, productIdField,
-	)
-	if _, err := tgx.Exec(queryDeleteInterests, productId); err != nil {
-		tgx.Rollback()
-		return err
+	if action == entities.VersionActionDelete {
+		return nil, ErrNotFound
+	}
 
+	var product entities.ProductInfo
+	if err := json.Unmarshal(snapshot, &product); err != nil {
+		return nil, err
 	}
 
-	//добавление новых интересов пользователя
-	if err := addProductKeyWords(product, tgx, productId, p.log); err != nil {
-		tgx.Rollback()
-		return err
+	return &product, nil
+}
+
+// восстановление продукта из снимка версии, восстановленное состояние сохраняется
+// новой версией. Операция администратора: допустимость смены статуса не проверяется
+func (p *PostgresDB) RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error) {
+
+	var product entities.ProductInfo
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка что продукт существует, строка блокируется до конца транзакции
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 FOR UPDATE`, id, productsTable, id)
+		if err := tgx.QueryRowContext(ctx, queryCheck, productId).Scan(&productId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
+
+		var snapshot []byte
+		querySnapshot := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s = $2`,
+			snapshotField, productVersionsTable, productIdField, versionField,
+		)
+		if err := tgx.QueryRowContext(ctx, querySnapshot, productId, version).Scan(&snapshot); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrVersionNotFound
+			}
+			return err
+		}
+
+		if err := json.Unmarshal(snapshot, &product); err != nil {
+			return err
+		}
+
+		//категория версии могла быть удалена, имя и путь берутся текущие
+		if err := fillProductCategory(ctx, tgx, &product); err != nil {
+			return err
+		}
+
+		if err := updateProductRow(ctx, tgx, productId, &product, p.log); err != nil {
+			return err
+		}
+
+		product.ProductId = productId
+		return addProductVersion(ctx, tgx, &product, entities.VersionActionRestore)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	tgx.Commit()
-	return nil
+	return &product, nil
 }
 
-func (p *PostgresDB) DeleteProduct(ctx context.Context, productId int) error {
+func (p *PostgresDB) GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := selectProductsWithKeyWords(fmt.Sprintf(`p.%s = $1 AND %s`, id, merchantScope("p.", 2)))
+
+	product, err := scanProduct(p.DB.QueryRowContext(ctx, query, productId, entities.MerchantScope(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (p *PostgresDB) GetProducts(ctx context.Context, filter *entities.ProductFilter) ([]entities.ProductInfo, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
 
-	tgx, err := p.DB.Begin()
+	//пустое значение фильтра означает отсутствие ограничения по полю,
+	//фильтр по категории включает все ее подкатегории
+	query := fmt.Sprintf(
+		`%s 
+		 ORDER BY p.%s 
+		 LIMIT $5`,
+		selectProductsWithKeyWords(fmt.Sprintf(
+			`p.%s > $1 
+			 AND ($2::int = 0 OR c.%s @> ARRAY[$2::int]) 
+			 AND ($3::text = '' OR p.%s = $3) 
+			 AND ($4::text = '' OR EXISTS (
+			 	SELECT 1 FROM %s fpk JOIN %s fk ON fk.%s = fpk.%s 
+			 	WHERE fpk.%s = p.%s AND fk.%s = $4)) 
+			 AND %s`,
+			id,
+			catPathField,
+			statusField,
+			productKwTable, kwTable, id, kwIdField,
+			productIdField, id, kwNameField,
+			merchantScope("p.", 6),
+		)),
+		id,
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query,
+		filter.Cursor, filter.CategoryId, filter.Status, filter.Keyword, filter.Limit,
+		entities.ScopedMerchantId(ctx, filter.MerchantId))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	//проверка что пользователь существует
-	rowCheck := p.DB.QueryRow(`SELECT id FROM products WHERE id = $1`, productId)
-	if err := rowCheck.Scan(&productId); err != nil {
-		if err == sql.ErrNoRows {
-			err = ErrNotFound
+	defer rows.Close()
+
+	products := make([]entities.ProductInfo, 0, filter.Limit)
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
 		}
-		tgx.Rollback()
-		return err
+		products = append(products, *product)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
+	return products, nil
+}
+
+func (p *PostgresDB) SearchProducts(ctx context.Context, search *entities.ProductSearch) ([]entities.ProductSearchHit, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	//конфигурация поиска ($1) выбирается по языку запроса, подсветка - в описании продукта
 	query := fmt.Sprintf(
-		`DELETE FROM %s WHERE %s = $1`,
+		`SELECT p.%s, p.%s, c.%s, %s, p.%s, p.%s, p.%s, p.%s, p.%s, 
+		 (SELECT COALESCE(array_agg(k.%s ORDER BY k.%s), '{}') 
+		 	FROM %s pk JOIN %s k ON k.%s = pk.%s WHERE pk.%s = p.%s), 
+		 ts_rank(p.%s, q.query) AS rank, 
+		 ts_headline($1::regconfig, p.%s, q.query, $3) 
+		 FROM %s p 
+		 JOIN %s c ON c.%s = p.%s 
+		 CROSS JOIN websearch_to_tsquery($1::regconfig, $2) AS q(query) 
+		 WHERE p.%s @@ q.query 
+		 AND ($4::int = 0 OR c.%s @> ARRAY[$4::int]) 
+		 AND ($5::text = '' OR p.%s = $5) 
+		 AND %s 
+		 ORDER BY rank DESC, p.%s 
+		 LIMIT $6 OFFSET $7`,
+		id, categoryIdField, catNameField, categoryPathNames("c"), describtionField, statusField, versionField,
+		attributesField, merchantIdField,
+		kwNameField, kwNameField,
+		productKwTable, kwTable, id, kwIdField, productIdField, id,
+		searchVectorField,
+		describtionField,
 		productsTable,
+		categoriesTable, id, categoryIdField,
+		searchVectorField,
+		catPathField,
+		statusField,
+		merchantScope("p.", 8),
 		id,
 	)
 
-	if _, err := p.DB.Exec(query, productId); err != nil {
-		tgx.Rollback()
-		return err
+	rows, err := p.DB.QueryContext(ctx, query,
+		search.Config(), search.Query, headlineOptions,
+		search.CategoryId, search.Status, search.Limit, search.Offset,
+		entities.ScopedMerchantId(ctx, search.MerchantId))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	//удание старых ключевых слов продукта
-	queryDeleteInterests := fmt.Sprintf(
-		`DELETE FROM %s WHERE %s = $1`,
-		productKwTable, productIdField,
+	hits := make([]entities.ProductSearchHit, 0, search.Limit)
+	for rows.Next() {
+		var (
+			hit        entities.ProductSearchHit
+			attributes []byte
+		)
+		if err := rows.Scan(
+			&hit.ProductId, &hit.CategoryId, &hit.Category, pq.Array(&hit.CategoryPath),
+			&hit.Description, &hit.Status, &hit.Version, &attributes, &hit.MerchantId,
+			pq.Array(&hit.ProductKeyWords), &hit.Rank, &hit.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attributes, &hit.Attributes); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+// запрос на чтение продуктов, удовлетворяющих условию, ключевые слова агрегируются в массив
+func selectProductsWithKeyWords(conditions string) string {
+	return fmt.Sprintf(
+		`SELECT p.%s, p.%s, c.%s, %s, p.%s, p.%s, p.%s, p.%s, p.%s, p.%s, 
+		 COALESCE(array_agg(k.%s ORDER BY k.%s) FILTER (WHERE k.%s IS NOT NULL), '{}') 
+		 FROM %s p 
+		 JOIN %s c ON c.%s = p.%s 
+		 LEFT JOIN %s pk ON pk.%s = p.%s 
+		 LEFT JOIN %s k ON k.%s = pk.%s 
+		 WHERE %s 
+		 GROUP BY p.%s, c.%s`,
+		id, categoryIdField, catNameField, categoryPathNames("c"), describtionField, statusField, versionField,
+		attributesField, merchantIdField, suggestedKeywordsField,
+		kwNameField, kwNameField, kwNameField,
+		productsTable,
+		categoriesTable, id, categoryIdField,
+		productKwTable, productIdField, id,
+		kwTable, id, kwIdField,
+		conditions,
+		id, id,
 	)
-	if _, err := tgx.Exec(queryDeleteInterests, productId); err != nil {
-		tgx.Rollback()
-		return err
+}
 
+// подзапрос, возвращающий имена категорий из материализованного пути категории alias
+func categoryPathNames(alias string) string {
+	return fmt.Sprintf(
+		`(SELECT array_agg(a.%s ORDER BY array_position(%s.%s, a.%s)) 
+		 FROM %s a WHERE a.%s = ANY(%s.%s))`,
+		catNameField, alias, catPathField, id,
+		categoriesTable, id, alias, catPathField,
+	)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProduct(row rowScanner) (*entities.ProductInfo, error) {
+	var (
+		product    entities.ProductInfo
+		attributes []byte
+	)
+
+	if err := row.Scan(
+		&product.ProductId, &product.CategoryId, &product.Category, pq.Array(&product.CategoryPath),
+		&product.Description, &product.Status, &product.Version, &attributes, &product.MerchantId,
+		pq.Array(&product.SuggestedKeyWords), pq.Array(&product.ProductKeyWords),
+	); err != nil {
+		return nil, err
 	}
 
-	return nil
+	if err := json.Unmarshal(attributes, &product.Attributes); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// атрибуты продукта для записи в jsonb, отсутствие атрибутов - пустой объект
+func attributesJSON(attributes map[string]any) ([]byte, error) {
+	if attributes == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attributes)
+}
+
+func (p *PostgresDB) AddCategory(ctx context.Context, category *entities.Category) (int, error) {
+
+	var categoryId int
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(trx *sql.Tx) error {
+		//путь родительской категории, у корневой категории путь пустой
+		parentPath, err := getCategoryIdPath(ctx, trx, category.ParentId)
+		if err != nil {
+			return err
+		}
+
+		schema, err := schemaJSON(category.Attributes)
+		if err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`INSERT INTO %s (%s, %s, %s) VALUES ($1, $2, $3) RETURNING %s`,
+			categoriesTable, catNameField, parentIdField, catAttributesField, id,
+		)
+		if err := trx.QueryRowContext(ctx, query,
+			category.Name, nullableId(category.ParentId), schema,
+		).Scan(&categoryId); err != nil {
+			return uniqueViolation(err, ErrCategoryAlreadyExists)
+		}
+
+		//путь новой категории - путь родителя и ее собственный id
+		queryPath := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2`,
+			categoriesTable, catPathField, id,
+		)
+		if _, err := trx.ExecContext(ctx, queryPath,
+			pq.Array(append(parentPath, int64(categoryId))), categoryId,
+		); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return categoryId, nil
+}
+
+func (p *PostgresDB) GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`%s WHERE c.%s = $1`, selectCategories(), id)
+
+	category, err := scanCategory(p.DB.QueryRowContext(ctx, query, categoryId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCategoryNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// все категории, упорядоченные так, что родитель идет раньше своих подкатегорий
+func (p *PostgresDB) GetCategories(ctx context.Context) ([]entities.Category, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`%s ORDER BY c.%s`, selectCategories(), catPathField)
+
+	rows, err := p.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]entities.Category, 0)
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// переименование и/или перенос категории вместе со всем ее поддеревом
+func (p *PostgresDB) UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(trx *sql.Tx) error {
+		//проверка что категория существует, строка блокируется до конца транзакции
+		var oldPath pq.Int64Array
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 FOR UPDATE`,
+			catPathField, categoriesTable, id,
+		)
+		if err := trx.QueryRowContext(ctx, queryCheck, categoryId).Scan(&oldPath); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrCategoryNotFound
+			}
+			return err
+		}
+
+		parentPath, err := getCategoryIdPath(ctx, trx, category.ParentId)
+		if err != nil {
+			return err
+		}
+
+		//новый родитель не может находиться в поддереве переносимой категории
+		for _, ancestorId := range parentPath {
+			if ancestorId == int64(categoryId) {
+				return ErrCategoryCycle
+			}
+		}
+
+		schema, err := schemaJSON(category.Attributes)
+		if err != nil {
+			return err
+		}
+
+		//изменение схемы не перепроверяет атрибуты уже добавленных продуктов
+		query := fmt.Sprintf(`UPDATE %s SET %s = $1, %s = $2, %s = $3 WHERE %s = $4`,
+			categoriesTable, catNameField, parentIdField, catAttributesField, id,
+		)
+		if _, err := trx.ExecContext(ctx, query,
+			category.Name, nullableId(category.ParentId), schema, categoryId,
+		); err != nil {
+			return uniqueViolation(err, ErrCategoryAlreadyExists)
+		}
+
+		//замена префикса пути у категории и всех ее подкатегорий
+		newPath := append(parentPath, int64(categoryId))
+		queryPath := fmt.Sprintf(
+			`UPDATE %s SET %s = $1::int[] || %s[$2:] WHERE %s @> ARRAY[$3::int]`,
+			categoriesTable, catPathField, catPathField, catPathField,
+		)
+		if _, err := trx.ExecContext(ctx, queryPath,
+			pq.Array(newPath), len(oldPath)+1, categoryId,
+		); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// удаление категории, у которой нет подкатегорий и продуктов
+func (p *PostgresDB) DeleteCategory(ctx context.Context, categoryId int) error {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(trx *sql.Tx) error {
+		var inUse bool
+		queryCheck := fmt.Sprintf(
+			`SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1) 
+			 OR EXISTS (SELECT 1 FROM %s WHERE %s = $1) 
+			 FROM %s WHERE %s = $1 FOR UPDATE`,
+			categoriesTable, parentIdField,
+			productsTable, categoryIdField,
+			categoriesTable, id,
+		)
+		if err := trx.QueryRowContext(ctx, queryCheck, categoryId).Scan(&inUse); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrCategoryNotFound
+			}
+			return err
+		}
+
+		if inUse {
+			return ErrCategoryInUse
+		}
+
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, categoriesTable, id)
+		if _, err := trx.ExecContext(ctx, query, categoryId); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// мерчант с числом его продуктов, квота мерчанта без записи в merchants - квота по умолчанию
+func (p *PostgresDB) GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	merchant := entities.Merchant{MerchantId: merchantId}
+
+	query := fmt.Sprintf(
+		`SELECT COALESCE((SELECT %s FROM %s WHERE %s = $1), $2), 
+		 (SELECT count(*) FROM %s WHERE %s = $1)`,
+		maxProductsField, merchantsTable, id,
+		productsTable, merchantIdField,
+	)
+	if err := p.DB.QueryRowContext(ctx, query, merchantId, entities.DefaultMerchantQuota).Scan(
+		&merchant.MaxProducts, &merchant.ProductCount,
+	); err != nil {
+		return nil, err
+	}
+
+	return &merchant, nil
+}
+
+// установка квоты мерчанта, уже добавленные продукты сверх квоты не удаляются
+func (p *PostgresDB) SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(
+		`INSERT INTO %s (%s, %s) VALUES ($1, $2) 
+		 ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s`,
+		merchantsTable, id, maxProductsField,
+		id, maxProductsField, maxProductsField,
+	)
+	if _, err := p.DB.ExecContext(ctx, query, merchantId, maxProducts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// добавление одного ключевого слова продукту, возвращает продукт с новым набором ключевых слов
+func (p *PostgresDB) AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	return p.changeProductKeyWords(ctx, productId, func(product *entities.ProductInfo) ([]string, []string, error) {
+		if slices.Contains(product.ProductKeyWords, keyWord) {
+			return nil, nil, ErrKeyWordAlreadyExists
+		}
+		return []string{keyWord}, nil, nil
+	})
+}
+
+// удаление одного ключевого слова продукта, возвращает продукт с новым набором ключевых слов
+func (p *PostgresDB) DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	return p.changeProductKeyWords(ctx, productId, func(product *entities.ProductInfo) ([]string, []string, error) {
+		if !slices.Contains(product.ProductKeyWords, keyWord) {
+			return nil, nil, ErrKeyWordNotFound
+		}
+		//у продукта остается хотя бы одно ключевое слово, иначе он выпадает из рекомендаций
+		if len(product.ProductKeyWords) == 1 {
+			return nil, nil, ErrLastKeyWord
+		}
+		return nil, []string{keyWord}, nil
+	})
+}
+
+// подтверждение предложенных ключевых слов, пустой keyWords - подтверждение всех предложенных
+func (p *PostgresDB) ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error) {
+	return p.changeProductKeyWords(ctx, productId, func(product *entities.ProductInfo) ([]string, []string, error) {
+		if len(keyWords) == 0 {
+			keyWords = product.SuggestedKeyWords
+		}
+		if len(keyWords) == 0 {
+			return nil, nil, ErrKeyWordNotSuggested
+		}
+
+		added := make([]string, 0, len(keyWords))
+		for _, keyWord := range keyWords {
+			if !slices.Contains(product.SuggestedKeyWords, keyWord) {
+				return nil, nil, fmt.Errorf("%w: %s", ErrKeyWordNotSuggested, keyWord)
+			}
+			if !slices.Contains(product.ProductKeyWords, keyWord) && !slices.Contains(added, keyWord) {
+				added = append(added, keyWord)
+			}
+		}
+		return added, nil, nil
+	})
+}
+
+// изменение ключевых слов продукта под блокировкой его строки, change по текущему продукту
+// возвращает добавляемые и удаляемые ключевые слова. Каждое изменение - новая версия продукта,
+// ставшие ключевыми словами предложения убираются из предложенных
+func (p *PostgresDB) changeProductKeyWords(ctx context.Context, productId int,
+	change func(product *entities.ProductInfo) ([]string, []string, error)) (*entities.ProductInfo, error) {
+
+	var product *entities.ProductInfo
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка что продукт существует и принадлежит мерчанту, строка блокируется до конца транзакции
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s FOR UPDATE`,
+			id, productsTable, id, merchantScope("", 2),
+		)
+		if err := tgx.QueryRowContext(ctx, queryCheck, productId, entities.MerchantScope(ctx)).Scan(&productId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
+
+		queryGet := selectProductsWithKeyWords(fmt.Sprintf(`p.%s = $1`, id))
+		var err error
+		if product, err = scanProduct(tgx.QueryRowContext(ctx, queryGet, productId)); err != nil {
+			return err
+		}
+
+		added, removed, err := change(product)
+		if err != nil {
+			return err
+		}
+
+		return applyProductKeyWords(ctx, tgx, product, added, removed, p.log)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// добавление и удаление ключевых слов заблокированного в транзакции продукта с новой версией,
+// новый набор ключевых слов, предложений и номер версии записываются в product
+func applyProductKeyWords(ctx context.Context, trx *sql.Tx, product *entities.ProductInfo,
+	added []string, removed []string, log *slog.Logger) error {
+
+	//добавление новых ключевых слов
+	if err := addProductKeyWords(ctx, &entities.ProductInfo{ProductKeyWords: added}, trx, product.ProductId, log); err != nil {
+		return err
+	}
+
+	//удаление ключевых слов
+	if len(removed) != 0 {
+		queryDelete := fmt.Sprintf(
+			`DELETE FROM %s WHERE %s = $1 AND %s IN (SELECT %s FROM %s WHERE %s = ANY($2))`,
+			productKwTable, productIdField, kwIdField, id, kwTable, kwNameField,
+		)
+		if _, err := trx.ExecContext(ctx, queryDelete, product.ProductId, pq.Array(removed)); err != nil {
+			return err
+		}
+	}
+
+	//новый набор ключевых слов и предложений продукта
+	product.ProductKeyWords = slices.DeleteFunc(append(product.ProductKeyWords, added...), func(keyWord string) bool {
+		return slices.Contains(removed, keyWord)
+	})
+	slices.Sort(product.ProductKeyWords)
+	product.SuggestedKeyWords = slices.DeleteFunc(product.SuggestedKeyWords, func(keyWord string) bool {
+		return slices.Contains(product.ProductKeyWords, keyWord)
+	})
+
+	//ключевые слова для полнотекстового поиска и новая версия продукта
+	query := fmt.Sprintf(`UPDATE %s SET %s = $1, %s = $2, %s = %s + 1 WHERE %s = $3 RETURNING %s`,
+		productsTable, keywordsField, suggestedKeywordsField, versionField, versionField, id, versionField,
+	)
+	if err := trx.QueryRowContext(ctx, query,
+		strings.Join(product.ProductKeyWords, " "), suggestedKeyWords(product), product.ProductId,
+	).Scan(&product.Version); err != nil {
+		return err
+	}
+
+	return addProductVersion(ctx, trx, product, entities.VersionActionUpdate)
+}
+
+// число продуктов, в описании или ключевых словах которых встречается каждый из терминов
+// (с учетом словоформ русского и английского языков), и общее число продуктов каталога
+func (p *PostgresDB) GetDocumentFrequencies(ctx context.Context, terms []string) (int, map[string]int, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var total int
+	queryTotal := fmt.Sprintf(`SELECT count(*) FROM %s`, productsTable)
+	if err := p.DB.QueryRowContext(ctx, queryTotal).Scan(&total); err != nil {
+		return 0, nil, err
+	}
+
+	query := fmt.Sprintf(
+		`SELECT t.term, 
+		 (SELECT count(*) FROM %s p 
+		 	WHERE p.%s @@ (plainto_tsquery('russian', t.term) || plainto_tsquery('english', t.term))) 
+		 FROM unnest($1::text[]) AS t(term)`,
+		productsTable, searchVectorField,
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query, pq.Array(terms))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	frequencies := make(map[string]int, len(terms))
+	for rows.Next() {
+		var (
+			term      string
+			frequency int
+		)
+		if err := rows.Scan(&term, &frequency); err != nil {
+			return 0, nil, err
+		}
+		frequencies[term] = frequency
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return total, frequencies, nil
+}
+
+// все ключевые слова с числом продуктов, в которых они используются, по убыванию числа продуктов
+func (p *PostgresDB) GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(
+		`SELECT k.%s, count(pk.%s) FROM %s k 
+		 LEFT JOIN %s pk ON pk.%s = k.%s 
+		 GROUP BY k.%s 
+		 ORDER BY count(pk.%s) DESC, k.%s`,
+		kwNameField, productIdField, kwTable,
+		productKwTable, kwIdField, id,
+		id,
+		productIdField, kwNameField,
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keyWords := make([]entities.KeyWordUsage, 0)
+	for rows.Next() {
+		var usage entities.KeyWordUsage
+		if err := rows.Scan(&usage.KeyWord, &usage.Products); err != nil {
+			return nil, err
+		}
+		keyWords = append(keyWords, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keyWords, nil
+}
+
+// продукты, у сигнатур которых есть общая с signature полоса, кроме самого продукта
+func (p *PostgresDB) GetDuplicateCandidates(ctx context.Context, signature *entities.ProductSignature) ([]entities.ProductSignature, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s && $1 AND %s <> $2 ORDER BY %s`,
+		productIdField, signatureField, productSignaturesTable,
+		bandsField, productIdField, productIdField,
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query, pq.Array(signature.Bands), signature.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]entities.ProductSignature, 0)
+	for rows.Next() {
+		var candidate entities.ProductSignature
+		if err := rows.Scan(&candidate.ProductId, pq.Array(&candidate.Signature)); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// сохранение сигнатуры продукта и замена всех пар дубликатов с его участием
+func (p *PostgresDB) SetProductSignature(ctx context.Context, signature *entities.ProductSignature, duplicates []entities.ProductDuplicate) error {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		querySignature := fmt.Sprintf(
+			`INSERT INTO %s (%s, %s, %s) VALUES ($1, $2, $3) 
+			 ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s`,
+			productSignaturesTable, productIdField, signatureField, bandsField,
+			productIdField, signatureField, signatureField, bandsField, bandsField,
+		)
+		if _, err := tgx.ExecContext(ctx, querySignature,
+			signature.ProductId, pq.Array(signature.Signature), pq.Array(signature.Bands),
+		); err != nil {
+			return err
+		}
+
+		queryDelete := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1 OR %s = $1`,
+			productDuplicatesTable, productIdField, duplicateIdField,
+		)
+		if _, err := tgx.ExecContext(ctx, queryDelete, signature.ProductId); err != nil {
+			return err
+		}
+
+		if len(duplicates) != 0 {
+			duplicateIds := make([]int64, len(duplicates))
+			similarities := make([]float64, len(duplicates))
+			for i, duplicate := range duplicates {
+				duplicateIds[i] = int64(duplicate.ProductId)
+				similarities[i] = duplicate.Similarity
+			}
+
+			//каждая пара в обе стороны
+			queryInsert := fmt.Sprintf(
+				`INSERT INTO %s (%s, %s, %s) 
+				 SELECT $1, d.id, d.similarity FROM unnest($2::int[], $3::float8[]) AS d(id, similarity) 
+				 UNION ALL 
+				 SELECT d.id, $1, d.similarity FROM unnest($2::int[], $3::float8[]) AS d(id, similarity)`,
+				productDuplicatesTable, productIdField, duplicateIdField, similarityField,
+			)
+			if _, err := tgx.ExecContext(ctx, queryInsert,
+				signature.ProductId, pq.Array(duplicateIds), pq.Array(similarities),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// дубликаты продукта по убыванию сходства, мерчанту видны только его продукты
+func (p *PostgresDB) GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	merchantId := entities.MerchantScope(ctx)
+
+	//проверка что продукт существует и принадлежит мерчанту
+	queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s`,
+		id, productsTable, id, merchantScope("", 2),
+	)
+	if err := p.DB.QueryRowContext(ctx, queryCheck, productId, merchantId).Scan(&productId); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(
+		`SELECT d.%s, d.%s FROM %s d 
+		 JOIN %s p ON p.%s = d.%s 
+		 WHERE d.%s = $1 AND %s 
+		 ORDER BY d.%s DESC, d.%s`,
+		duplicateIdField, similarityField, productDuplicatesTable,
+		productsTable, id, duplicateIdField,
+		productIdField, merchantScope("p.", 2),
+		similarityField, duplicateIdField,
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query, productId, merchantId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	duplicates := make([]entities.ProductDuplicate, 0)
+	for rows.Next() {
+		var duplicate entities.ProductDuplicate
+		if err := rows.Scan(&duplicate.ProductId, &duplicate.Similarity); err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return duplicates, nil
+}
+
+// слияние дубликата с продуктом в одной транзакции: недостающие ключевые слова дубликата
+// добавляются продукту (новая версия продукта), дубликат удаляется
+func (p *PostgresDB) MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error) {
+
+	merge := &entities.ProductMergeResult{}
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//обе строки блокируются в порядке id, чтобы встречные слияния не заблокировали друг друга
+		queryLock := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = ANY($1) AND %s ORDER BY %s FOR UPDATE`,
+			id, productsTable, id, merchantScope("", 2), id,
+		)
+		rows, err := tgx.QueryContext(ctx, queryLock,
+			pq.Array([]int64{int64(productId), int64(duplicateId)}), entities.MerchantScope(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		locked := 0
+		for rows.Next() {
+			locked++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if locked != 2 {
+			return ErrNotFound
+		}
+
+		queryGet := selectProductsWithKeyWords(fmt.Sprintf(`p.%s = $1`, id))
+		if merge.Product, err = scanProduct(tgx.QueryRowContext(ctx, queryGet, productId)); err != nil {
+			return err
+		}
+
+		if merge.Duplicate, err = deleteLockedProduct(ctx, tgx, duplicateId); err != nil {
+			return err
+		}
+
+		added := make([]string, 0)
+		for _, keyWord := range merge.Duplicate.ProductKeyWords {
+			if !slices.Contains(merge.Product.ProductKeyWords, keyWord) && !slices.Contains(added, keyWord) {
+				added = append(added, keyWord)
+			}
+		}
+		if len(added) == 0 {
+			return nil
+		}
+
+		merge.Updated = true
+		return applyProductKeyWords(ctx, tgx, merge.Product, added, nil, p.log)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merge, nil
+}
+
+func selectCategories() string {
+	return fmt.Sprintf(
+		`SELECT c.%s, c.%s, COALESCE(c.%s, 0), %s, c.%s FROM %s c`,
+		id, catNameField, parentIdField, categoryPathNames("c"), catAttributesField, categoriesTable,
+	)
+}
+
+func scanCategory(row rowScanner) (*entities.Category, error) {
+	var (
+		category entities.Category
+		schema   []byte
+	)
+
+	if err := row.Scan(
+		&category.CategoryId, &category.Name, &category.ParentId, pq.Array(&category.Path), &schema,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(schema, &category.Attributes); err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// материализованный путь категории (id от корня), для categoryId = 0 - пустой путь
+func getCategoryIdPath(ctx context.Context, trx *sql.Tx, categoryId int) ([]int64, error) {
+	var path pq.Int64Array
+
+	if categoryId == 0 {
+		return []int64{}, nil
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`,
+		catPathField, categoriesTable, id,
+	)
+	if err := trx.QueryRowContext(ctx, query, categoryId).Scan(&path); errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCategoryNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return path, nil
+}
+
+// схема атрибутов категории для записи в jsonb, отсутствие схемы - пустой массив
+func schemaJSON(schema []entities.AttributeSchema) ([]byte, error) {
+	if schema == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(schema)
+}
+
+// id 0 означает отсутствие родителя и записывается как NULL
+func nullableId(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// условие принадлежности продукта мерчанту из параметра $n, 0 - продукты всех мерчантов
+func merchantScope(alias string, n int) string {
+	return fmt.Sprintf(`($%d::int = 0 OR %s%s = $%d)`, n, alias, merchantIdField, n)
+}
+
+// проверка что мерчант может добавить еще один продукт, строка квоты мерчанта
+// блокируется до конца транзакции, чтобы параллельные добавления не превысили квоту
+func reserveMerchantQuota(ctx context.Context, trx *sql.Tx, merchantId int) error {
+
+	queryDefault := fmt.Sprintf(
+		`INSERT INTO %s (%s, %s) VALUES ($1, $2) ON CONFLICT (%s) DO NOTHING`,
+		merchantsTable, id, maxProductsField, id,
+	)
+	if _, err := trx.ExecContext(ctx, queryDefault, merchantId, entities.DefaultMerchantQuota); err != nil {
+		return err
+	}
+
+	var maxProducts, productCount int
+	queryQuota := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 FOR UPDATE`,
+		maxProductsField, merchantsTable, id,
+	)
+	if err := trx.QueryRowContext(ctx, queryQuota, merchantId).Scan(&maxProducts); err != nil {
+		return err
+	}
+
+	queryCount := fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s = $1`, productsTable, merchantIdField)
+	if err := trx.QueryRowContext(ctx, queryCount, merchantId).Scan(&productCount); err != nil {
+		return err
+	}
+
+	if productCount >= maxProducts {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// ошибка уникальности (код 23505) заменяется на доменную ошибку
+func uniqueViolation(err error, domainErr error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return domainErr
+	}
+	return err
+}
+
+// обновление полей продукта с увеличением версии и замена его ключевых слов,
+// новая версия и мерчант продукта (не меняется при обновлении) записываются в product
+func updateProductRow(ctx context.Context, trx *sql.Tx, productId int, product *entities.ProductInfo, log *slog.Logger) error {
+
+	attributes, err := attributesJSON(product.Attributes)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(
+		`UPDATE %s 
+		 SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5, %s = $6, %s = %s + 1 
+		 WHERE %s = $7 
+		 RETURNING %s, %s`,
+		productsTable,
+		categoryIdField, describtionField, statusField, keywordsField, attributesField, suggestedKeywordsField,
+		versionField, versionField,
+		id,
+		versionField, merchantIdField,
+	)
+
+	if err := trx.QueryRowContext(ctx, query,
+		product.CategoryId, product.Description, product.Status,
+		strings.Join(product.ProductKeyWords, " "), attributes, suggestedKeyWords(product),
+		productId,
+	).Scan(&product.Version, &product.MerchantId); err != nil {
+		return err
+	}
+
+	//удание старых ключевых слов продукта
+	queryDeleteKeyWords := fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = $1`,
+		productKwTable, productIdField,
+	)
+	if _, err := trx.ExecContext(ctx, queryDeleteKeyWords, productId); err != nil {
+		return err
+	}
+
+	//добавление новых ключевых слов продукта
+	return addProductKeyWords(ctx, product, trx, productId, log)
+}
+
+// предложенные ключевые слова продукта для записи в массив, отсутствие предложений - пустой массив
+func suggestedKeyWords(product *entities.ProductInfo) pq.StringArray {
+	if product.SuggestedKeyWords == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(product.SuggestedKeyWords)
+}
+
+// сохранение полного снимка продукта в истории, номер версии снимка - product.Version
+func addProductVersion(ctx context.Context, trx *sql.Tx, product *entities.ProductInfo, action string) error {
+
+	snapshot, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s, %s, %s, %s) VALUES ($1, $2, $3, $4)`,
+		productVersionsTable, productIdField, versionField, versionActionField, snapshotField,
+	)
+	if _, err := trx.ExecContext(ctx, query, product.ProductId, product.Version, action, snapshot); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// проверка что категория продукта существует, заполняет имя и путь категории,
+// атрибуты продукта проверяются по схемам категории и всех ее родителей
+func fillProductCategory(ctx context.Context, trx *sql.Tx, product *entities.ProductInfo) error {
+
+	query := fmt.Sprintf(
+		`SELECT c.%s, %s, 
+		 (SELECT COALESCE(jsonb_agg(a.%s ORDER BY array_position(c.%s, a.%s)), '[]') 
+		 	FROM %s a WHERE a.%s = ANY(c.%s)) 
+		 FROM %s c WHERE c.%s = $1`,
+		catNameField, categoryPathNames("c"),
+		catAttributesField, catPathField, id,
+		categoriesTable, id, catPathField,
+		categoriesTable, id,
+	)
+
+	var schemas []byte
+	if err := trx.QueryRowContext(ctx, query, product.CategoryId).Scan(
+		&product.Category, pq.Array(&product.CategoryPath), &schemas,
+	); errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	} else if err != nil {
+		return err
+	}
+
+	var pathSchemas [][]entities.AttributeSchema
+	if err := json.Unmarshal(schemas, &pathSchemas); err != nil {
+		return err
+	}
 
+	return entities.ValidateAttributes(entities.MergeAttributeSchemas(pathSchemas), product.Attributes)
 }
 
-func addProductKeyWords(product *entities.ProductInfo, trx *sql.Tx, productId int, log *slog.Logger) error {
+func addProductKeyWords(ctx context.Context, product *entities.ProductInfo, trx *sql.Tx, productId int, log *slog.Logger) error {
 	for _, keyWord := range product.ProductKeyWords {
 
 		var keyWordId int
 		// проверяем, есть ли уже такой интерес в таблице keyWords
 		queryGetKeyWord := fmt.Sprintf(`SELECT id FROM %s WHERE %s = $1`, kwTable, kwNameField)
 
-		rowSelect := trx.QueryRow(queryGetKeyWord, keyWord)
+		rowSelect := trx.QueryRowContext(ctx, queryGetKeyWord, keyWord)
 
 		if err := rowSelect.Scan(&keyWordId); errors.Is(err, sql.ErrNoRows) { //если такого нет keyWord
 			log.Info(fmt.Sprintf("postgres: keyWord %s not found, will be add", keyWord))
@@ -195,7 +1610,7 @@ func addProductKeyWords(product *entities.ProductInfo, trx *sql.Tx, productId in
 				kwNameField, id,
 			)
 			//выполняем запрос
-			rowInsert := trx.QueryRow(queryAddKeyWord, keyWord)
+			rowInsert := trx.QueryRowContext(ctx, queryAddKeyWord, keyWord)
 
 			//получем id интереса
 			if err := rowInsert.Scan(&keyWordId); err != nil {
@@ -212,7 +1627,7 @@ func addProductKeyWords(product *entities.ProductInfo, trx *sql.Tx, productId in
 			kwIdField, productIdField,
 		)
 		//добавляем ид продукта и его key-words в таблицу связку
-		if _, err := trx.Exec(querryKeyWordsProduct, keyWordId, productId); err != nil {
+		if _, err := trx.ExecContext(ctx, querryKeyWordsProduct, keyWordId, productId); err != nil {
 			return err
 		}
 	}
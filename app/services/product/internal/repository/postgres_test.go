@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/config"
@@ -57,6 +58,26 @@ func loadConf() config.DBConfig {
 	}
 }
 
+// id тестовой категории верхнего уровня, категория создается при первом обращении
+func testCategoryId(t *testing.T, dbConn *PostgresDB, name string) int {
+	categoryId, err := dbConn.AddCategory(context.Background(), &entities.Category{Name: name})
+	if !errors.Is(err, ErrCategoryAlreadyExists) {
+		assert.NoError(t, err)
+		return categoryId
+	}
+
+	categories, err := dbConn.GetCategories(context.Background())
+	assert.NoError(t, err)
+	for _, category := range categories {
+		if category.Name == name && category.ParentId == 0 {
+			return category.CategoryId
+		}
+	}
+
+	t.Fatalf("category %s not found", name)
+	return 0
+}
+
 func TestPostgreDB_AddNewProduct_Correct(t *testing.T) {
 
 	cfg := loadConf()
@@ -73,9 +94,10 @@ func TestPostgreDB_AddNewProduct_Correct(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "кино",
+		CategoryId:      testCategoryId(t, dbConn, "кино"),
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{"режиссер", "актёр", "камера"},
 	}
 
@@ -86,7 +108,7 @@ func TestPostgreDB_AddNewProduct_Correct(t *testing.T) {
 
 	// проверка что продукт добавился в таблицу products и получил тот же id, который нам вернулся
 	var idFromDb int
-	err2 := dbConn.DB.QueryRow("SELECT id FROM products WHERE category = $1", product.Category).Scan(&idFromDb)
+	err2 := dbConn.DB.QueryRow("SELECT id FROM products WHERE category_id = $1 ORDER BY id DESC LIMIT 1", product.CategoryId).Scan(&idFromDb)
 	assert.NoError(t, err2)
 	assert.Equal(t, idSource, idFromDb)
 
@@ -122,9 +144,10 @@ func TestPostgreDB_AddNewProduct_Incorrect1(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "",
+		CategoryId:      0,
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{"режиссер", "актёр", "камера"},
 	}
 
@@ -150,9 +173,10 @@ func TestPostgreDB_AddNewProduct_Incorrect2(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "гонки",
+		CategoryId:      testCategoryId(t, dbConn, "гонки"),
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{""},
 	}
 
@@ -178,9 +202,10 @@ func TestPostgreDB_UpdateProduct_Correct(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "кино",
+		CategoryId:      testCategoryId(t, dbConn, "кино"),
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{"режиссер", "актёр", "камера", "сценарий"},
 	}
 
@@ -190,7 +215,7 @@ func TestPostgreDB_UpdateProduct_Correct(t *testing.T) {
 
 	// проверка что продукт добавился в таблицу products и получил тот же id, который нам вернулся
 	var idFromDb, idSource int = 0, 1
-	err2 := dbConn.DB.QueryRow("SELECT id FROM products WHERE category = $1", product.Category).Scan(&idFromDb)
+	err2 := dbConn.DB.QueryRow("SELECT id FROM products WHERE id = $1 AND category_id = $2", idSource, product.CategoryId).Scan(&idFromDb)
 	assert.NoError(t, err2)
 	assert.Equal(t, idSource, idFromDb)
 
@@ -226,9 +251,10 @@ func TestPostgreDB_UpdateProduct_Incorrect1(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "rbyj",
+		CategoryId:      testCategoryId(t, dbConn, "rbyj"),
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{"режиссер", "актёр", "камера"},
 	}
 
@@ -253,9 +279,10 @@ func TestPostgreDB_UpdateProduct_Incorrect2(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "гонки",
+		CategoryId:      testCategoryId(t, dbConn, "гонки"),
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{""},
 	}
 
@@ -280,9 +307,10 @@ func TestPostgreDB_UpdateProduct_Incorrect3(t *testing.T) {
 	}()
 	// тестовый продукт
 	product := entities.ProductInfo{
-		Category:        "",
+		CategoryId:      0,
 		Description:     "корректно",
-		Status:          "availible",
+		Status:          "active",
+		MerchantId:      1,
 		ProductKeyWords: []string{"режиссер", "актёр", "камера"},
 	}
 
@@ -306,8 +334,9 @@ func TestPostgre_DeleteProduct_Correct(t *testing.T) {
 		}
 	}()
 
-	err := dbConn.DeleteProduct(context.Background(), 1)
+	product, err := dbConn.DeleteProduct(context.Background(), 1)
 	assert.NoError(t, err)
+	assert.Equal(t, 1, product.ProductId)
 }
 
 func TestPostgre_DeleteProduct_Incorrect(t *testing.T) {
@@ -325,6 +354,508 @@ func TestPostgre_DeleteProduct_Incorrect(t *testing.T) {
 		}
 	}()
 
-	err := dbConn.DeleteProduct(context.Background(), 100)
+	_, err := dbConn.DeleteProduct(context.Background(), 100)
 	assert.Error(t, err)
 }
+
+func TestPostgreDB_GetProductById_Correct(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+	// тестовый продукт
+	product := entities.ProductInfo{
+		CategoryId:      testCategoryId(t, dbConn, "музыка"),
+		Description:     "корректно",
+		Status:          "active",
+		MerchantId:      1,
+		ProductKeyWords: []string{"гитара", "барабаны"},
+	}
+
+	idSource, err1 := dbConn.AddNewProduct(context.Background(), &product)
+	assert.NoError(t, err1)
+
+	// продукт читается вместе с ключевыми словами (отсортированными по имени)
+	productFromDb, err2 := dbConn.GetProductById(context.Background(), idSource)
+	assert.NoError(t, err2)
+	assert.Equal(t, idSource, productFromDb.ProductId)
+	assert.Equal(t, product.CategoryId, productFromDb.CategoryId)
+	assert.Equal(t, "музыка", productFromDb.Category)
+	assert.Equal(t, []string{"музыка"}, productFromDb.CategoryPath)
+	assert.Equal(t, []string{"барабаны", "гитара"}, productFromDb.ProductKeyWords)
+	assert.Equal(t, 1, productFromDb.Version)
+
+	// каждое изменение увеличивает версию продукта
+	changed, errStatus := dbConn.ChangeProductStatus(context.Background(), idSource, "archived")
+	assert.NoError(t, errStatus)
+	assert.Equal(t, 2, changed.Version)
+
+	// фильтр по ключевому слову находит добавленный продукт
+	products, err3 := dbConn.GetProducts(context.Background(), &entities.ProductFilter{
+		Cursor: idSource - 1, Keyword: "гитара", Limit: 1,
+	})
+	assert.NoError(t, err3)
+	assert.Len(t, products, 1)
+	assert.Equal(t, idSource, products[0].ProductId)
+}
+
+func TestPostgreDB_GetProductById_NotFound(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	productFromDb, err := dbConn.GetProductById(context.Background(), 1000000)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, productFromDb)
+}
+
+func TestPostgreDB_SearchProducts_Correct(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+	// тестовый продукт
+	product := entities.ProductInfo{
+		CategoryId:      testCategoryId(t, dbConn, "книги"),
+		Description:     "Сборник рассказов о путешествиях по горам",
+		Status:          "active",
+		MerchantId:      1,
+		ProductKeyWords: []string{"туризм"},
+	}
+
+	idSource, err1 := dbConn.AddNewProduct(context.Background(), &product)
+	assert.NoError(t, err1)
+
+	// поиск по словоформе из описания с фильтром по категории
+	hits, err2 := dbConn.SearchProducts(context.Background(), &entities.ProductSearch{
+		Query: "путешествие", CategoryId: product.CategoryId, Limit: 10,
+	})
+	assert.NoError(t, err2)
+	assert.NotEmpty(t, hits)
+	assert.Equal(t, idSource, hits[0].ProductId)
+	assert.Contains(t, hits[0].Snippet, "<b>")
+}
+
+func TestPostgreDB_Category_Subtree(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	// дерево электроника -> гитары -> акустика
+	rootId := testCategoryId(t, dbConn, "электроника")
+	childId, err1 := dbConn.AddCategory(context.Background(), &entities.Category{Name: "гитары", ParentId: rootId})
+	assert.NoError(t, err1)
+	leafId, err2 := dbConn.AddCategory(context.Background(), &entities.Category{Name: "акустика", ParentId: childId})
+	assert.NoError(t, err2)
+
+	leaf, err3 := dbConn.GetCategoryById(context.Background(), leafId)
+	assert.NoError(t, err3)
+	assert.Equal(t, []string{"электроника", "гитары", "акустика"}, leaf.Path)
+
+	// родителя нельзя перенести в его же подкатегорию
+	err4 := dbConn.UpdateCategory(context.Background(), childId, &entities.Category{Name: "гитары", ParentId: leafId})
+	assert.ErrorIs(t, err4, ErrCategoryCycle)
+
+	// продукт листовой категории находится фильтром по корню дерева
+	idSource, err5 := dbConn.AddNewProduct(context.Background(), &entities.ProductInfo{
+		CategoryId:      leafId,
+		Description:     "корректно",
+		Status:          "active",
+		MerchantId:      1,
+		ProductKeyWords: []string{"струны"},
+	})
+	assert.NoError(t, err5)
+
+	products, err6 := dbConn.GetProducts(context.Background(), &entities.ProductFilter{
+		Cursor: idSource - 1, CategoryId: rootId, Limit: 1,
+	})
+	assert.NoError(t, err6)
+	assert.Len(t, products, 1)
+	assert.Equal(t, leaf.Path, products[0].CategoryPath)
+
+	// категорию с подкатегориями удалить нельзя
+	err7 := dbConn.DeleteCategory(context.Background(), childId)
+	assert.ErrorIs(t, err7, ErrCategoryInUse)
+}
+
+func TestPostgreDB_AddNewProducts_Correct(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+	// вторая строка ссылается на несуществующую категорию
+	products := []entities.ProductInfo{
+		{
+			CategoryId:      testCategoryId(t, dbConn, "кино"),
+			Description:     "корректно",
+			Status:          "active",
+			MerchantId:      1,
+			ProductKeyWords: []string{"режиссер"},
+		},
+		{
+			CategoryId:  1000000,
+			Description: "корректно",
+			Status:      "active",
+			MerchantId:  1,
+		},
+		{
+			CategoryId:      testCategoryId(t, dbConn, "кино"),
+			Description:     "корректно",
+			Status:          "draft",
+			MerchantId:      1,
+			ProductKeyWords: []string{"актёр"},
+		},
+	}
+
+	// ошибка одной строки не откатывает остальные
+	rowErrs, err := dbConn.AddNewProducts(context.Background(), products)
+	assert.NoError(t, err)
+	assert.NoError(t, rowErrs[0])
+	assert.ErrorIs(t, rowErrs[1], ErrCategoryNotFound)
+	assert.NoError(t, rowErrs[2])
+	assert.Empty(t, products[1].ProductId)
+
+	productFromDb, err2 := dbConn.GetProductById(context.Background(), products[2].ProductId)
+	assert.NoError(t, err2)
+	assert.Equal(t, []string{"актёр"}, productFromDb.ProductKeyWords)
+}
+
+func TestPostgreDB_ProductHistory_Correct(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	product := entities.ProductInfo{
+		CategoryId:      testCategoryId(t, dbConn, "кино"),
+		Description:     "первая версия",
+		Status:          "draft",
+		MerchantId:      1,
+		ProductKeyWords: []string{"режиссер"},
+	}
+	productId, err := dbConn.AddNewProduct(context.Background(), &product)
+	assert.NoError(t, err)
+	createdAt := time.Now()
+
+	product.Description = "вторая версия"
+	product.Status = "active"
+	assert.NoError(t, dbConn.UpdateProduct(context.Background(), productId, &product))
+
+	// на момент после создания продукт выглядит как первая версия
+	productAsOf, err := dbConn.GetProductAsOf(context.Background(), productId, createdAt)
+	assert.NoError(t, err)
+	assert.Equal(t, "первая версия", productAsOf.Description)
+
+	// восстановление - новая версия с содержимым первой
+	restored, err := dbConn.RestoreProductVersion(context.Background(), productId, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, restored.Version)
+	assert.Equal(t, "draft", restored.Status)
+
+	_, err = dbConn.DeleteProduct(context.Background(), productId)
+	assert.NoError(t, err)
+
+	history, err := dbConn.GetProductHistory(context.Background(), productId)
+	assert.NoError(t, err)
+	assert.Len(t, history, 4)
+	assert.Equal(t, entities.VersionActionRestore, history[2].Action)
+	assert.Equal(t, entities.VersionActionDelete, history[3].Action)
+
+	// после удаления продукта его состояние на текущий момент не найдено
+	_, err = dbConn.GetProductAsOf(context.Background(), productId, time.Now())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPostgreDB_ProductAttributes_Schema(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	// цена задана в родительской категории, размер - в подкатегории
+	parentId := testCategoryId(t, dbConn, "товары")
+	category := entities.Category{
+		Name:     "обувь",
+		ParentId: parentId,
+		Attributes: []entities.AttributeSchema{
+			{Name: "size", Type: entities.AttributeEnum, Values: []string{"S", "M", "L"}},
+		},
+	}
+	categoryId, err := dbConn.AddCategory(context.Background(), &category)
+	if errors.Is(err, ErrCategoryAlreadyExists) {
+		t.Skip("category already exists")
+	}
+	assert.NoError(t, err)
+	assert.NoError(t, dbConn.UpdateCategory(context.Background(), parentId, &entities.Category{
+		Name:       "товары",
+		Attributes: []entities.AttributeSchema{{Name: "price", Type: entities.AttributeNumber, Required: true}},
+	}))
+
+	product := entities.ProductInfo{
+		CategoryId:  categoryId,
+		Description: "кроссовки",
+		Status:      "active",
+		MerchantId:  1,
+		Attributes:  map[string]any{"size": "XL"},
+	}
+	_, err = dbConn.AddNewProduct(context.Background(), &product)
+	assert.ErrorIs(t, err, entities.ErrInvalidAttributes)
+
+	product.Attributes = map[string]any{"size": "M", "price": 4990}
+	productId, err := dbConn.AddNewProduct(context.Background(), &product)
+	assert.NoError(t, err)
+
+	productFromDb, err := dbConn.GetProductById(context.Background(), productId)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"size": "M", "price": float64(4990)}, productFromDb.Attributes)
+}
+
+func TestPostgreDB_MerchantScopeAndQuota(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	merchant, err := dbConn.GetMerchant(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.NoError(t, dbConn.SetMerchantQuota(context.Background(), 2, merchant.ProductCount+1))
+
+	product := entities.ProductInfo{
+		CategoryId:  testCategoryId(t, dbConn, "кино"),
+		Description: "продукт второго мерчанта",
+		Status:      "active",
+		MerchantId:  2,
+	}
+	productId, err := dbConn.AddNewProduct(context.Background(), &product)
+	assert.NoError(t, err)
+
+	// квота исчерпана
+	_, err = dbConn.AddNewProduct(context.Background(), &product)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// чужой мерчант не видит и не может изменить продукт, администратор - может
+	other := context.WithValue(context.Background(), entities.CallerKey, entities.Caller{MerchantId: 1})
+	_, err = dbConn.GetProductById(other, productId)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = dbConn.DeleteProduct(other, productId)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	admin := context.WithValue(context.Background(), entities.CallerKey, entities.Caller{Admin: true})
+	productFromDb, err := dbConn.GetProductById(admin, productId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, productFromDb.MerchantId)
+
+	products, err := dbConn.GetProducts(other, &entities.ProductFilter{Limit: entities.MaxPageLimit, MerchantId: 2})
+	assert.NoError(t, err)
+	for _, p := range products {
+		assert.Equal(t, 1, p.MerchantId)
+	}
+
+	_, err = dbConn.DeleteProduct(admin, productId)
+	assert.NoError(t, err)
+}
+
+func TestPostgreDB_ProductKeyWords(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	product := entities.ProductInfo{
+		CategoryId:      testCategoryId(t, dbConn, "кино"),
+		Description:     "продукт с ключевыми словами",
+		Status:          "active",
+		ProductKeyWords: []string{"Футбол"},
+		MerchantId:      1,
+	}
+	productId, err := dbConn.AddNewProduct(context.Background(), &product)
+	assert.NoError(t, err)
+
+	// каждое изменение - новая версия с полным набором ключевых слов
+	added, err := dbConn.AddProductKeyWord(context.Background(), productId, "Сашими")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Футбол", "Сашими"}, added.ProductKeyWords)
+	assert.Equal(t, 2, added.Version)
+
+	_, err = dbConn.AddProductKeyWord(context.Background(), productId, "Сашими")
+	assert.ErrorIs(t, err, ErrKeyWordAlreadyExists)
+
+	deleted, err := dbConn.DeleteProductKeyWord(context.Background(), productId, "Футбол")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Сашими"}, deleted.ProductKeyWords)
+	assert.Equal(t, 3, deleted.Version)
+
+	_, err = dbConn.DeleteProductKeyWord(context.Background(), productId, "Футбол")
+	assert.ErrorIs(t, err, ErrKeyWordNotFound)
+
+	// последнее ключевое слово не удаляется
+	_, err = dbConn.DeleteProductKeyWord(context.Background(), productId, "Сашими")
+	assert.ErrorIs(t, err, ErrLastKeyWord)
+
+	// подтвержденное предложение становится ключевым словом и убирается из предложенных
+	_, err = dbConn.DB.Exec(`UPDATE products SET prd_suggested_keywords = '{кино,фильм}' WHERE id = $1`, productId)
+	assert.NoError(t, err)
+	_, err = dbConn.ConfirmSuggestedKeyWords(context.Background(), productId, []string{"мультфильм"})
+	assert.ErrorIs(t, err, ErrKeyWordNotSuggested)
+	confirmed, err := dbConn.ConfirmSuggestedKeyWords(context.Background(), productId, []string{"кино"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Сашими", "кино"}, confirmed.ProductKeyWords)
+	assert.Equal(t, []string{"фильм"}, confirmed.SuggestedKeyWords)
+
+	total, frequencies, err := dbConn.GetDocumentFrequencies(context.Background(), []string{"кино", "самураи"})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, total, 1)
+	assert.GreaterOrEqual(t, frequencies["кино"], 1)
+
+	keyWords, err := dbConn.GetKeyWords(context.Background())
+	assert.NoError(t, err)
+	assert.NotEmpty(t, keyWords)
+
+	_, err = dbConn.DeleteProduct(context.Background(), productId)
+	assert.NoError(t, err)
+}
+
+func TestPostgreDB_ProductDuplicates(t *testing.T) {
+
+	cfg := loadConf()
+
+	// коннект к бд (Маст)
+	dbConn := NewPostgresDB(cfg, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+	// закрываем коннект, выводим ошибку
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			t.Error(errors.New("Ошибка закрытия БД" + err.Error()))
+		}
+	}()
+
+	productIds := make([]int, 2)
+	for i, keyWord := range []string{"Футбол", "Сашими"} {
+		product := entities.ProductInfo{
+			CategoryId:      testCategoryId(t, dbConn, "кино"),
+			Description:     "продукт-дубликат",
+			Status:          "active",
+			ProductKeyWords: []string{keyWord},
+			MerchantId:      1,
+		}
+		var err error
+		productIds[i], err = dbConn.AddNewProduct(context.Background(), &product)
+		assert.NoError(t, err)
+	}
+
+	// кандидаты - продукты с общей полосой сигнатуры
+	first := &entities.ProductSignature{ProductId: productIds[0], Signature: []int64{1, 2}, Bands: []int64{-11, 12}}
+	assert.NoError(t, dbConn.SetProductSignature(context.Background(), first, nil))
+	second := &entities.ProductSignature{ProductId: productIds[1], Signature: []int64{1, 3}, Bands: []int64{-11, 13}}
+	candidates, err := dbConn.GetDuplicateCandidates(context.Background(), second)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.ProductSignature{{ProductId: productIds[0], Signature: []int64{1, 2}}}, candidates)
+
+	// пара дубликатов видна с обеих сторон
+	assert.NoError(t, dbConn.SetProductSignature(context.Background(), second,
+		[]entities.ProductDuplicate{{ProductId: productIds[0], Similarity: 0.5}},
+	))
+	duplicates, err := dbConn.GetProductDuplicates(context.Background(), productIds[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.ProductDuplicate{{ProductId: productIds[1], Similarity: 0.5}}, duplicates)
+
+	// ключевые слова дубликата переходят к продукту, дубликат удаляется
+	merge, err := dbConn.MergeProducts(context.Background(), productIds[0], productIds[1])
+	assert.NoError(t, err)
+	assert.True(t, merge.Updated)
+	assert.Equal(t, []string{"Сашими", "Футбол"}, merge.Product.ProductKeyWords)
+	assert.Equal(t, productIds[1], merge.Duplicate.ProductId)
+
+	duplicates, err = dbConn.GetProductDuplicates(context.Background(), productIds[0])
+	assert.NoError(t, err)
+	assert.Empty(t, duplicates)
+	_, err = dbConn.MergeProducts(context.Background(), productIds[0], productIds[1])
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = dbConn.DeleteProduct(context.Background(), productIds[0])
+	assert.NoError(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 )
@@ -16,8 +17,32 @@ type ProductRepository struct {
 
 type Repository interface {
 	AddNewProduct(ctx context.Context, productInfo *entities.ProductInfo) (int, error)
+	AddNewProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error)
 	UpdateProduct(ctx context.Context, productId int, productInfo *entities.ProductInfo) error
-	DeleteProduct(ctx context.Context, productId int) error
+	DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error)
+	GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error)
+	GetProducts(ctx context.Context, filter *entities.ProductFilter) ([]entities.ProductInfo, error)
+	SearchProducts(ctx context.Context, search *entities.ProductSearch) ([]entities.ProductSearchHit, error)
+	ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error)
+	GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error)
+	GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error)
+	RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error)
+	AddCategory(ctx context.Context, category *entities.Category) (int, error)
+	GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error)
+	GetCategories(ctx context.Context) ([]entities.Category, error)
+	UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error
+	DeleteCategory(ctx context.Context, categoryId int) error
+	GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error)
+	SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error
+	AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error)
+	DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error)
+	ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error)
+	GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error)
+	GetDocumentFrequencies(ctx context.Context, terms []string) (int, map[string]int, error)
+	GetDuplicateCandidates(ctx context.Context, signature *entities.ProductSignature) ([]entities.ProductSignature, error)
+	SetProductSignature(ctx context.Context, signature *entities.ProductSignature, duplicates []entities.ProductDuplicate) error
+	GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error)
+	MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error)
 }
 
 // слой репощитория - взаимодействие с Базами данных
@@ -41,6 +66,25 @@ func (r *ProductRepository) AddNewProduct(ctx context.Context, productInfo *enti
 	return productId, nil
 }
 
+func (r *ProductRepository) AddNewProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error) {
+	fi := "repository.ProductRepository.AddNewProducts"
+
+	rowErrs, err := r.relDB.AddNewProducts(ctx, products)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	added := 0
+	for _, rowErr := range rowErrs {
+		if rowErr == nil {
+			added++
+		}
+	}
+	r.log.Info(fmt.Sprintf("%s: added %d of %d products", fi, added, len(products)))
+	return rowErrs, nil
+}
+
 func (r *ProductRepository) UpdateProduct(ctx context.Context, productId int, productInfo *entities.ProductInfo) error {
 	fi := "repository.ProductRepository.UpdateProduct"
 
@@ -53,14 +97,285 @@ func (r *ProductRepository) UpdateProduct(ctx context.Context, productId int, pr
 	return nil
 }
 
-func (r *ProductRepository) DeleteProduct(ctx context.Context, productId int) error {
+func (r *ProductRepository) DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error) {
 	fi := "repository.ProductRepository.DeleteProduct"
 
-	err := r.relDB.DeleteProduct(ctx, productId)
+	product, err := r.relDB.DeleteProduct(ctx, productId)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
-		return err
+		return nil, err
 	}
 	r.log.Info(fmt.Sprintf("%s: product with id %d deleted", fi, productId))
+	return product, nil
+}
+
+func (r *ProductRepository) GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.GetProductById"
+
+	product, err := r.relDB.GetProductById(ctx, productId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (r *ProductRepository) GetProducts(ctx context.Context, filter *entities.ProductFilter) ([]entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.GetProducts"
+
+	products, err := r.relDB.GetProducts(ctx, filter)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return products, nil
+}
+
+func (r *ProductRepository) SearchProducts(ctx context.Context, search *entities.ProductSearch) ([]entities.ProductSearchHit, error) {
+	fi := "repository.ProductRepository.SearchProducts"
+
+	hits, err := r.relDB.SearchProducts(ctx, search)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+func (r *ProductRepository) ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.ChangeProductStatus"
+
+	product, err := r.relDB.ChangeProductStatus(ctx, productId, status)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("%s: product with id %d moved to status %s", fi, productId, status))
+	return product, nil
+}
+
+func (r *ProductRepository) GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error) {
+	fi := "repository.ProductRepository.GetProductHistory"
+
+	history, err := r.relDB.GetProductHistory(ctx, productId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (r *ProductRepository) GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.GetProductAsOf"
+
+	product, err := r.relDB.GetProductAsOf(ctx, productId, asOf)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (r *ProductRepository) RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.RestoreProductVersion"
+
+	product, err := r.relDB.RestoreProductVersion(ctx, productId, version)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("%s: product with id %d restored from version %d", fi, productId, version))
+	return product, nil
+}
+
+func (r *ProductRepository) AddCategory(ctx context.Context, category *entities.Category) (int, error) {
+	fi := "repository.ProductRepository.AddCategory"
+
+	categoryId, err := r.relDB.AddCategory(ctx, category)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return 0, err
+	}
+	r.log.Info(fmt.Sprintf("%s: add new category with id %d", fi, categoryId))
+	return categoryId, nil
+}
+
+func (r *ProductRepository) GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error) {
+	fi := "repository.ProductRepository.GetCategoryById"
+
+	category, err := r.relDB.GetCategoryById(ctx, categoryId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (r *ProductRepository) GetCategories(ctx context.Context) ([]entities.Category, error) {
+	fi := "repository.ProductRepository.GetCategories"
+
+	categories, err := r.relDB.GetCategories(ctx)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *ProductRepository) UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error {
+	fi := "repository.ProductRepository.UpdateCategory"
+
+	if err := r.relDB.UpdateCategory(ctx, categoryId, category); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+	r.log.Info(fmt.Sprintf("%s: category with id %d updated", fi, categoryId))
 	return nil
 }
+
+func (r *ProductRepository) DeleteCategory(ctx context.Context, categoryId int) error {
+	fi := "repository.ProductRepository.DeleteCategory"
+
+	if err := r.relDB.DeleteCategory(ctx, categoryId); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+	r.log.Info(fmt.Sprintf("%s: category with id %d deleted", fi, categoryId))
+	return nil
+}
+
+func (r *ProductRepository) GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error) {
+	fi := "repository.ProductRepository.GetMerchant"
+
+	merchant, err := r.relDB.GetMerchant(ctx, merchantId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	return merchant, nil
+}
+
+func (r *ProductRepository) SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error {
+	fi := "repository.ProductRepository.SetMerchantQuota"
+
+	if err := r.relDB.SetMerchantQuota(ctx, merchantId, maxProducts); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+	r.log.Info(fmt.Sprintf("%s: quota of merchant %d set to %d", fi, merchantId, maxProducts))
+	return nil
+}
+
+func (r *ProductRepository) AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.AddProductKeyWord"
+
+	product, err := r.relDB.AddProductKeyWord(ctx, productId, keyWord)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("%s: keyword %s added to product with id %d", fi, keyWord, productId))
+	return product, nil
+}
+
+func (r *ProductRepository) DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.DeleteProductKeyWord"
+
+	product, err := r.relDB.DeleteProductKeyWord(ctx, productId, keyWord)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("%s: keyword %s deleted from product with id %d", fi, keyWord, productId))
+	return product, nil
+}
+
+func (r *ProductRepository) GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error) {
+	fi := "repository.ProductRepository.GetKeyWords"
+
+	keyWords, err := r.relDB.GetKeyWords(ctx)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return keyWords, nil
+}
+
+func (r *ProductRepository) ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error) {
+	fi := "repository.ProductRepository.ConfirmSuggestedKeyWords"
+
+	product, err := r.relDB.ConfirmSuggestedKeyWords(ctx, productId, keyWords)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("%s: suggested keywords of product with id %d confirmed", fi, productId))
+	return product, nil
+}
+
+func (r *ProductRepository) GetDocumentFrequencies(ctx context.Context, terms []string) (int, map[string]int, error) {
+	fi := "repository.ProductRepository.GetDocumentFrequencies"
+
+	total, frequencies, err := r.relDB.GetDocumentFrequencies(ctx, terms)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return 0, nil, err
+	}
+
+	return total, frequencies, nil
+}
+
+func (r *ProductRepository) GetDuplicateCandidates(ctx context.Context, signature *entities.ProductSignature) ([]entities.ProductSignature, error) {
+	fi := "repository.ProductRepository.GetDuplicateCandidates"
+
+	candidates, err := r.relDB.GetDuplicateCandidates(ctx, signature)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+func (r *ProductRepository) SetProductSignature(ctx context.Context, signature *entities.ProductSignature, duplicates []entities.ProductDuplicate) error {
+	fi := "repository.ProductRepository.SetProductSignature"
+
+	if err := r.relDB.SetProductSignature(ctx, signature, duplicates); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+	r.log.Info(fmt.Sprintf("%s: product with id %d has %d duplicates", fi, signature.ProductId, len(duplicates)))
+	return nil
+}
+
+func (r *ProductRepository) GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error) {
+	fi := "repository.ProductRepository.GetProductDuplicates"
+
+	duplicates, err := r.relDB.GetProductDuplicates(ctx, productId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return duplicates, nil
+}
+
+func (r *ProductRepository) MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error) {
+	fi := "repository.ProductRepository.MergeProducts"
+
+	merge, err := r.relDB.MergeProducts(ctx, productId, duplicateId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	r.log.Info(fmt.Sprintf("%s: product with id %d merged into product with id %d", fi, duplicateId, productId))
+	return merge, nil
+}
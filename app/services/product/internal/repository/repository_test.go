@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 	"github.com/stretchr/testify/assert"
@@ -19,19 +20,181 @@ func (m *MockRelationaldatabase) AddNewProduct(ctx context.Context, product *ent
 	}
 	return 1, nil
 }
+func (m *MockRelationaldatabase) AddNewProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error) {
+	rowErrs := make([]error, len(products))
+	for i := range products {
+		if products[i].Category == "некорректно" {
+			rowErrs[i] = ErrCategoryNotFound
+			continue
+		}
+		products[i].ProductId = i + 1
+	}
+	return rowErrs, nil
+}
 func (m *MockRelationaldatabase) UpdateProduct(ctx context.Context, productId int, uproduct *entities.ProductInfo) error {
 	if uproduct.Category == "некорректно" {
 		return errors.New("ошибка")
 	}
 	return nil
 }
-func (m *MockRelationaldatabase) DeleteProduct(ctx context.Context, productId int) error {
+func (m *MockRelationaldatabase) DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, Version: 2}, nil
+}
+
+func (m *MockRelationaldatabase) GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, ErrNotFound
+	}
+	return &entities.ProductInfo{ProductId: productId}, nil
+}
+func (m *MockRelationaldatabase) GetProducts(ctx context.Context, filter *entities.ProductFilter) ([]entities.ProductInfo, error) {
+	if filter.Keyword == "некорректно" {
+		return nil, errors.New("ошибка")
+	}
+	return []entities.ProductInfo{{ProductId: filter.Cursor + 1}}, nil
+}
+
+func (m *MockRelationaldatabase) SearchProducts(ctx context.Context, search *entities.ProductSearch) ([]entities.ProductSearchHit, error) {
+	if search.Query == "некорректно" {
+		return nil, errors.New("ошибка")
+	}
+	return []entities.ProductSearchHit{{ProductInfo: entities.ProductInfo{ProductId: 1}, Rank: 0.5}}, nil
+}
+
+func (m *MockRelationaldatabase) ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, Status: status}, nil
+}
+
+func (m *MockRelationaldatabase) GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error) {
+	if productId == 0 {
+		return nil, ErrNotFound
+	}
+	return []entities.ProductVersion{{ProductId: productId, Version: 1, Action: entities.VersionActionCreate}}, nil
+}
+
+func (m *MockRelationaldatabase) GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, ErrNotFound
+	}
+	return &entities.ProductInfo{ProductId: productId}, nil
+}
+
+func (m *MockRelationaldatabase) RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error) {
+	if version == 0 {
+		return nil, ErrVersionNotFound
+	}
+	return &entities.ProductInfo{ProductId: productId, Version: version + 1}, nil
+}
+
+func (m *MockRelationaldatabase) AddCategory(ctx context.Context, category *entities.Category) (int, error) {
+	if category.Name == "некорректно" {
+		return 0, ErrCategoryAlreadyExists
+	}
+	return 1, nil
+}
+
+func (m *MockRelationaldatabase) GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error) {
+	if categoryId == 0 {
+		return nil, ErrCategoryNotFound
+	}
+	return &entities.Category{CategoryId: categoryId}, nil
+}
+
+func (m *MockRelationaldatabase) GetCategories(ctx context.Context) ([]entities.Category, error) {
+	return []entities.Category{{CategoryId: 1}}, nil
+}
+
+func (m *MockRelationaldatabase) UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error {
+	if categoryId == category.ParentId {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+func (m *MockRelationaldatabase) DeleteCategory(ctx context.Context, categoryId int) error {
+	if categoryId == 0 {
+		return ErrCategoryInUse
+	}
+	return nil
+}
+
+func (m *MockRelationaldatabase) GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error) {
+	return &entities.Merchant{MerchantId: merchantId, MaxProducts: entities.DefaultMerchantQuota}, nil
+}
+
+func (m *MockRelationaldatabase) SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error {
+	if merchantId == 0 {
+		return errors.New("ошибка")
+	}
+	return nil
+}
+
+func (m *MockRelationaldatabase) AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{keyWord}}, nil
+}
+
+func (m *MockRelationaldatabase) DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{}}, nil
+}
+
+func (m *MockRelationaldatabase) ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error) {
 	if productId == 0 {
+		return nil, ErrKeyWordNotSuggested
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: keyWords}, nil
+}
+
+func (m *MockRelationaldatabase) GetDocumentFrequencies(ctx context.Context, terms []string) (int, map[string]int, error) {
+	if len(terms) == 0 {
+		return 0, nil, errors.New("ошибка")
+	}
+	return 1, map[string]int{terms[0]: 1}, nil
+}
+
+func (m *MockRelationaldatabase) GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error) {
+	return []entities.KeyWordUsage{{KeyWord: "Футбол", Products: 2}}, nil
+}
+
+func (m *MockRelationaldatabase) GetDuplicateCandidates(ctx context.Context, signature *entities.ProductSignature) ([]entities.ProductSignature, error) {
+	return []entities.ProductSignature{{ProductId: signature.ProductId + 1, Signature: signature.Signature}}, nil
+}
+
+func (m *MockRelationaldatabase) SetProductSignature(ctx context.Context, signature *entities.ProductSignature, duplicates []entities.ProductDuplicate) error {
+	if signature.ProductId == 0 {
 		return errors.New("ошибка")
 	}
 	return nil
 }
 
+func (m *MockRelationaldatabase) GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error) {
+	if productId == 0 {
+		return nil, ErrNotFound
+	}
+	return []entities.ProductDuplicate{{ProductId: productId + 1, Similarity: 0.9}}, nil
+}
+
+func (m *MockRelationaldatabase) MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error) {
+	if productId == 0 {
+		return nil, ErrNotFound
+	}
+	return &entities.ProductMergeResult{
+		Product:   &entities.ProductInfo{ProductId: productId},
+		Duplicate: &entities.ProductInfo{ProductId: duplicateId},
+	}, nil
+}
+
 func TestRepository_AddNewProduct_Correct(t *testing.T) {
 	repository := NewProductRepository(
 		&MockRelationaldatabase{},
@@ -101,9 +264,10 @@ func TestRepository_DeleteProduct_Correct(t *testing.T) {
 		),
 	)
 
-	err := repository.DeleteProduct(context.Background(), 1)
+	product, err := repository.DeleteProduct(context.Background(), 1)
 
 	assert.NoError(t, err)
+	assert.Equal(t, 2, product.Version)
 }
 
 func TestRepository_DeleteProductt_CorrectButSomeError(t *testing.T) {
@@ -114,7 +278,231 @@ func TestRepository_DeleteProductt_CorrectButSomeError(t *testing.T) {
 		),
 	)
 
-	err := repository.DeleteProduct(context.Background(), 0)
+	_, err := repository.DeleteProduct(context.Background(), 0)
+
+	assert.Error(t, err)
+}
+
+func TestRepository_GetProductById_Correct(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	product, err := repository.GetProductById(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, product.ProductId)
+}
+
+func TestRepository_GetProductById_CorrectButNotFound(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	product, err := repository.GetProductById(context.Background(), 0)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, product)
+}
+
+func TestRepository_AddCategory_CorrectButAlreadyExists(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	categoryId, err := repository.AddCategory(context.Background(), &entities.Category{Name: "некорректно"})
+
+	assert.ErrorIs(t, err, ErrCategoryAlreadyExists)
+	assert.Equal(t, 0, categoryId)
+}
+
+func TestRepository_GetCategoryById_CorrectButNotFound(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	category, err := repository.GetCategoryById(context.Background(), 0)
+
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+	assert.Nil(t, category)
+}
+
+func TestRepository_UpdateCategory_CorrectButCycle(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	err := repository.UpdateCategory(context.Background(), 2, &entities.Category{Name: "кино", ParentId: 2})
+
+	assert.ErrorIs(t, err, ErrCategoryCycle)
+}
+
+func TestRepository_DeleteCategory_CorrectButInUse(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	err := repository.DeleteCategory(context.Background(), 0)
+
+	assert.ErrorIs(t, err, ErrCategoryInUse)
+}
+
+func TestRepository_AddNewProducts_CorrectWithRowError(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	products := []entities.ProductInfo{{Category: "корректно"}, {Category: "некорректно"}}
+	rowErrs, err := repository.AddNewProducts(context.Background(), products)
+
+	assert.NoError(t, err)
+	assert.NoError(t, rowErrs[0])
+	assert.ErrorIs(t, rowErrs[1], ErrCategoryNotFound)
+	assert.Equal(t, 1, products[0].ProductId)
+}
+
+func TestRepository_RestoreProductVersion_CorrectButVersionNotFound(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	product, err := repository.RestoreProductVersion(context.Background(), 1, 0)
+
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	assert.Nil(t, product)
+}
+
+func TestRepository_SetMerchantQuota_Incorrect(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	err := repository.SetMerchantQuota(context.Background(), 0, 10)
+
+	assert.Error(t, err)
+}
+
+func TestRepository_AddProductKeyWord_Correct(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	product, err := repository.AddProductKeyWord(context.Background(), 1, "Футбол")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Футбол"}, product.ProductKeyWords)
+}
+
+func TestRepository_DeleteProductKeyWord_Incorrect(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	product, err := repository.DeleteProductKeyWord(context.Background(), 0, "Футбол")
+
+	assert.Error(t, err)
+	assert.Nil(t, product)
+}
+
+func TestRepository_ConfirmSuggestedKeyWords_Incorrect(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	product, err := repository.ConfirmSuggestedKeyWords(context.Background(), 0, nil)
+
+	assert.ErrorIs(t, err, ErrKeyWordNotSuggested)
+	assert.Nil(t, product)
+}
+
+func TestRepository_GetDocumentFrequencies_Correct(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	total, frequencies, err := repository.GetDocumentFrequencies(context.Background(), []string{"фильм"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 1, frequencies["фильм"])
+}
+
+func TestRepository_SetProductSignature_Incorrect(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	err := repository.SetProductSignature(context.Background(), &entities.ProductSignature{}, nil)
 
 	assert.Error(t, err)
 }
+
+func TestRepository_GetProductDuplicates_Correct(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	duplicates, err := repository.GetProductDuplicates(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.ProductDuplicate{{ProductId: 2, Similarity: 0.9}}, duplicates)
+}
+
+func TestRepository_MergeProducts_Incorrect(t *testing.T) {
+	repository := NewProductRepository(
+		&MockRelationaldatabase{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	merge, err := repository.MergeProducts(context.Background(), 0, 2)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, merge)
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// драйвер базы для проверки WithTx: считает коммиты и откаты,
+// запрос выполняется, пока не отменен его контекст
+type txDriver struct {
+	commits   atomic.Int32
+	rollbacks atomic.Int32
+}
+
+type txConn struct{ driver *txDriver }
+
+type txTx struct{ driver *txDriver }
+
+func (d *txDriver) Open(name string) (driver.Conn, error) {
+	return &txConn{driver: d}, nil
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c *txConn) Close() error {
+	return nil
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	return &txTx{driver: c.driver}, nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (t *txTx) Commit() error {
+	t.driver.commits.Add(1)
+	return nil
+}
+
+func (t *txTx) Rollback() error {
+	t.driver.rollbacks.Add(1)
+	return nil
+}
+
+var txDriverCount atomic.Int32
+
+func newTxDB(t *testing.T) (*sqlx.DB, *txDriver) {
+	drv := &txDriver{}
+	//имя драйвера уникально, sql.Register запрещает повторную регистрацию
+	name := "tx_test_" + strconv.Itoa(int(txDriverCount.Add(1)))
+	sql.Register(name, drv)
+
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatalf("Error opening db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db, drv
+}
+
+func TestWithTx_Commit(t *testing.T) {
+	db, drv := newTxDB(t)
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), drv.commits.Load())
+	assert.Equal(t, int32(0), drv.rollbacks.Load())
+}
+
+func TestWithTx_RollbackOnError(t *testing.T) {
+	db, drv := newTxDB(t)
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		return ErrNotFound
+	})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(0), drv.commits.Load())
+	assert.Equal(t, int32(1), drv.rollbacks.Load())
+}
+
+func TestWithTx_RollbackOnPanic(t *testing.T) {
+	db, drv := newTxDB(t)
+
+	assert.PanicsWithValue(t, "ошибка", func() {
+		WithTx(context.Background(), db, func(tx *sql.Tx) error {
+			panic("ошибка")
+		})
+	})
+	assert.Equal(t, int32(0), drv.commits.Load())
+	assert.Equal(t, int32(1), drv.rollbacks.Load())
+}
+
+func TestWithTx_QueryTimeout(t *testing.T) {
+	db, drv := newTxDB(t)
+
+	ctx, cancel := withQueryTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	//запрос прерывается по таймауту, транзакция откатывается
+	err := WithTx(ctx, db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "SELECT pg_sleep(10)")
+		return err
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(0), drv.commits.Load())
+	assert.Eventually(t, func() bool {
+		return drv.rollbacks.Load() == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestWithQueryTimeout(t *testing.T) {
+	ctx, cancel := withQueryTimeout(context.Background(), 0)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	ctx, cancel = withQueryTimeout(context.Background(), time.Minute)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"golang.org/x/net/context"
+)
+
+// поиск почти одинаковых продуктов: описание разбивается на символьные шинглы, по ним
+// строится MinHash-сигнатура, сигнатура делится на полосы (LSH). Продукты с общей полосой -
+// кандидаты, дубликаты - кандидаты с долей совпавших позиций сигнатур не меньше порога
+
+const (
+	//длина шингла в символах
+	shingleSize = 5
+	//число хэш-функций MinHash (длина сигнатуры) и позиций сигнатуры в одной полосе
+	signatureSize = 64
+	bandRows      = 4
+	//минимальное сходство описаний дубликатов
+	duplicateSimilarity = 0.8
+)
+
+// затравки хэш-функций MinHash, одинаковые при каждом запуске сервиса
+var minHashSeeds = func() []uint64 {
+	seeds := make([]uint64, signatureSize)
+	for i := range seeds {
+		seeds[i] = mix64(uint64(i) + 1)
+	}
+	return seeds
+}()
+
+// финальное перемешивание splitmix64
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// символьные шинглы описания: регистр, знаки препинания и лишние пробелы не учитываются,
+// описание короче шингла - один шингл
+func descriptionShingles(description string) map[string]struct{} {
+	shingles := make(map[string]struct{})
+
+	text := []rune(strings.Join(strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " "))
+	if len(text) == 0 {
+		return shingles
+	}
+
+	if len(text) <= shingleSize {
+		shingles[string(text)] = struct{}{}
+		return shingles
+	}
+	for i := 0; i+shingleSize <= len(text); i++ {
+		shingles[string(text[i:i+shingleSize])] = struct{}{}
+	}
+
+	return shingles
+}
+
+// MinHash-сигнатура описания продукта и хэши ее полос, nil - в описании нет ни одного шингла
+func productSignature(productId int, description string) *entities.ProductSignature {
+	shingles := descriptionShingles(description)
+	if len(shingles) == 0 {
+		return nil
+	}
+
+	mins := make([]uint64, signatureSize)
+	for i := range mins {
+		mins[i] = ^uint64(0)
+	}
+	for shingle := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		base := h.Sum64()
+		for i, seed := range minHashSeeds {
+			mins[i] = min(mins[i], mix64(base^seed))
+		}
+	}
+
+	signature := &entities.ProductSignature{
+		ProductId: productId,
+		Signature: make([]int64, signatureSize),
+		Bands:     make([]int64, 0, signatureSize/bandRows),
+	}
+	for i, value := range mins {
+		signature.Signature[i] = int64(value)
+	}
+
+	//хэш полосы включает ее номер, чтобы одинаковые значения разных полос не совпадали
+	buf := make([]byte, 8)
+	for band := 0; band < signatureSize/bandRows; band++ {
+		h := fnv.New64a()
+		binary.LittleEndian.PutUint64(buf, uint64(band))
+		h.Write(buf)
+		for _, value := range mins[band*bandRows : (band+1)*bandRows] {
+			binary.LittleEndian.PutUint64(buf, value)
+			h.Write(buf)
+		}
+		signature.Bands = append(signature.Bands, int64(h.Sum64()))
+	}
+
+	return signature
+}
+
+// оценка сходства описаний - доля совпавших позиций сигнатур
+func signatureSimilarity(a, b []int64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	equal := 0
+	for i := range a {
+		if a[i] == b[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+// пересчет сигнатуры продукта и его дубликатов, возвращает число найденных дубликатов
+func (s *ProductService) findDuplicates(ctx context.Context, productId int, description string) (int, error) {
+	signature := productSignature(productId, description)
+	if signature == nil {
+		return 0, nil
+	}
+
+	candidates, err := s.repo.GetDuplicateCandidates(ctx, signature)
+	if err != nil {
+		return 0, err
+	}
+
+	duplicates := make([]entities.ProductDuplicate, 0)
+	for _, candidate := range candidates {
+		if similarity := signatureSimilarity(signature.Signature, candidate.Signature); similarity >= duplicateSimilarity {
+			duplicates = append(duplicates, entities.ProductDuplicate{
+				ProductId:  candidate.ProductId,
+				Similarity: similarity,
+			})
+		}
+	}
+
+	if err := s.repo.SetProductSignature(ctx, signature, duplicates); err != nil {
+		return 0, err
+	}
+
+	return len(duplicates), nil
+}
+
+// поиск дубликатов сохраненных продуктов, продукты без id (не сохраненные) пропускаются.
+// Ошибка поиска не мешает сохранению продукта - дубликаты найдет пакетный поиск
+func (s *ProductService) detectDuplicates(ctx context.Context, products ...*entities.ProductInfo) {
+	fi := "service.ProductService.detectDuplicates"
+
+	for _, product := range products {
+		if product.ProductId == 0 {
+			continue
+		}
+		if _, err := s.findDuplicates(ctx, product.ProductId, product.Description); err != nil {
+			s.log.Error("%s: Error Finding Duplicates: %v", fi, err)
+		}
+	}
+}
+
+// функция пересчитывает сигнатуры и дубликаты всех продуктов после fromId в порядке id,
+// после каждой страницы каталога прогресс передается в progress
+func (s *ProductService) DetectDuplicates(ctx context.Context, fromId int, progress func(detection *entities.DuplicateDetection) error) error {
+	fi := "service.ProductService.DetectDuplicates"
+
+	detection := entities.DuplicateDetection{LastId: fromId}
+	filter := entities.ProductFilter{Cursor: fromId, Limit: entities.MaxPageLimit}
+	for {
+		products, err := s.repo.GetProducts(ctx, &filter)
+		if err != nil {
+			s.log.Error("%s: Error Getting Products: %v", fi, err)
+			return err
+		}
+
+		for _, product := range products {
+			duplicates, err := s.findDuplicates(ctx, product.ProductId, product.Description)
+			if err != nil {
+				s.log.Error("%s: Error Finding Duplicates: %v", fi, err)
+				return err
+			}
+			detection.Checked++
+			detection.Duplicates += duplicates
+			detection.LastId = product.ProductId
+		}
+
+		if len(products) != 0 {
+			if err := progress(&detection); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < filter.Limit {
+			return nil
+		}
+		filter.Cursor = products[len(products)-1].ProductId
+	}
+}
+
+// функция возвращает дубликаты продукта по убыванию сходства
+func (s *ProductService) GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error) {
+	fi := "service.ProductService.GetProductDuplicates"
+
+	duplicates, err := s.repo.GetProductDuplicates(ctx, productId)
+	if err != nil {
+		s.log.Error("%s: Error Getting Product Duplicates: %v", fi, err)
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// функция удаляет дубликат, его ключевые слова переходят к продукту
+func (s *ProductService) MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error) {
+	fi := "service.ProductService.MergeProducts"
+
+	//иначе репозиторий удалил бы сам продукт и вернул 404
+	if productId == duplicateId {
+		s.log.Error("%s: Error Merging Products: %v", fi, entities.ErrSelfMerge)
+		return nil, entities.ErrSelfMerge
+	}
+
+	merge, err := s.repo.MergeProducts(ctx, productId, duplicateId)
+	if err != nil {
+		s.log.Error("%s: Error Merging Products: %v", fi, err)
+		return nil, err
+	}
+	return merge, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"golang.org/x/net/context"
+)
+
+// извлечение ключевых слов из описания продукта: описание разбивается на термины,
+// стоп-слова отбрасываются, термины ранжируются по TF-IDF относительно текущего каталога
+
+const (
+	//более короткие термины не предлагаются
+	minTermLength = 3
+	//число предложенных ключевых слов, если не задано в конфигурации
+	defaultMaxSuggested = 5
+)
+
+// стоп-слова русского и английского языков
+var stopWords = func() map[string]struct{} {
+	words := strings.Fields(`
+		а без более бы был была были было быть в вам вас весь во вот все всего всех вы где да даже
+		для до его ее если есть еще же за здесь и из или им их к как когда кто ли либо мне может
+		мы на над надо наш не него нее нет ни них но ну о об однако он она они оно от очень по
+		под после при про с со так также такой там те тем то того тоже той только том ты у уже
+		хотя чего чей чем что чтобы чье эта эти это этот я свой своя свои свое себя который
+		которая которые которое каждый между через
+		a about above after again against all am an and any are as at be because been before being
+		below between both but by can did do does doing down during each few for from further had has
+		have having he her here hers herself him himself his how i if in into is it its itself just me
+		more most my myself no nor not now of off on once only or other our ours ourselves out over own
+		same she should so some such than that the their theirs them themselves then there these they
+		this those through to too under until up very was we were what when where which while who whom
+		why will with you your yours yourself yourselves new best`)
+
+	stop := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		stop[word] = struct{}{}
+	}
+	return stop
+}()
+
+// термины описания в нижнем регистре с числом их вхождений
+func descriptionTerms(description string) map[string]int {
+	terms := make(map[string]int)
+
+	tokens := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, token := range tokens {
+		if utf8.RuneCountInString(token) < minTermLength {
+			continue
+		}
+		if _, ok := stopWords[token]; ok {
+			continue
+		}
+		//предлагаются только термины, допустимые как ключевые слова
+		if entities.ValidateProductKeyWord(token) != nil {
+			continue
+		}
+		terms[token]++
+	}
+
+	return terms
+}
+
+// не более limit терминов с наибольшим TF-IDF, которых еще нет среди ключевых слов продукта,
+// total - число продуктов каталога, frequencies - число продуктов с термином
+func rankTerms(terms map[string]int, keyWords []string, total int, frequencies map[string]int, limit int) []string {
+	count := 0
+	for _, n := range terms {
+		count += n
+	}
+
+	scores := make(map[string]float64, len(terms))
+	for term, n := range terms {
+		if slices.ContainsFunc(keyWords, func(keyWord string) bool { return strings.EqualFold(keyWord, term) }) {
+			continue
+		}
+		tf := float64(n) / float64(count)
+		idf := math.Log(float64(1+total)/float64(1+frequencies[term])) + 1
+		scores[term] = tf * idf
+	}
+
+	//по убыванию TF-IDF, при равенстве - по алфавиту
+	ranked := slices.SortedFunc(maps.Keys(scores), func(a, b string) int {
+		if c := cmp.Compare(scores[b], scores[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	return ranked[:min(limit, len(ranked))]
+}
+
+// предложение ключевых слов продуктам по их описаниям, частоты терминов запрашиваются
+// одним запросом на все продукты. При AutoApply предложения сразу становятся ключевыми словами.
+// Ошибка извлечения не мешает сохранению продукта - продукт остается без предложений
+func (s *ProductService) suggestKeyWords(ctx context.Context, products ...*entities.ProductInfo) {
+	fi := "service.ProductService.suggestKeyWords"
+
+	productTerms := make([]map[string]int, len(products))
+	unique := make(map[string]struct{})
+	for i, product := range products {
+		productTerms[i] = descriptionTerms(product.Description)
+		for term := range productTerms[i] {
+			unique[term] = struct{}{}
+		}
+	}
+	if len(unique) == 0 {
+		return
+	}
+
+	total, frequencies, err := s.repo.GetDocumentFrequencies(ctx, slices.Sorted(maps.Keys(unique)))
+	if err != nil {
+		s.log.Error("%s: Error Getting Document Frequencies: %v", fi, err)
+		return
+	}
+
+	limit := s.keyWords.MaxSuggested
+	if limit <= 0 {
+		limit = defaultMaxSuggested
+	}
+
+	for i, product := range products {
+		suggested := rankTerms(productTerms[i], product.ProductKeyWords, total, frequencies, limit)
+		if s.keyWords.AutoApply {
+			product.ProductKeyWords = append(product.ProductKeyWords, suggested...)
+			product.SuggestedKeyWords = []string{}
+		} else {
+			product.SuggestedKeyWords = suggested
+		}
+	}
+}
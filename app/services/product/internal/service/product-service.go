@@ -2,22 +2,26 @@ package service
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/config"
 	"golang.org/x/net/context"
 )
 
 // имплементация интерфейса Service
 type ProductService struct {
-	repo repository.Repository
-	log  *slog.Logger
+	repo     repository.Repository
+	log      *slog.Logger
+	keyWords config.KeyWordsConfig
 }
 
-func NewProductService(repo repository.Repository, log *slog.Logger) *ProductService {
+func NewProductService(repo repository.Repository, log *slog.Logger, keyWords config.KeyWordsConfig) *ProductService {
 	return &ProductService{
-		repo: repo,
-		log:  log,
+		repo:     repo,
+		log:      log,
+		keyWords: keyWords,
 	}
 }
 
@@ -25,34 +29,384 @@ func NewProductService(repo repository.Repository, log *slog.Logger) *ProductSer
 func (s *ProductService) CreateProduct(ctx context.Context, product *entities.ProductInfo) (int, error) {
 	fi := "service.ProductService.CreateProduct"
 
+	s.suggestKeyWords(ctx, product)
+
 	productId, err := s.repo.AddNewProduct(ctx, product)
 	if err != nil {
 		s.log.Error("%s: Error Creating Product: %v", fi, err)
 		return 0, err
 	}
+
+	product.ProductId = productId
+	s.detectDuplicates(ctx, product)
+
 	return productId, nil
 }
 
+// функция добавляет пачку продуктов, ошибки отдельных продуктов возвращаются по индексам
+func (s *ProductService) CreateProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error) {
+	fi := "service.ProductService.CreateProducts"
+
+	suggested := make([]*entities.ProductInfo, len(products))
+	for i := range products {
+		suggested[i] = &products[i]
+	}
+	s.suggestKeyWords(ctx, suggested...)
+
+	rowErrs, err := s.repo.AddNewProducts(ctx, products)
+	if err != nil {
+		s.log.Error("%s: Error Creating Products: %v", fi, err)
+		return nil, err
+	}
+
+	//дубликаты ищутся только для добавленных продуктов
+	added := make([]*entities.ProductInfo, 0, len(products))
+	for i := range products {
+		if i >= len(rowErrs) || rowErrs[i] == nil {
+			added = append(added, &products[i])
+		}
+	}
+	s.detectDuplicates(ctx, added...)
+
+	return rowErrs, nil
+}
+
+// функция постранично обходит весь каталог и передает каждый продукт в send,
+// так весь каталог не держится в памяти и не требует одного долгого запроса
+func (s *ProductService) ExportProducts(ctx context.Context, send func(product *entities.ProductInfo) error) error {
+	fi := "service.ProductService.ExportProducts"
+
+	filter := entities.ProductFilter{Limit: entities.MaxPageLimit}
+	for {
+		products, err := s.repo.GetProducts(ctx, &filter)
+		if err != nil {
+			s.log.Error("%s: Error Getting Products: %v", fi, err)
+			return err
+		}
+
+		for i := range products {
+			if err := send(&products[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < filter.Limit {
+			return nil
+		}
+		filter.Cursor = products[len(products)-1].ProductId
+	}
+}
+
+// функция передает в publish пачки продуктов после republish.FromId в порядке id,
+// не быстрее republish.Rate продуктов в секунду
+func (s *ProductService) RepublishProducts(ctx context.Context, republish *entities.Republish, publish func(products []entities.ProductInfo) error) error {
+	fi := "service.ProductService.RepublishProducts"
+
+	//пачка не больше числа продуктов в секунду, пауза между пачками выравнивает скорость
+	filter := entities.ProductFilter{Cursor: republish.FromId, Limit: min(entities.BulkBatchSize, republish.Rate)}
+	ticker := time.NewTicker(time.Duration(filter.Limit) * time.Second / time.Duration(republish.Rate))
+	defer ticker.Stop()
+
+	for {
+		products, err := s.repo.GetProducts(ctx, &filter)
+		if err != nil {
+			s.log.Error("%s: Error Getting Products: %v", fi, err)
+			return err
+		}
+
+		if len(products) != 0 {
+			if err := publish(products); err != nil {
+				return err
+			}
+		}
+
+		if len(products) < filter.Limit {
+			return nil
+		}
+		filter.Cursor = products[len(products)-1].ProductId
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 // функция заменяет информацию о пользователе в базе по его id
 func (s *ProductService) UpdateProduct(ctx context.Context, productId int, product *entities.ProductInfo) error {
 	fi := "service.ProductService.UpdateProduct"
 
+	s.suggestKeyWords(ctx, product)
+
 	if err := s.repo.UpdateProduct(ctx, productId, product); err != nil {
 		s.log.Error("%s: Error Updating Product: %v", fi, err)
 		return err
 
 	}
 
+	//описание могло измениться, дубликаты продукта пересчитываются
+	product.ProductId = productId
+	s.detectDuplicates(ctx, product)
+
 	return nil
 }
 
-// функция удаляет информацию о существующем продукте
-func (s *ProductService) DeleteProduct(ctx context.Context, productId int) error {
+// функция переводит продукт в новый статус, если переход допустим
+func (s *ProductService) ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.ChangeProductStatus"
+
+	product, err := s.repo.ChangeProductStatus(ctx, productId, status)
+	if err != nil {
+		s.log.Error("%s: Error Changing Product Status: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция удаляет информацию о существующем продукте и возвращает его снимок с версией удаления
+func (s *ProductService) DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error) {
 	fi := "service.ProductService.DeleteProduct"
 
-	if err := s.repo.DeleteProduct(ctx, productId); err != nil {
+	product, err := s.repo.DeleteProduct(ctx, productId)
+	if err != nil {
 		s.log.Error("%s: Error Deleting Product: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция возвращает информацию о продукте по его id
+func (s *ProductService) GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.GetProductById"
+
+	product, err := s.repo.GetProductById(ctx, productId)
+	if err != nil {
+		s.log.Error("%s: Error Getting Product: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция возвращает страницу продуктов, удовлетворяющих фильтру
+func (s *ProductService) GetProducts(ctx context.Context, filter *entities.ProductFilter) (*entities.ProductList, error) {
+	fi := "service.ProductService.GetProducts"
+
+	//запрашиваем на один продукт больше, чтобы понять, есть ли следующая страница
+	pageFilter := *filter
+	pageFilter.Limit = filter.Limit + 1
+
+	products, err := s.repo.GetProducts(ctx, &pageFilter)
+	if err != nil {
+		s.log.Error("%s: Error Getting Products: %v", fi, err)
+		return nil, err
+	}
+
+	list := &entities.ProductList{Products: products}
+	if len(products) > filter.Limit {
+		list.Products = products[:filter.Limit]
+		list.NextCursor = list.Products[filter.Limit-1].ProductId
+	}
+
+	return list, nil
+}
+
+// функция выполняет полнотекстовый поиск продуктов, результаты упорядочены по рангу
+func (s *ProductService) SearchProducts(ctx context.Context, search *entities.ProductSearch) (*entities.ProductSearchResult, error) {
+	fi := "service.ProductService.SearchProducts"
+
+	//запрашиваем на один продукт больше, чтобы понять, есть ли следующая страница
+	pageSearch := *search
+	pageSearch.Limit = search.Limit + 1
+
+	hits, err := s.repo.SearchProducts(ctx, &pageSearch)
+	if err != nil {
+		s.log.Error("%s: Error Searching Products: %v", fi, err)
+		return nil, err
+	}
+
+	result := &entities.ProductSearchResult{Products: hits}
+	if len(hits) > search.Limit {
+		result.Products = hits[:search.Limit]
+		result.NextOffset = search.Offset + search.Limit
+	}
+
+	return result, nil
+}
+
+// функция возвращает все версии продукта, включая версию удаления
+func (s *ProductService) GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error) {
+	fi := "service.ProductService.GetProductHistory"
+
+	history, err := s.repo.GetProductHistory(ctx, productId)
+	if err != nil {
+		s.log.Error("%s: Error Getting Product History: %v", fi, err)
+		return nil, err
+	}
+	return history, nil
+}
+
+// функция возвращает продукт в том виде, в котором он был на момент asOf
+func (s *ProductService) GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.GetProductAsOf"
+
+	product, err := s.repo.GetProductAsOf(ctx, productId, asOf)
+	if err != nil {
+		s.log.Error("%s: Error Getting Product As Of: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция восстанавливает продукт из снимка одной из его версий
+func (s *ProductService) RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.RestoreProductVersion"
+
+	product, err := s.repo.RestoreProductVersion(ctx, productId, version)
+	if err != nil {
+		s.log.Error("%s: Error Restoring Product Version: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция добавляет новую категорию в дерево категорий
+func (s *ProductService) CreateCategory(ctx context.Context, category *entities.Category) (int, error) {
+	fi := "service.ProductService.CreateCategory"
+
+	categoryId, err := s.repo.AddCategory(ctx, category)
+	if err != nil {
+		s.log.Error("%s: Error Creating Category: %v", fi, err)
+		return 0, err
+	}
+	return categoryId, nil
+}
+
+// функция возвращает категорию вместе с полным путем от корня
+func (s *ProductService) GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error) {
+	fi := "service.ProductService.GetCategoryById"
+
+	category, err := s.repo.GetCategoryById(ctx, categoryId)
+	if err != nil {
+		s.log.Error("%s: Error Getting Category: %v", fi, err)
+		return nil, err
+	}
+	return category, nil
+}
+
+// функция возвращает все дерево категорий
+func (s *ProductService) GetCategories(ctx context.Context) ([]entities.Category, error) {
+	fi := "service.ProductService.GetCategories"
+
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		s.log.Error("%s: Error Getting Categories: %v", fi, err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+// функция переименовывает категорию и/или переносит ее к другому родителю
+func (s *ProductService) UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error {
+	fi := "service.ProductService.UpdateCategory"
+
+	if err := s.repo.UpdateCategory(ctx, categoryId, category); err != nil {
+		s.log.Error("%s: Error Updating Category: %v", fi, err)
 		return err
 	}
 	return nil
 }
+
+// функция удаляет категорию без подкатегорий и продуктов
+func (s *ProductService) DeleteCategory(ctx context.Context, categoryId int) error {
+	fi := "service.ProductService.DeleteCategory"
+
+	if err := s.repo.DeleteCategory(ctx, categoryId); err != nil {
+		s.log.Error("%s: Error Deleting Category: %v", fi, err)
+		return err
+	}
+	return nil
+}
+
+// функция возвращает число продуктов мерчанта и его квоту
+func (s *ProductService) GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error) {
+	fi := "service.ProductService.GetMerchant"
+
+	merchant, err := s.repo.GetMerchant(ctx, merchantId)
+	if err != nil {
+		s.log.Error("%s: Error Getting Merchant: %v", fi, err)
+		return nil, err
+	}
+	return merchant, nil
+}
+
+// функция устанавливает максимальное число продуктов мерчанта
+func (s *ProductService) SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error {
+	fi := "service.ProductService.SetMerchantQuota"
+
+	if err := s.repo.SetMerchantQuota(ctx, merchantId, maxProducts); err != nil {
+		s.log.Error("%s: Error Setting Merchant Quota: %v", fi, err)
+		return err
+	}
+	return nil
+}
+
+// функция возвращает ключевые слова продукта
+func (s *ProductService) GetProductKeyWords(ctx context.Context, productId int) ([]string, error) {
+	fi := "service.ProductService.GetProductKeyWords"
+
+	product, err := s.repo.GetProductById(ctx, productId)
+	if err != nil {
+		s.log.Error("%s: Error Getting Product: %v", fi, err)
+		return nil, err
+	}
+	return product.ProductKeyWords, nil
+}
+
+// функция добавляет продукту одно ключевое слово, остальные ключевые слова не изменяются
+func (s *ProductService) AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.AddProductKeyWord"
+
+	product, err := s.repo.AddProductKeyWord(ctx, productId, keyWord)
+	if err != nil {
+		s.log.Error("%s: Error Adding Product KeyWord: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция удаляет у продукта одно ключевое слово, остальные ключевые слова не изменяются
+func (s *ProductService) DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.DeleteProductKeyWord"
+
+	product, err := s.repo.DeleteProductKeyWord(ctx, productId, keyWord)
+	if err != nil {
+		s.log.Error("%s: Error Deleting Product KeyWord: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
+
+// функция возвращает все ключевые слова с числом продуктов, в которых они используются
+func (s *ProductService) GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error) {
+	fi := "service.ProductService.GetKeyWords"
+
+	keyWords, err := s.repo.GetKeyWords(ctx)
+	if err != nil {
+		s.log.Error("%s: Error Getting KeyWords: %v", fi, err)
+		return nil, err
+	}
+	return keyWords, nil
+}
+
+// функция переносит предложенные ключевые слова в ключевые слова продукта
+func (s *ProductService) ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error) {
+	fi := "service.ProductService.ConfirmSuggestedKeyWords"
+
+	product, err := s.repo.ConfirmSuggestedKeyWords(ctx, productId, keyWords)
+	if err != nil {
+		s.log.Error("%s: Error Confirming Suggested KeyWords: %v", fi, err)
+		return nil, err
+	}
+	return product, nil
+}
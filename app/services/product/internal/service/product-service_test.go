@@ -4,9 +4,12 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/pkg/config"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 )
@@ -20,6 +23,17 @@ func (m *RepositoryMock) AddNewProduct(ctx context.Context, productInfo *entitie
 	return 1, nil
 }
 
+func (m *RepositoryMock) AddNewProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error) {
+	rowErrs := make([]error, len(products))
+	for i := range products {
+		if products[i].Category == "некорректно" {
+			return nil, errors.New("ошибка")
+		}
+		products[i].ProductId = i + 1
+	}
+	return rowErrs, nil
+}
+
 func (m *RepositoryMock) UpdateProduct(ctx context.Context, productId int, productInfo *entities.ProductInfo) error {
 	if productInfo.Category == "некорректно" {
 		return errors.New("ошибка")
@@ -27,19 +41,196 @@ func (m *RepositoryMock) UpdateProduct(ctx context.Context, productId int, produ
 	return nil
 }
 
-func (m *RepositoryMock) DeleteProduct(ctx context.Context, productId int) error {
+func (m *RepositoryMock) DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, Version: 2}, nil
+}
+
+func (m *RepositoryMock) GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId}, nil
+}
+
+// мок отдает продукты с id от Cursor+1 до Cursor+3
+func (m *RepositoryMock) GetProducts(ctx context.Context, filter *entities.ProductFilter) ([]entities.ProductInfo, error) {
+	if filter.Keyword == "некорректно" {
+		return nil, errors.New("ошибка")
+	}
+	products := make([]entities.ProductInfo, 0)
+	for i := 1; i <= 3 && i <= filter.Limit; i++ {
+		products = append(products, entities.ProductInfo{ProductId: filter.Cursor + i})
+	}
+	return products, nil
+}
+
+// мок отдает три найденных продукта начиная с Offset
+func (m *RepositoryMock) SearchProducts(ctx context.Context, search *entities.ProductSearch) ([]entities.ProductSearchHit, error) {
+	if search.Query == "некорректно" {
+		return nil, errors.New("ошибка")
+	}
+	hits := make([]entities.ProductSearchHit, 0)
+	for i := 1; i <= 3 && i <= search.Limit; i++ {
+		hits = append(hits, entities.ProductSearchHit{ProductInfo: entities.ProductInfo{ProductId: search.Offset + i}})
+	}
+	return hits, nil
+}
+
+func (m *RepositoryMock) ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, Status: status}, nil
+}
+
+func (m *RepositoryMock) GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return []entities.ProductVersion{{ProductId: productId, Version: 1, Action: entities.VersionActionCreate}}, nil
+}
+
+func (m *RepositoryMock) GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId}, nil
+}
+
+func (m *RepositoryMock) RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error) {
 	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, Version: version + 1}, nil
+}
+
+func (m *RepositoryMock) AddCategory(ctx context.Context, category *entities.Category) (int, error) {
+	if category.Name == "некорректно" {
+		return 0, errors.New("ошибка")
+	}
+	return 1, nil
+}
+
+func (m *RepositoryMock) GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error) {
+	if categoryId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.Category{CategoryId: categoryId}, nil
+}
+
+func (m *RepositoryMock) GetCategories(ctx context.Context) ([]entities.Category, error) {
+	return []entities.Category{{CategoryId: 1}, {CategoryId: 2, ParentId: 1}}, nil
+}
+
+func (m *RepositoryMock) UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error {
+	if categoryId == 0 {
 		return errors.New("ошибка")
 	}
 	return nil
 }
 
+func (m *RepositoryMock) DeleteCategory(ctx context.Context, categoryId int) error {
+	if categoryId == 0 {
+		return errors.New("ошибка")
+	}
+	return nil
+}
+
+func (m *RepositoryMock) GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error) {
+	if merchantId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.Merchant{MerchantId: merchantId, MaxProducts: entities.DefaultMerchantQuota}, nil
+}
+
+func (m *RepositoryMock) SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error {
+	if merchantId == 0 {
+		return errors.New("ошибка")
+	}
+	return nil
+}
+
+func (m *RepositoryMock) AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{keyWord}}, nil
+}
+
+func (m *RepositoryMock) DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{}}, nil
+}
+
+func (m *RepositoryMock) ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: keyWords}, nil
+}
+
+// мок: каталог из 10 продуктов, "фильм" есть в 9 из них, остальные термины - в одном,
+// описание "error" - ошибка получения частот
+func (m *RepositoryMock) GetDocumentFrequencies(ctx context.Context, terms []string) (int, map[string]int, error) {
+	if slices.Contains(terms, "error") {
+		return 0, nil, errors.New("ошибка")
+	}
+	frequencies := make(map[string]int, len(terms))
+	for _, term := range terms {
+		frequencies[term] = 1
+	}
+	frequencies["фильм"] = 9
+	return 10, frequencies, nil
+}
+
+func (m *RepositoryMock) GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error) {
+	return []entities.KeyWordUsage{{KeyWord: "Футбол", Products: 2}}, nil
+}
+
+// мок: кандидаты - продукт 7 с похожим описанием и продукт 8 с другим,
+// продукт 13 - ошибка получения кандидатов
+func (m *RepositoryMock) GetDuplicateCandidates(ctx context.Context, signature *entities.ProductSignature) ([]entities.ProductSignature, error) {
+	if signature.ProductId == 13 {
+		return nil, errors.New("ошибка")
+	}
+	return []entities.ProductSignature{
+		*productSignature(7, "Японский меч ручной работы из дамасской стали"),
+		*productSignature(8, "Футбольный мяч для игры на траве"),
+	}, nil
+}
+
+func (m *RepositoryMock) SetProductSignature(ctx context.Context, signature *entities.ProductSignature, duplicates []entities.ProductDuplicate) error {
+	return nil
+}
+
+func (m *RepositoryMock) GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return []entities.ProductDuplicate{{ProductId: 7, Similarity: 0.9}}, nil
+}
+
+func (m *RepositoryMock) MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error) {
+	if productId == 0 {
+		return nil, errors.New("ошибка")
+	}
+	return &entities.ProductMergeResult{
+		Product:   &entities.ProductInfo{ProductId: productId},
+		Duplicate: &entities.ProductInfo{ProductId: duplicateId},
+	}, nil
+}
+
 func TestService_CreateProduct_Correct(t *testing.T) {
 	service := NewProductService(
 		&RepositoryMock{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		),
+		), config.KeyWordsConfig{},
 	)
 
 	productId, err := service.CreateProduct(context.Background(), &entities.ProductInfo{
@@ -55,7 +246,7 @@ func TestService_CreateProduct_CorrectButSomeError(t *testing.T) {
 		&RepositoryMock{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		),
+		), config.KeyWordsConfig{},
 	)
 
 	productId, err := service.CreateProduct(context.Background(), &entities.ProductInfo{
@@ -71,7 +262,7 @@ func TestService_UpdateProduct_Correct(t *testing.T) {
 		&RepositoryMock{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		),
+		), config.KeyWordsConfig{},
 	)
 	err := service.UpdateProduct(context.Background(), 1, &entities.ProductInfo{
 		Category: "корректно",
@@ -85,7 +276,7 @@ func TestService_UpdateProduct_CorrectButSomeError(t *testing.T) {
 		&RepositoryMock{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		),
+		), config.KeyWordsConfig{},
 	)
 
 	err := service.UpdateProduct(context.Background(), 1, &entities.ProductInfo{
@@ -100,23 +291,591 @@ func TestService_DeleteProduct_Correct(t *testing.T) {
 		&RepositoryMock{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		),
+		), config.KeyWordsConfig{},
 	)
 
-	err := service.DeleteProduct(context.Background(), 1)
+	product, err := service.DeleteProduct(context.Background(), 1)
 
 	assert.NoError(t, err)
+	assert.Equal(t, 2, product.Version)
 }
 
 func TestService_DeleteProductt_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	_, err := service.DeleteProduct(context.Background(), 0)
+
+	assert.Error(t, err)
+}
+
+func TestService_GetProducts_CorrectHasNextPage(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	list, err := service.GetProducts(context.Background(), &entities.ProductFilter{Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, list.Products, 2)
+	assert.Equal(t, 2, list.NextCursor)
+}
+
+func TestService_GetProducts_CorrectLastPage(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	list, err := service.GetProducts(context.Background(), &entities.ProductFilter{Cursor: 5, Limit: 3})
+
+	assert.NoError(t, err)
+	assert.Len(t, list.Products, 3)
+	assert.Equal(t, 0, list.NextCursor)
+}
+
+func TestService_GetProducts_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	list, err := service.GetProducts(context.Background(), &entities.ProductFilter{
+		Keyword: "некорректно", Limit: 1,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, list)
+}
+
+func TestService_SearchProducts_CorrectHasNextPage(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	result, err := service.SearchProducts(context.Background(), &entities.ProductSearch{
+		Query: "гитара", Offset: 4, Limit: 2,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Products, 2)
+	assert.Equal(t, 6, result.NextOffset)
+}
+
+func TestService_SearchProducts_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	result, err := service.SearchProducts(context.Background(), &entities.ProductSearch{
+		Query: "некорректно", Limit: 2,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestService_ChangeProductStatus_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	product, err := service.ChangeProductStatus(context.Background(), 1, entities.StatusArchived)
+
+	assert.NoError(t, err)
+	assert.Equal(t, entities.StatusArchived, product.Status)
+}
+
+func TestService_ChangeProductStatus_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	product, err := service.ChangeProductStatus(context.Background(), 0, entities.StatusArchived)
+
+	assert.Error(t, err)
+	assert.Nil(t, product)
+}
+
+func TestService_CreateCategory_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	categoryId, err := service.CreateCategory(context.Background(), &entities.Category{Name: "кино"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, categoryId)
+}
+
+func TestService_CreateCategory_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	categoryId, err := service.CreateCategory(context.Background(), &entities.Category{Name: "некорректно"})
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, categoryId)
+}
+
+func TestService_GetCategories_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	categories, err := service.GetCategories(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+}
+
+func TestService_UpdateCategory_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	err := service.UpdateCategory(context.Background(), 0, &entities.Category{Name: "кино"})
+
+	assert.Error(t, err)
+}
+
+func TestService_DeleteCategory_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	err := service.DeleteCategory(context.Background(), 1)
+
+	assert.NoError(t, err)
+}
+
+func TestService_CreateProducts_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	products := []entities.ProductInfo{{Category: "корректно"}, {Category: "корректно"}}
+	rowErrs, err := service.CreateProducts(context.Background(), products)
+
+	assert.NoError(t, err)
+	assert.Len(t, rowErrs, 2)
+	assert.Equal(t, 2, products[1].ProductId)
+}
+
+func TestService_CreateProducts_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	rowErrs, err := service.CreateProducts(context.Background(), []entities.ProductInfo{{Category: "некорректно"}})
+
+	assert.Error(t, err)
+	assert.Nil(t, rowErrs)
+}
+
+func TestService_ExportProducts_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	ids := make([]int, 0)
+	err := service.ExportProducts(context.Background(), func(product *entities.ProductInfo) error {
+		ids = append(ids, product.ProductId)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids)
+}
+
+func TestService_ExportProducts_SendError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	err := service.ExportProducts(context.Background(), func(product *entities.ProductInfo) error {
+		return errors.New("ошибка")
+	})
+
+	assert.Error(t, err)
+}
+
+func TestService_GetProductHistory_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	history, err := service.GetProductHistory(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, entities.VersionActionCreate, history[0].Action)
+}
+
+func TestService_RestoreProductVersion_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	product, err := service.RestoreProductVersion(context.Background(), 0, 1)
+
+	assert.Error(t, err)
+	assert.Nil(t, product)
+}
+
+func TestService_GetMerchant_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	merchant, err := service.GetMerchant(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, merchant.MerchantId)
+	assert.Equal(t, entities.DefaultMerchantQuota, merchant.MaxProducts)
+}
+
+func TestService_SetMerchantQuota_Incorrect(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	err := service.SetMerchantQuota(context.Background(), 0, 10)
+
+	assert.Error(t, err)
+}
+
+func TestService_RepublishProducts_FromId(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	ids := make([]int, 0)
+	err := service.RepublishProducts(context.Background(), &entities.Republish{FromId: 5, Rate: 1000},
+		func(products []entities.ProductInfo) error {
+			for _, product := range products {
+				ids = append(ids, product.ProductId)
+			}
+			return nil
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{6, 7, 8}, ids)
+}
+
+func TestService_RepublishProducts_Cancelled(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	// при скорости 2 продукта в секунду пачка - 2 продукта, следующая пачка ждет паузы
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batches := 0
+	err := service.RepublishProducts(ctx, &entities.Republish{Rate: 2},
+		func(products []entities.ProductInfo) error {
+			batches++
+			assert.Len(t, products, 2)
+			cancel()
+			return nil
+		})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, batches)
+}
+
+func TestService_AddProductKeyWord_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	product, err := service.AddProductKeyWord(context.Background(), 1, "Футбол")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Футбол"}, product.ProductKeyWords)
+}
+
+func TestService_DeleteProductKeyWord_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	product, err := service.DeleteProductKeyWord(context.Background(), 0, "Футбол")
+
+	assert.Error(t, err)
+	assert.Nil(t, product)
+}
+
+func TestService_GetProductKeyWords_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	keyWords, err := service.GetProductKeyWords(context.Background(), 0)
+
+	assert.Error(t, err)
+	assert.Nil(t, keyWords)
+}
+
+func TestService_GetKeyWords_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		), config.KeyWordsConfig{},
+	)
+
+	keyWords, err := service.GetKeyWords(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, keyWords[0].Products)
+}
+
+func TestService_CreateProduct_SuggestedKeyWords(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{MaxSuggested: 2},
+	)
+
+	// "фильм" есть почти во всех продуктах каталога, стоп-слова и ключевые слова не предлагаются
+	product := entities.ProductInfo{
+		Description:     "Фильм про футбол и самураев, the best фильм о японии",
+		ProductKeyWords: []string{"Футбол"},
+	}
+	_, err := service.CreateProduct(context.Background(), &product)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"самураев", "японии"}, product.SuggestedKeyWords)
+	assert.Equal(t, []string{"Футбол"}, product.ProductKeyWords)
+}
+
+func TestService_UpdateProduct_AutoApplySuggestedKeyWords(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{AutoApply: true, MaxSuggested: 1},
+	)
+
+	product := entities.ProductInfo{
+		Description:     "Sushi and sashimi, sushi delivery",
+		ProductKeyWords: []string{"Японская"},
+	}
+	err := service.UpdateProduct(context.Background(), 1, &product)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Японская", "sushi"}, product.ProductKeyWords)
+	assert.Empty(t, product.SuggestedKeyWords)
+}
+
+func TestService_CreateProduct_SuggestedKeyWordsError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{},
+	)
+
+	// без частот терминов продукт сохраняется без предложений
+	product := entities.ProductInfo{Description: "error"}
+	_, err := service.CreateProduct(context.Background(), &product)
+
+	assert.NoError(t, err)
+	assert.Empty(t, product.SuggestedKeyWords)
+}
+
+func TestService_ConfirmSuggestedKeyWords_CorrectButSomeError(t *testing.T) {
 	service := NewProductService(
 		&RepositoryMock{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.KeyWordsConfig{},
 	)
 
-	err := service.DeleteProduct(context.Background(), 0)
+	product, err := service.ConfirmSuggestedKeyWords(context.Background(), 0, nil)
 
 	assert.Error(t, err)
+	assert.Nil(t, product)
+}
+
+func TestDescriptionTerms(t *testing.T) {
+	terms := descriptionTerms("Это лучший фильм! The film is the BEST film, ёлка 4K")
+
+	// стоп-слова, короткие термины и термины, недопустимые как ключевые слова, отбрасываются
+	assert.Equal(t, map[string]int{"лучший": 1, "фильм": 1, "film": 2}, terms)
+}
+
+func TestProductSignature(t *testing.T) {
+	original := productSignature(1, "Японский меч ручной работы из дамасской стали")
+
+	// регистр, знаки препинания и пробелы не влияют на сигнатуру
+	same := productSignature(2, "японский меч,  ручной работы из дамасской стали!")
+	assert.Equal(t, original.Signature, same.Signature)
+	assert.Equal(t, original.Bands, same.Bands)
+	assert.Equal(t, signatureSize/bandRows, len(original.Bands))
+
+	similar := productSignature(3, "Японский меч ручной работы из дамасской стали.Новинка")
+	assert.GreaterOrEqual(t, signatureSimilarity(original.Signature, similar.Signature), duplicateSimilarity)
+
+	different := productSignature(4, "Футбольный мяч для игры на траве")
+	assert.Less(t, signatureSimilarity(original.Signature, different.Signature), duplicateSimilarity)
+
+	assert.Nil(t, productSignature(5, " !? "))
+}
+
+func TestService_CreateProduct_FindsDuplicates(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{},
+	)
+
+	// из двух кандидатов дубликат только продукт 7 с похожим описанием
+	duplicates, err := service.findDuplicates(context.Background(), 1, "Японский меч ручной работы из дамасской стали!")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, duplicates)
+
+	// ошибка поиска дубликатов не мешает сохранению продукта
+	_, err = service.findDuplicates(context.Background(), 13, "Японский меч")
+	assert.Error(t, err)
+	productId, err := service.CreateProduct(context.Background(), &entities.ProductInfo{Description: "Японский меч"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, productId)
+}
+
+func TestService_DetectDuplicates_Correct(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{},
+	)
+
+	progress := make([]entities.DuplicateDetection, 0)
+	err := service.DetectDuplicates(context.Background(), 10, func(detection *entities.DuplicateDetection) error {
+		progress = append(progress, *detection)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.DuplicateDetection{{Checked: 3, LastId: 13}}, progress)
+}
+
+func TestService_MergeProducts_CorrectButSomeError(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{},
+	)
+
+	merge, err := service.MergeProducts(context.Background(), 0, 2)
+
+	assert.Error(t, err)
+	assert.Nil(t, merge)
+}
+
+func TestService_MergeProducts_IncorrectSelfMerge(t *testing.T) {
+	service := NewProductService(
+		&RepositoryMock{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.KeyWordsConfig{},
+	)
+
+	merge, err := service.MergeProducts(context.Background(), 2, 2)
+
+	assert.ErrorIs(t, err, entities.ErrSelfMerge)
+	assert.Nil(t, merge)
 }
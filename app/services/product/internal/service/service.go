@@ -2,24 +2,90 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 )
 
 type Service interface {
 	ProductCreater
+	ProductBulker
 	ProductUpdater
+	ProductStatusChanger
 	ProductDeleter
+	ProductGetter
+	ProductSearcher
+	ProductHistorian
+	CategoryManager
+	MerchantManager
+	ProductRepublisher
+	ProductKeyWordManager
+	ProductDeduplicator
 }
 
 type ProductCreater interface {
 	CreateProduct(ctx context.Context, user *entities.ProductInfo) (int, error)
 }
 
+type ProductBulker interface {
+	CreateProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error)
+	ExportProducts(ctx context.Context, send func(product *entities.ProductInfo) error) error
+}
+
 type ProductUpdater interface {
 	UpdateProduct(ctx context.Context, userId int, user *entities.ProductInfo) error
 }
 
+type ProductStatusChanger interface {
+	ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error)
+}
+
 type ProductDeleter interface {
-	DeleteProduct(ctx context.Context, productId int) error
+	DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error)
+}
+
+type ProductGetter interface {
+	GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error)
+	GetProducts(ctx context.Context, filter *entities.ProductFilter) (*entities.ProductList, error)
+}
+
+type ProductSearcher interface {
+	SearchProducts(ctx context.Context, search *entities.ProductSearch) (*entities.ProductSearchResult, error)
+}
+
+type ProductHistorian interface {
+	GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error)
+	GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error)
+	RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error)
+}
+
+type CategoryManager interface {
+	CreateCategory(ctx context.Context, category *entities.Category) (int, error)
+	GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error)
+	GetCategories(ctx context.Context) ([]entities.Category, error)
+	UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error
+	DeleteCategory(ctx context.Context, categoryId int) error
+}
+
+type MerchantManager interface {
+	GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error)
+	SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error
+}
+
+type ProductRepublisher interface {
+	RepublishProducts(ctx context.Context, republish *entities.Republish, publish func(products []entities.ProductInfo) error) error
+}
+
+type ProductKeyWordManager interface {
+	GetProductKeyWords(ctx context.Context, productId int) ([]string, error)
+	AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error)
+	DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error)
+	ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error)
+	GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error)
+}
+
+type ProductDeduplicator interface {
+	GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error)
+	MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error)
+	DetectDuplicates(ctx context.Context, fromId int, progress func(detection *entities.DuplicateDetection) error) error
 }
@@ -0,0 +1,405 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	csvContentType    = "text/csv"
+
+	// максимальный размер одной строки ndjson
+	maxNDJSONLineSize = 1 << 20
+)
+
+// тело запроса дальше не читается (слишком длинная строка или ошибка чтения), импорт прекращается
+var errBodyRead = errors.New("request body can`t be read")
+
+// построчное чтение продуктов из тела запроса, io.EOF - строки закончились
+type productDecoder interface {
+	Next() (*entities.ProductInfo, error)
+}
+
+// импорт продуктов из ndjson или csv, в ответ построчно возвращается результат по каждой строке
+func (h *Handler) importProducts(c *gin.Context) {
+	fi := "api.Handler.importProducts"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	format := bulkFormat(c)
+	if err := entities.ValidateBulkFormat(format); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	decoder, err := newProductDecoder(format, c.Request.Body)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	encoder := json.NewEncoder(c.Writer)
+
+	//результаты строк копятся до добавления пачки, чтобы ответ шел в порядке строк
+	results := make([]entities.BulkRowResult, 0, entities.BulkBatchSize)
+	batch := make([]entities.ProductInfo, 0, entities.BulkBatchSize)
+	batchRows := make([]int, 0, entities.BulkBatchSize)
+
+	flush := func() {
+		//200 с первой отправленной пачкой, дальше ошибки возвращаются в результатах строк
+		if !c.Writer.Written() {
+			c.Header("Content-Type", ndjsonContentType)
+			c.Status(http.StatusOK)
+		}
+
+		h.importBatch(ctx, fi, batch, batchRows, results)
+		for _, result := range results {
+			encoder.Encode(result)
+		}
+		c.Writer.Flush()
+
+		results = results[:0]
+		batch = batch[:0]
+		batchRows = batchRows[:0]
+	}
+
+	for row := 1; ; row++ {
+		product, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, errBodyRead) {
+			h.stopImport(c, fi, row, err, flush)
+			return
+		} else if err == nil {
+			err = product.ValidateNewProductInfo()
+		}
+		if err == nil {
+			err = assignMerchant(c, product)
+		}
+
+		if err != nil {
+			results = append(results, entities.BulkRowResult{Row: row, Error: err.Error()})
+		} else {
+			results = append(results, entities.BulkRowResult{Row: row})
+			batch = append(batch, *product)
+			batchRows = append(batchRows, len(results)-1)
+		}
+
+		if len(batch) == entities.BulkBatchSize {
+			flush()
+		}
+	}
+	flush()
+}
+
+// остановка импорта при ошибке чтения тела: до отправки ответа - 400 (строка длиннее
+// maxNDJSONLineSize) или 500, иначе прочитанные строки добавляются, а ошибка возвращается
+// последней строкой ответа
+func (h *Handler) stopImport(c *gin.Context, fi string, row int, err error, flush func()) {
+
+	status := http.StatusInternalServerError
+	if errors.Is(err, bufio.ErrTooLong) {
+		status = http.StatusBadRequest
+	}
+
+	//400, 500
+	if !c.Writer.Written() {
+		logMassage(fi, h.log, err.Error(), status)
+		newErrorResponse(c, status, err.Error())
+		return
+	}
+
+	logMassage(fi, h.log, err.Error(), status)
+	flush()
+	json.NewEncoder(c.Writer).Encode(entities.BulkRowResult{Row: row, Error: err.Error()})
+	c.Writer.Flush()
+	c.Abort()
+}
+
+// добавление пачки продуктов и отправка событий о добавленных продуктах одной пачкой,
+// результаты записываются в results по индексам из batchRows
+func (h *Handler) importBatch(ctx context.Context, fi string, batch []entities.ProductInfo, batchRows []int, results []entities.BulkRowResult) {
+
+	if len(batch) == 0 {
+		return
+	}
+
+	rowErrs, err := h.service.CreateProducts(ctx, batch)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		for _, i := range batchRows {
+			results[i].Error = err.Error()
+		}
+		return
+	}
+
+	added := make([]entities.ProductInfo, 0, len(batch))
+	for j, i := range batchRows {
+		if rowErrs[j] != nil {
+			results[i].Error = rowErrs[j].Error()
+			continue
+		}
+		results[i].ProductId = batch[j].ProductId
+		added = append(added, batch[j])
+	}
+
+	//продукты уже сохранены, ошибка отправки событий только логгируется
+	if err := h.kafka.SendMessages(ctx, added, "add"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// выгрузка всего каталога в ndjson или csv
+func (h *Handler) exportProducts(c *gin.Context) {
+	fi := "api.Handler.exportProducts"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	format := c.DefaultQuery("format", entities.BulkFormatNDJSON)
+
+	//400
+	if err := entities.ValidateBulkFormat(format); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		written int
+		encode  func(product *entities.ProductInfo) error
+		csvW    *csv.Writer
+	)
+	if format == entities.BulkFormatCSV {
+		csvW = csv.NewWriter(c.Writer)
+		encode = func(product *entities.ProductInfo) error {
+			return csvW.Write(productToCSV(product))
+		}
+	} else {
+		encoder := json.NewEncoder(c.Writer)
+		encode = func(product *entities.ProductInfo) error {
+			return encoder.Encode(product)
+		}
+	}
+
+	err := h.service.ExportProducts(ctx, func(product *entities.ProductInfo) error {
+		//заголовки и статус отправляются вместе с первым продуктом
+		if written == 0 {
+			writeExportHeader(c, format, csvW)
+		}
+		if err := encode(product); err != nil {
+			return err
+		}
+
+		written++
+		if written%entities.BulkBatchSize == 0 {
+			if csvW != nil {
+				csvW.Flush()
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	//500, если данные уже начали отправляться, ответ просто обрывается
+	if err != nil && written == 0 {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		c.Abort()
+		return
+	}
+
+	//200
+	if written == 0 {
+		writeExportHeader(c, format, csvW)
+	}
+	if csvW != nil {
+		csvW.Flush()
+	}
+	c.Writer.Flush()
+}
+
+func writeExportHeader(c *gin.Context, format string, csvW *csv.Writer) {
+	if format == entities.BulkFormatCSV {
+		c.Header("Content-Type", csvContentType)
+		c.Header("Content-Disposition", `attachment; filename="products.csv"`)
+		c.Status(http.StatusOK)
+		csvW.Write(entities.CSVColumns)
+		return
+	}
+
+	c.Header("Content-Type", ndjsonContentType)
+	c.Header("Content-Disposition", `attachment; filename="products.ndjson"`)
+	c.Status(http.StatusOK)
+}
+
+// формат импорта из параметра format, иначе из Content-Type (по умолчанию ndjson)
+func bulkFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == csvContentType {
+		return entities.BulkFormatCSV
+	}
+
+	return entities.BulkFormatNDJSON
+}
+
+func newProductDecoder(format string, body io.Reader) (productDecoder, error) {
+	if format == entities.BulkFormatCSV {
+		return newCSVDecoder(body)
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &ndjsonDecoder{scanner: scanner}, nil
+}
+
+// каждая строка - отдельный json продукта, пустые строки пропускаются
+type ndjsonDecoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *ndjsonDecoder) Next() (*entities.ProductInfo, error) {
+	for d.scanner.Scan() {
+		line := strings.TrimSpace(d.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var product entities.ProductInfo
+		if err := json.Unmarshal([]byte(line), &product); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return &product, nil
+	}
+
+	if err := d.scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return nil, fmt.Errorf("%w: line is longer than %d bytes: %w", errBodyRead, maxNDJSONLineSize, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %w", errBodyRead, err)
+	}
+	return nil, io.EOF
+}
+
+// первая строка csv - заголовок с именами колонок из entities.CSVColumns
+type csvDecoder struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVDecoder(body io.Reader) (*csvDecoder, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid csv: header is missing")
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	return &csvDecoder{reader: reader, columns: columns}, nil
+}
+
+func (d *csvDecoder) Next() (*entities.ProductInfo, error) {
+	record, err := d.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	} else if errors.As(err, &parseErr) {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %w", errBodyRead, err)
+	}
+
+	var product entities.ProductInfo
+
+	if product.ProductId, err = d.intField(record, "productId"); err != nil {
+		return nil, err
+	}
+	if product.CategoryId, err = d.intField(record, "categoryId"); err != nil {
+		return nil, err
+	}
+	if product.MerchantId, err = d.intField(record, "merchantId"); err != nil {
+		return nil, err
+	}
+	product.Description = d.field(record, "description")
+	product.Status = d.field(record, "status")
+	if keyWords := d.field(record, "productKeyWords"); keyWords != "" {
+		product.ProductKeyWords = strings.Split(keyWords, entities.CSVKeyWordsSeparator)
+	}
+	if attributes := d.field(record, "attributes"); attributes != "" {
+		if err := json.Unmarshal([]byte(attributes), &product.Attributes); err != nil {
+			return nil, fmt.Errorf("invalid csv: attributes column incorrect: %w", err)
+		}
+	}
+
+	return &product, nil
+}
+
+func (d *csvDecoder) field(record []string, name string) string {
+	i, ok := d.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+func (d *csvDecoder) intField(record []string, name string) (int, error) {
+	value := d.field(record, name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid csv: %s column incorrect: %w", name, err)
+	}
+	return result, nil
+}
+
+func productToCSV(product *entities.ProductInfo) []string {
+	attributes := ""
+	if len(product.Attributes) != 0 {
+		data, _ := json.Marshal(product.Attributes)
+		attributes = string(data)
+	}
+
+	return []string{
+		strconv.Itoa(product.ProductId),
+		strconv.Itoa(product.CategoryId),
+		product.Category,
+		strings.Join(product.CategoryPath, "/"),
+		product.Description,
+		product.Status,
+		strings.Join(product.ProductKeyWords, entities.CSVKeyWordsSeparator),
+		attributes,
+		strconv.Itoa(product.MerchantId),
+	}
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+)
+
+// разбор построчного ответа импорта
+func decodeBulkResults(t *testing.T, body *bytes.Buffer) []entities.BulkRowResult {
+	results := make([]entities.BulkRowResult, 0)
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var result entities.BulkRowResult
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("Error decoding result: %v", err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestHandler_ImportProducts_NDJSON(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	body := strings.Join([]string{
+		`{"userId": 1, "categoryId": 1, "description": "корректненько", "status": "active", "productKeyWords": ["фильм"]}`,
+		`{"userId": 1, "categoryId": 1,`,
+		``,
+		`{"userId": 1, "categoryId": 3, "description": "корректненько", "status": "active"}`,
+		`{"userId": 1, "categoryId": 1, "description": "корректненько", "status": "unknown"}`,
+	}, "\n")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	c.Request.Header.Set("Content-Type", "application/x-ndjson")
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	results := decodeBulkResults(t, w.Body)
+	assert.Equal(t, 4, len(results))
+	assert.Equal(t, 10, results[0].ProductId)
+	assert.Equal(t, "", results[0].Error)
+	assert.Equal(t, 2, results[1].Row)
+	assert.Equal(t, true, strings.HasPrefix(results[1].Error, "invalid json"))
+	assert.Equal(t, "category not found", results[2].Error)
+	assert.Equal(t, 0, results[2].ProductId)
+	assert.Equal(t, 4, results[3].Row)
+	assert.Equal(t, true, results[3].Error != "")
+}
+
+func TestHandler_ImportProducts_CSV(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	body := "productId,categoryId,description,status,productKeyWords\n" +
+		"1,1,корректненько,active,фильм|актер\n" +
+		"1,abc,корректненько,active,фильм\n"
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	c.Request.Header.Set("Content-Type", "text/csv; charset=utf-8")
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	results := decodeBulkResults(t, w.Body)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, 10, results[0].ProductId)
+	assert.Equal(t, true, strings.HasPrefix(results[1].Error, "invalid csv: categoryId column incorrect"))
+}
+
+func TestHandler_ImportProducts_CorrectButInternalError(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	body := `{"userId": 1, "categoryId": 1, "description": "интернал", "status": "active"}`
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk?format=ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	results := decodeBulkResults(t, w.Body)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Внутренняя ошибка сервера", results[0].Error)
+}
+
+func TestHandler_ImportProducts_WithoutProductId(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	body := `{"categoryId": 1, "description": "корректненько", "status": "active"}`
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk?format=ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	results := decodeBulkResults(t, w.Body)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "", results[0].Error)
+	assert.Equal(t, 10, results[0].ProductId)
+}
+
+func TestHandler_ImportProducts_LineTooLong(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	body := `{"categoryId": 1, "description": "корректненько", "status": "active"}` + "\n" +
+		`{"categoryId": 1, "description": "` + strings.Repeat("a", maxNDJSONLineSize) + `", "status": "active"}` + "\n" +
+		`{"categoryId": 1, "description": "корректненько", "status": "active"}`
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk?format=ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	assert.Equal(t, true, strings.Contains(w.Body.String(), "line is longer than"))
+}
+
+func TestHandler_ImportProducts_LineTooLongAfterBatch(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	row := `{"categoryId": 1, "description": "корректненько", "status": "active"}`
+	body := strings.Repeat(row+"\n", entities.BulkBatchSize+1) +
+		`{"categoryId": 1, "description": "` + strings.Repeat("a", maxNDJSONLineSize) + `", "status": "active"}` + "\n" +
+		row
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk?format=ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	//прочитанные строки добавлены, ошибка - последняя строка ответа, следующие строки не читаются
+	results := decodeBulkResults(t, w.Body)
+	assert.Equal(t, entities.BulkBatchSize+2, len(results))
+	assert.Equal(t, 10, results[entities.BulkBatchSize].ProductId)
+	assert.Equal(t, entities.BulkBatchSize+2, results[entities.BulkBatchSize+1].Row)
+	assert.Equal(t, true, strings.Contains(results[entities.BulkBatchSize+1].Error, "line is longer than"))
+}
+
+func TestHandler_ImportProducts_CSVReadError(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	body := io.MultiReader(
+		strings.NewReader("categoryId,description,status\n1,корректненько,active\n"),
+		iotest.ErrReader(errors.New("connection reset")),
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk?format=csv", body)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestHandler_ImportProducts_IncorrectFormat(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/bulk?format=xml", strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.importProducts(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_ExportProducts_NDJSON(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/export", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.exportProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	var product entities.ProductInfo
+	json.Unmarshal([]byte(lines[1]), &product)
+	assert.Equal(t, 2, product.ProductId)
+}
+
+func TestHandler_ExportProducts_CSV(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/export?format=csv", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.exportProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Error reading csv: %v", err)
+	}
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, entities.CSVColumns, records[0])
+	assert.Equal(t, "фильм|актер", records[1][6])
+}
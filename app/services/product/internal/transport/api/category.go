@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) addNewCategory(c *gin.Context) {
+	var category entities.Category
+	fi := "api.Handler.addNewCategory"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	if err := c.BindJSON(&category); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := category.ValidateCategoryInfo(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//400, 409 и 500
+	id, err := h.service.CreateCategory(ctx, &category)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrCategoryAlreadyExists) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, map[string]interface{}{
+		"categoryId": id,
+	})
+}
+
+func (h *Handler) getCategories(c *gin.Context) {
+	fi := "api.Handler.getCategories"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//500
+	categories, err := h.service.GetCategories(ctx)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, categories)
+}
+
+func (h *Handler) getCategory(c *gin.Context) {
+	fi := "api.Handler.getCategory"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	catId, err := categoryIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404 и 500
+	category, err := h.service.GetCategoryById(ctx, catId)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, category)
+}
+
+func (h *Handler) updateCategory(c *gin.Context) {
+	var category entities.Category
+	fi := "api.Handler.updateCategory"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	catId, err := categoryIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&category); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	category.CategoryId = catId
+	if err := category.ValidateCategoryInfo(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404, 409 и 500
+	if err := h.service.UpdateCategory(ctx, catId, &category); errors.Is(err, repository.ErrCategoryNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrCategoryCycle) || errors.Is(err, repository.ErrCategoryAlreadyExists) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, map[string]interface{}{
+		"categoryId": catId,
+	})
+}
+
+func (h *Handler) deleteCategory(c *gin.Context) {
+	fi := "api.Handler.deleteCategory"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	catId, err := categoryIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404, 409 и 500
+	if err := h.service.DeleteCategory(ctx, catId); errors.Is(err, repository.ErrCategoryNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrCategoryInUse) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, "OK")
+}
+
+// id категории из пути запроса
+func categoryIdFromPath(c *gin.Context) (int, error) {
+	catIdStr := c.Param("categoryId")
+	if catIdStr == "" {
+		return 0, errors.New("categoryId parametr does not exist in path")
+	}
+
+	catId, err := strconv.Atoi(catIdStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := entities.ValidateCategoryId(catId); err != nil {
+		return 0, err
+	}
+
+	return catId, nil
+}
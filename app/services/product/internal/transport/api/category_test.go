@@ -0,0 +1,317 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_AddNewCategory_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonData, err := json.Marshal(entities.Category{Name: "комедия", ParentId: 1})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/category", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewCategory(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response map[string]int
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, response["categoryId"])
+}
+
+func TestHandler_AddNewCategory_IncorrectName(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonData, err := json.Marshal(entities.Category{Name: "кино 2"})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/category", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewCategory(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_AddNewCategory_CorrectButParentNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonData, err := json.Marshal(entities.Category{Name: "комедия", ParentId: 3})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/category", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewCategory(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_AddNewCategory_CorrectButAlreadyExists(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonData, err := json.Marshal(entities.Category{Name: "дубль"})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/category", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewCategory(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_GetCategories_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	handler.getCategories(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []entities.Category
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"кино", "комедия"}, response[1].Path)
+}
+
+func TestHandler_GetCategory_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "categoryId", Value: "3"},
+	}
+
+	handler.getCategory(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_GetCategory_IncorrectId(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "categoryId", Value: "0"},
+	}
+
+	handler.getCategory(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_UpdateCategory_CorrectButCycle(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "categoryId", Value: "1"},
+	}
+
+	jsonData, err := json.Marshal(entities.Category{Name: "кино", ParentId: 4})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("PATCH", "/category/1", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.updateCategory(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_UpdateCategory_IncorrectSelfParent(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "categoryId", Value: "1"},
+	}
+
+	jsonData, err := json.Marshal(entities.Category{Name: "кино", ParentId: 1})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("PATCH", "/category/1", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.updateCategory(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_DeleteCategory_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "categoryId", Value: "1"},
+	}
+
+	handler.deleteCategory(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestHandler_DeleteCategory_CorrectButInUse(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "categoryId", Value: "4"},
+	}
+
+	handler.deleteCategory(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_AddNewCategory_IncorrectAttributeSchema(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	// у атрибута типа enum должны быть допустимые значения
+	jsonData, err := json.Marshal(entities.Category{
+		Name: "одежда",
+		Attributes: []entities.AttributeSchema{
+			{Name: "brand", Type: entities.AttributeString, Required: true},
+			{Name: "size", Type: entities.AttributeEnum},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/category", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewCategory(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid attribute size: enum must have values", response["reason"])
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// почти одинаковые продукты по убыванию сходства описаний
+func (h *Handler) getProductDuplicates(c *gin.Context) {
+	fi := "api.Handler.getProductDuplicates"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404 и 500
+	duplicates, err := h.service.GetProductDuplicates(ctx, prdId)
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, duplicates)
+}
+
+// слияние дубликата с продуктом: для подписчиков - удаление дубликата
+// и обновление продукта, если к нему перешли ключевые слова дубликата
+func (h *Handler) mergeProducts(c *gin.Context) {
+	var merge entities.ProductMerge
+	fi := "api.Handler.mergeProducts"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&merge); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := merge.ValidateProductMerge(prdId); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//400, 404 и 500
+	result, err := h.service.MergeProducts(ctx, prdId, merge.DuplicateId)
+	if errors.Is(err, entities.ErrSelfMerge) {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if errk := h.kafka.SendMessage(ctx, *result.Duplicate, "delete"); errk != nil {
+		logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, errk.Error())
+		return
+	}
+
+	if result.Updated {
+		if errk := h.kafka.SendMessage(ctx, *result.Product, "update"); errk != nil {
+			logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
+			newErrorResponse(c, http.StatusInternalServerError, errk.Error())
+			return
+		}
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, result.Product)
+}
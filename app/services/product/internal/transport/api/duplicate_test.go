@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_GetProductDuplicates_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/1/duplicates", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProductDuplicates(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []entities.ProductDuplicate
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []entities.ProductDuplicate{{ProductId: 2, Similarity: 0.9}}, response)
+}
+
+func TestHandler_GetProductDuplicates_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/3/duplicates", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "3"},
+	}
+
+	handler.getProductDuplicates(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_MergeProducts_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	for _, duplicateId := range []string{"2", "4"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		var err error
+		c.Request, err = http.NewRequest("POST", "/admin/product/1/merge",
+			bytes.NewReader([]byte(`{"duplicateId":`+duplicateId+`}`)))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+
+		c.Params = gin.Params{
+			gin.Param{Key: "productId", Value: "1"},
+		}
+
+		handler.mergeProducts(c)
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		var response entities.ProductInfo
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, []string{"Сашими", "Футбол"}, response.ProductKeyWords)
+	}
+}
+
+func TestHandler_MergeProducts_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/3/merge",
+		bytes.NewReader([]byte(`{"duplicateId":2}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "3"},
+	}
+
+	handler.mergeProducts(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_MergeProducts_CorrectButKafkaError(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/1/merge",
+		bytes.NewReader([]byte(`{"duplicateId":5}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.mergeProducts(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestHandler_MergeProducts_IncorrectDuplicateId(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+	t.Setenv("ADMIN_TOKEN", "секрет")
+	router := handler.InitRoutes()
+
+	for body, code := range map[string]int{
+		`{"duplicateId":1}`:  http.StatusBadRequest,
+		`{"duplicateId":-2}`: http.StatusBadRequest,
+		`{}`:                 http.StatusBadRequest,
+		`{"duplicateId":2}`:  http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/admin/product/1/merge", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		req.Header.Set(adminTokenHeader, "секрет")
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Result().StatusCode)
+	}
+}
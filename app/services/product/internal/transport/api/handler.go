@@ -3,9 +3,11 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
@@ -46,10 +48,24 @@ func (h *Handler) addNewProduct(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	//400
+	if err := assignMerchant(c, &prdInfo); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	id, err := h.service.CreateProduct(ctx, &prdInfo)
-	//500
-	if err != nil {
+	//400, 409 (квота мерчанта исчерпана) и 500
+	if errors.Is(err, repository.ErrCategoryNotFound) || errors.Is(err, entities.ErrInvalidAttributes) {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrQuotaExceeded) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
 		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -58,7 +74,7 @@ func (h *Handler) addNewProduct(c *gin.Context) {
 	//Отправка сообщения в кафку
 	action := "add"
 	prdInfo.ProductId = id
-	if err := h.kafka.SendMessage(prdInfo, action); err != nil {
+	if err := h.kafka.SendMessage(ctx, prdInfo, action); err != nil {
 		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -102,12 +118,26 @@ func (h *Handler) updateProduct(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	//404 и 500
+	//400
+	if err := entities.ValidateStatus(prdInfo.Status); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400, 404, 409 и 500
 	prdInfo.ProductId = prdId
 	if err := h.service.UpdateProduct(ctx, prdId, &prdInfo); errors.Is(err, repository.ErrNotFound) {
 		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
 		newErrorResponse(c, http.StatusNotFound, err.Error())
 		return
+	} else if errors.Is(err, repository.ErrCategoryNotFound) || errors.Is(err, entities.ErrInvalidAttributes) {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	} else if errors.Is(err, entities.ErrInvalidStatusTransition) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
 	} else if err != nil {
 		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
@@ -115,7 +145,7 @@ func (h *Handler) updateProduct(c *gin.Context) {
 	}
 
 	action := "update"
-	if errk := h.kafka.SendMessage(prdInfo, action); errk != nil {
+	if errk := h.kafka.SendMessage(ctx, prdInfo, action); errk != nil {
 		logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, errk.Error())
 		return
@@ -125,8 +155,74 @@ func (h *Handler) updateProduct(c *gin.Context) {
 	c.AbortWithStatusJSON(http.StatusOK, "OK")
 }
 
+func (h *Handler) transitionProduct(c *gin.Context) {
+	var transition entities.StatusTransition
+	fi := "api.Handler.transitionProduct"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdIdStr := c.Param("productId")
+	if prdIdStr == "" {
+		logMassage(fi, h.log, "productId parametr does not exist in path", http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, "productId parametr does not exist in path")
+		return
+	}
+	//400
+	prdId, err := strconv.Atoi(prdIdStr)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := entities.ValidateProductId(prdId); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&transition); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := entities.ValidateStatus(transition.Status); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404, 409 и 500
+	prdInfo, err := h.service.ChangeProductStatus(ctx, prdId, transition.Status)
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, entities.ErrInvalidStatusTransition) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//смена статуса - обычное обновление продукта для подписчиков
+	action := "update"
+	if errk := h.kafka.SendMessage(ctx, *prdInfo, action); errk != nil {
+		logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, errk.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, prdInfo)
+}
+
 func (h *Handler) deleteProduct(c *gin.Context) {
-	var prdInfo entities.ProductInfo
 	fi := "api.Handler.deleteProduct"
 	ctx, cancel := context.WithCancel(c)
 	defer cancel()
@@ -152,9 +248,9 @@ func (h *Handler) deleteProduct(c *gin.Context) {
 		return
 	}
 
-	//404 и 500
-	prdInfo.ProductId = prdId
-	if err := h.service.DeleteProduct(ctx, prdId); errors.Is(err, repository.ErrNotFound) {
+	//404 и 500, в событие отправляется снимок продукта из транзакции удаления
+	product, err := h.service.DeleteProduct(ctx, prdId)
+	if errors.Is(err, repository.ErrNotFound) {
 		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
 		newErrorResponse(c, http.StatusNotFound, err.Error())
 		return
@@ -166,7 +262,7 @@ func (h *Handler) deleteProduct(c *gin.Context) {
 
 	//подключчеить кафку - сообщение откатить
 	action := "delete"
-	if errk := h.kafka.SendMessage(prdInfo, action); errk != nil {
+	if errk := h.kafka.SendMessage(ctx, *product, action); errk != nil {
 		logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, errk.Error())
 		return
@@ -176,6 +272,200 @@ func (h *Handler) deleteProduct(c *gin.Context) {
 	c.AbortWithStatusJSON(http.StatusOK, "OK")
 }
 
+func (h *Handler) getProduct(c *gin.Context) {
+	fi := "api.Handler.getProduct"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdIdStr := c.Param("productId")
+	if prdIdStr == "" {
+		logMassage(fi, h.log, "productId parametr does not exist in path", http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, "productId parametr does not exist in path")
+		return
+	}
+	//400
+	prdId, err := strconv.Atoi(prdIdStr)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := entities.ValidateProductId(prdId); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//400
+	asOf, err := queryTime(c, "asOf")
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404 и 500, с asOf продукт берется из истории версий
+	var product *entities.ProductInfo
+	if asOf.IsZero() {
+		product, err = h.service.GetProductById(ctx, prdId)
+	} else {
+		product, err = h.service.GetProductAsOf(ctx, prdId, asOf)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, product)
+}
+
+func (h *Handler) getProducts(c *gin.Context) {
+	fi := "api.Handler.getProducts"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	filter := entities.ProductFilter{
+		Status:  c.Query("status"),
+		Keyword: c.Query("keyword"),
+	}
+
+	//400
+	var err error
+	if filter.CategoryId, err = queryInt(c, "categoryId"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if filter.Cursor, err = queryInt(c, "cursor"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if filter.Limit, err = queryInt(c, "limit"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if filter.MerchantId, err = queryInt(c, "merchantId"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := filter.ValidateProductFilter(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//500
+	list, err := h.service.GetProducts(ctx, &filter)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, list)
+}
+
+func (h *Handler) searchProducts(c *gin.Context) {
+	fi := "api.Handler.searchProducts"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	search := entities.ProductSearch{
+		Query:  c.Query("q"),
+		Lang:   c.Query("lang"),
+		Status: c.Query("status"),
+	}
+
+	//400
+	var err error
+	if search.CategoryId, err = queryInt(c, "categoryId"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if search.Offset, err = queryInt(c, "offset"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if search.Limit, err = queryInt(c, "limit"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if search.MerchantId, err = queryInt(c, "merchantId"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := search.ValidateProductSearch(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//500
+	result, err := h.service.SearchProducts(ctx, &search)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, result)
+}
+
+// необязательный целочисленный query-параметр, 0 если параметр не передан
+func queryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s parametr incorrect: %w", name, err)
+	}
+
+	return result, nil
+}
+
+// параметр запроса со временем в формате RFC3339, нулевое время если параметра нет
+func queryTime(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s parametr incorrect: %w", name, err)
+	}
+
+	return result, nil
+}
+
 func logMassage(fi string, log *slog.Logger, msg string, code int) {
 	log.Error("Transport Level Error: " + fi + ": " + msg + "   Code : " + strconv.Itoa(code))
 }
@@ -5,11 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
@@ -23,32 +26,278 @@ type MockService struct{}
 func (m *MockService) CreateProduct(ctx context.Context, user *entities.ProductInfo) (int, error) {
 	if user.Description == "интернал" {
 		return 0, errors.New("Внутренняя ошибка сервера")
+	} else if user.CategoryId == 3 {
+		return 0, repository.ErrCategoryNotFound
+	} else if user.CategoryId == 4 {
+		return 0, fmt.Errorf("%w: attribute price is required", entities.ErrInvalidAttributes)
+	} else if user.MerchantId == 3 {
+		return 0, repository.ErrQuotaExceeded
 	}
 	return 1, nil
 }
 
-func (m *MockService) DeleteProduct(ctx context.Context, productId int) error {
+func (m *MockService) CreateProducts(ctx context.Context, products []entities.ProductInfo) ([]error, error) {
+	rowErrs := make([]error, len(products))
+	for i := range products {
+		if products[i].Description == "интернал" {
+			return nil, errors.New("Внутренняя ошибка сервера")
+		} else if products[i].CategoryId == 3 {
+			rowErrs[i] = repository.ErrCategoryNotFound
+			continue
+		}
+		products[i].ProductId = 10 + i
+	}
+	return rowErrs, nil
+}
+
+func (m *MockService) ExportProducts(ctx context.Context, send func(product *entities.ProductInfo) error) error {
+	for i := 1; i <= 2; i++ {
+		if err := send(&entities.ProductInfo{
+			ProductId:       i,
+			CategoryId:      1,
+			Category:        "кино",
+			CategoryPath:    []string{"кино"},
+			Description:     "корректненько",
+			Status:          "active",
+			ProductKeyWords: []string{"фильм", "актер"},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockService) DeleteProduct(ctx context.Context, productId int) (*entities.ProductInfo, error) {
 	if productId == 2 {
-		return errors.New("Внутренняя ошибка сервера")
+		return nil, errors.New("Внутренняя ошибка сервера")
 	} else if productId == 3 {
-		return repository.ErrNotFound
+		return nil, repository.ErrNotFound
 	}
-	return nil
+	return &entities.ProductInfo{ProductId: productId, Version: 2}, nil
 }
 func (m *MockService) UpdateProduct(ctx context.Context, userId int, user *entities.ProductInfo) error {
 	if user.Description == "интернал" {
 		return errors.New("Внутренняя ошибка сервера")
 	} else if user.Description == "не фаунд" {
 		return repository.ErrNotFound
+	} else if user.Status == entities.StatusDraft {
+		return entities.ErrInvalidStatusTransition
+	}
+
+	return nil
+}
+
+func (m *MockService) GetProductById(ctx context.Context, productId int) (*entities.ProductInfo, error) {
+	if productId == 2 {
+		return nil, errors.New("Внутренняя ошибка сервера")
+	} else if productId == 3 {
+		return nil, repository.ErrNotFound
+	}
+	return &entities.ProductInfo{
+		ProductId:       productId,
+		CategoryId:      1,
+		Category:        "кино",
+		CategoryPath:    []string{"кино"},
+		Description:     "корректненько",
+		Status:          "active",
+		ProductKeyWords: []string{"фильм"},
+	}, nil
+}
+
+func (m *MockService) GetProducts(ctx context.Context, filter *entities.ProductFilter) (*entities.ProductList, error) {
+	if filter.Keyword == "интернал" {
+		return nil, errors.New("Внутренняя ошибка сервера")
+	}
+	return &entities.ProductList{
+		Products:   []entities.ProductInfo{{ProductId: filter.Cursor + 1, CategoryId: filter.CategoryId}},
+		NextCursor: filter.Cursor + 1,
+	}, nil
+}
+
+func (m *MockService) SearchProducts(ctx context.Context, search *entities.ProductSearch) (*entities.ProductSearchResult, error) {
+	if search.Query == "интернал" {
+		return nil, errors.New("Внутренняя ошибка сервера")
+	}
+	return &entities.ProductSearchResult{
+		Products: []entities.ProductSearchHit{{
+			ProductInfo: entities.ProductInfo{ProductId: 1, CategoryId: search.CategoryId},
+			Snippet:     "<b>" + search.Query + "</b>",
+		}},
+	}, nil
+}
+
+func (m *MockService) ChangeProductStatus(ctx context.Context, productId int, status string) (*entities.ProductInfo, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	} else if status == entities.StatusDraft {
+		return nil, fmt.Errorf("%w: from %s to %s", entities.ErrInvalidStatusTransition, entities.StatusActive, status)
+	}
+	return &entities.ProductInfo{ProductId: productId, Status: status}, nil
+}
+
+func (m *MockService) GetProductHistory(ctx context.Context, productId int) ([]entities.ProductVersion, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	}
+	return []entities.ProductVersion{
+		{ProductId: productId, Version: 1, Action: entities.VersionActionCreate},
+		{ProductId: productId, Version: 2, Action: entities.VersionActionUpdate},
+	}, nil
+}
+
+func (m *MockService) GetProductAsOf(ctx context.Context, productId int, asOf time.Time) (*entities.ProductInfo, error) {
+	if productId == 3 || asOf.Year() < 2000 {
+		return nil, repository.ErrNotFound
+	}
+	return &entities.ProductInfo{ProductId: productId, Version: 1}, nil
+}
+
+func (m *MockService) RestoreProductVersion(ctx context.Context, productId int, version int) (*entities.ProductInfo, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	} else if version == 5 {
+		return nil, repository.ErrVersionNotFound
+	} else if version == 7 {
+		return nil, repository.ErrCategoryNotFound
+	}
+	return &entities.ProductInfo{ProductId: productId, Version: version + 1}, nil
+}
+
+func (m *MockService) CreateCategory(ctx context.Context, category *entities.Category) (int, error) {
+	if category.ParentId == 3 {
+		return 0, repository.ErrCategoryNotFound
+	} else if category.Name == "дубль" {
+		return 0, repository.ErrCategoryAlreadyExists
+	}
+	return 1, nil
+}
+
+func (m *MockService) GetCategoryById(ctx context.Context, categoryId int) (*entities.Category, error) {
+	if categoryId == 3 {
+		return nil, repository.ErrCategoryNotFound
+	}
+	return &entities.Category{CategoryId: categoryId, Name: "кино", Path: []string{"кино"}}, nil
+}
+
+func (m *MockService) GetCategories(ctx context.Context) ([]entities.Category, error) {
+	return []entities.Category{
+		{CategoryId: 1, Name: "кино", Path: []string{"кино"}},
+		{CategoryId: 2, Name: "комедия", ParentId: 1, Path: []string{"кино", "комедия"}},
+	}, nil
+}
+
+func (m *MockService) UpdateCategory(ctx context.Context, categoryId int, category *entities.Category) error {
+	if categoryId == 3 {
+		return repository.ErrCategoryNotFound
+	} else if category.ParentId == 4 {
+		return repository.ErrCategoryCycle
+	}
+	return nil
+}
+
+func (m *MockService) DeleteCategory(ctx context.Context, categoryId int) error {
+	if categoryId == 3 {
+		return repository.ErrCategoryNotFound
+	} else if categoryId == 4 {
+		return repository.ErrCategoryInUse
 	}
+	return nil
+}
+
+func (m *MockService) GetMerchant(ctx context.Context, merchantId int) (*entities.Merchant, error) {
+	if merchantId == 4 {
+		return nil, errors.New("Внутренняя ошибка сервера")
+	}
+	return &entities.Merchant{MerchantId: merchantId, ProductCount: 2, MaxProducts: entities.DefaultMerchantQuota}, nil
+}
+
+func (m *MockService) SetMerchantQuota(ctx context.Context, merchantId int, maxProducts int) error {
+	if merchantId == 4 {
+		return errors.New("Внутренняя ошибка сервера")
+	}
+	return nil
+}
 
+// мок отдает две пачки по два продукта после FromId, FromId 3 - ошибка
+func (m *MockService) RepublishProducts(ctx context.Context, republish *entities.Republish, publish func(products []entities.ProductInfo) error) error {
+	if republish.FromId == 3 {
+		return errors.New("Внутренняя ошибка сервера")
+	}
+	for cursor := republish.FromId; cursor < republish.FromId+4; cursor += 2 {
+		if err := publish([]entities.ProductInfo{{ProductId: cursor + 1}, {ProductId: cursor + 2}}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (m *MockService) GetProductKeyWords(ctx context.Context, productId int) ([]string, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	}
+	return []string{"Сашими", "Футбол"}, nil
+}
+
+func (m *MockService) AddProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	} else if keyWord == "Футбол" {
+		return nil, repository.ErrKeyWordAlreadyExists
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{"Футбол", keyWord}, Version: 2}, nil
+}
+
+func (m *MockService) DeleteProductKeyWord(ctx context.Context, productId int, keyWord string) (*entities.ProductInfo, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	} else if keyWord == "фильм" {
+		return nil, repository.ErrLastKeyWord
+	} else if keyWord != "Футбол" {
+		return nil, repository.ErrKeyWordNotFound
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{"Сашими"}, Version: 2}, nil
+}
+
+func (m *MockService) ConfirmSuggestedKeyWords(ctx context.Context, productId int, keyWords []string) (*entities.ProductInfo, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	} else if slices.Contains(keyWords, "Кино") {
+		return nil, repository.ErrKeyWordNotSuggested
+	}
+	return &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{"Футбол", "фильм"}, Version: 2}, nil
+}
+
+func (m *MockService) GetKeyWords(ctx context.Context) ([]entities.KeyWordUsage, error) {
+	return []entities.KeyWordUsage{{KeyWord: "Футбол", Products: 2}, {KeyWord: "Сашими", Products: 1}}, nil
+}
+
+func (m *MockService) GetProductDuplicates(ctx context.Context, productId int) ([]entities.ProductDuplicate, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	}
+	return []entities.ProductDuplicate{{ProductId: productId + 1, Similarity: 0.9}}, nil
+}
+
+// мок: дубликат 4 без новых ключевых слов, дубликат 5 - ошибка отправки события удаления
+func (m *MockService) MergeProducts(ctx context.Context, productId int, duplicateId int) (*entities.ProductMergeResult, error) {
+	if productId == 3 {
+		return nil, repository.ErrNotFound
+	}
+	return &entities.ProductMergeResult{
+		Product:   &entities.ProductInfo{ProductId: productId, ProductKeyWords: []string{"Сашими", "Футбол"}, Version: 2},
+		Duplicate: &entities.ProductInfo{ProductId: duplicateId, ProductKeyWords: []string{"Футбол"}},
+		Updated:   duplicateId != 4,
+	}, nil
+}
+
+func (m *MockService) DetectDuplicates(ctx context.Context, fromId int, progress func(detection *entities.DuplicateDetection) error) error {
+	return progress(&entities.DuplicateDetection{Checked: 2, LastId: fromId + 2})
+}
+
 // Мок для кафки
 type MockKafka struct{}
 
-func (m *MockKafka) SendMessage(prdInfo entities.ProductInfo, action string) error {
+func (m *MockKafka) SendMessage(ctx context.Context, prdInfo entities.ProductInfo, action string) error {
 	if prdInfo.ProductId == 5 {
 		return errors.New("Внутренняя ошибка сервера")
 	} else if prdInfo.Description == "kafka" {
@@ -57,6 +306,10 @@ func (m *MockKafka) SendMessage(prdInfo entities.ProductInfo, action string) err
 	return nil
 }
 
+func (m *MockKafka) SendMessages(ctx context.Context, prdInfos []entities.ProductInfo, action string) error {
+	return nil
+}
+
 func (m *MockKafka) Close() error {
 	return nil
 }
@@ -72,7 +325,7 @@ func TestHandler_AddNewProduct_Correct(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -80,15 +333,16 @@ func TestHandler_AddNewProduct_Correct(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "корректненько",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
 	// формируем тестовый запрос
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
 
 	jsonData, err := json.Marshal(product)
 	if err != nil {
@@ -126,14 +380,14 @@ func TestHandler_AddNewProduct_InorrectMissingPole(t *testing.T) {
 		&MockKafka{},
 	)
 	type ProductInfo struct {
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
 	}
 
 	product := ProductInfo{
-		Category:        "кино",
-		Status:          "avaible",
+		CategoryId:      1,
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -176,16 +430,16 @@ func TestHandler_AddNewProduct_InorrectValidation(t *testing.T) {
 		&MockKafka{},
 	)
 	type ProductInfo struct {
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
 	}
 
 	product := ProductInfo{
-		Category:        " ",
+		CategoryId:      -1,
 		Description:     "корректненько",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -210,7 +464,7 @@ func TestHandler_AddNewProduct_InorrectValidation(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
 	var response map[string]string
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Equal(t, "invalid category:   does not match regexp", response["reason"])
+	assert.Equal(t, "invalid category id: can`t be less or equal 0", response["reason"])
 
 	defer func() {
 		if err := c.Request.Body.Close(); err != nil {
@@ -230,7 +484,7 @@ func TestHandler_AddNewProduct_CorrectButInternalErr(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -238,15 +492,16 @@ func TestHandler_AddNewProduct_CorrectButInternalErr(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "интернал",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
 	// формируем тестовый запрос
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
 
 	jsonData, err := json.Marshal(product)
 	if err != nil {
@@ -286,7 +541,7 @@ func TestHandler_UpdateProduct_Correct(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -294,9 +549,9 @@ func TestHandler_UpdateProduct_Correct(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "корректненько",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -345,7 +600,7 @@ func TestHandler_UpdateProduct_CorrectButInternalErr(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -353,9 +608,9 @@ func TestHandler_UpdateProduct_CorrectButInternalErr(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "интернал",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -403,7 +658,7 @@ func TestHandler_UpdateProduct_CorrectButNotFound(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -411,9 +666,9 @@ func TestHandler_UpdateProduct_CorrectButNotFound(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "не фаунд",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -461,7 +716,7 @@ func TestHandler_UpdateProduct_IncorrectMissingProductId(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -469,9 +724,9 @@ func TestHandler_UpdateProduct_IncorrectMissingProductId(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "не фаунд",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -515,7 +770,7 @@ func TestHandler_UpdateProduct_IncorrectIncorrectProductId(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -523,9 +778,9 @@ func TestHandler_UpdateProduct_IncorrectIncorrectProductId(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "не фаунд",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -574,7 +829,7 @@ func TestHandler_UpdateProduct_IncorrectIncorrectValidationProductId(t *testing.
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -582,9 +837,9 @@ func TestHandler_UpdateProduct_IncorrectIncorrectValidationProductId(t *testing.
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "не фаунд",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -632,13 +887,13 @@ func TestHandler_UpdateProduct_IncorrectMissingField(t *testing.T) {
 		&MockKafka{},
 	)
 	type ProductInfo struct {
-		Category string `json:"category"`
-		Status   string `json:"status"`
+		CategoryId int    `json:"categoryId"`
+		Status     string `json:"status"`
 	}
 
 	product := ProductInfo{
-		Category: "кино",
-		Status:   "avaible",
+		CategoryId: 1,
+		Status:     "active",
 	}
 
 	// формируем тестовый запрос
@@ -827,7 +1082,7 @@ func TestHandler_UpdateProduct_IncorrectKafkaError(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -835,9 +1090,9 @@ func TestHandler_UpdateProduct_IncorrectKafkaError(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "корректненько",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
@@ -882,7 +1137,7 @@ func TestHandler_AddNewUser_IncorrectKafkaError(t *testing.T) {
 	)
 	type ProductInfo struct {
 		ProductId       int      `json:"userId"`
-		Category        string   `json:"category"`
+		CategoryId      int      `json:"categoryId"`
 		Description     string   `json:"description"`
 		Status          string   `json:"status"`
 		ProductKeyWords []string `json:"productKeyWords"`
@@ -890,15 +1145,16 @@ func TestHandler_AddNewUser_IncorrectKafkaError(t *testing.T) {
 
 	product := ProductInfo{
 		ProductId:       1,
-		Category:        "кино",
+		CategoryId:      1,
 		Description:     "kafka",
-		Status:          "avaible",
+		Status:          "active",
 		ProductKeyWords: []string{"фильм"},
 	}
 
 	// формируем тестовый запрос
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
 
 	jsonData, err := json.Marshal(product)
 	if err != nil {
@@ -923,3 +1179,426 @@ func TestHandler_AddNewUser_IncorrectKafkaError(t *testing.T) {
 		}
 	}()
 }
+
+func TestHandler_GetProduct_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProduct(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.ProductInfo
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, response.ProductId)
+	assert.Equal(t, []string{"фильм"}, response.ProductKeyWords)
+}
+
+func TestHandler_GetProduct_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "3"},
+	}
+
+	handler.getProduct(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_GetProduct_IncorrectValidation(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "-1"},
+	}
+
+	handler.getProduct(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_GetProducts_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product?categoryId=2&cursor=4&limit=1", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.getProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.ProductList
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 5, response.NextCursor)
+	assert.Equal(t, 2, response.Products[0].CategoryId)
+}
+
+func TestHandler_GetProducts_IncorrectLimit(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product?limit=1000", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.getProducts(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_SearchProducts_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/search?q=гитара&categoryId=3", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.searchProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.ProductSearchResult
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "<b>гитара</b>", response.Products[0].Snippet)
+	assert.Equal(t, 3, response.Products[0].CategoryId)
+}
+
+func TestHandler_SearchProducts_IncorrectEmptyQuery(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/search?q=%20", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.searchProducts(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid search query: can`t be empty", response["reason"])
+}
+
+func TestHandler_SearchProducts_CorrectButInternalErr(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/search?q=интернал", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.searchProducts(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestHandler_UpdateProduct_IncorrectStatusTransition(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	product := entities.ProductInfo{
+		CategoryId:      1,
+		Description:     "корректненько",
+		Status:          entities.StatusDraft,
+		ProductKeyWords: []string{"фильм"},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonData, err := json.Marshal(product)
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("PATCH", "/product/1", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.updateProduct(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_UpdateProduct_IncorrectUnknownStatus(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	product := entities.ProductInfo{
+		CategoryId:      1,
+		Description:     "корректненько",
+		Status:          "sold",
+		ProductKeyWords: []string{"фильм"},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonData, err := json.Marshal(product)
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("PATCH", "/product/1", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.updateProduct(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_TransitionProduct_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/transition",
+		bytes.NewReader([]byte(`{"status":"out_of_stock"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.transitionProduct(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.ProductInfo
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, entities.StatusOutOfStock, response.Status)
+}
+
+func TestHandler_TransitionProduct_IncorrectTransition(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/transition",
+		bytes.NewReader([]byte(`{"status":"draft"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.transitionProduct(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid product status transition: from active to draft", response["reason"])
+}
+
+func TestHandler_TransitionProduct_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/3/transition",
+		bytes.NewReader([]byte(`{"status":"archived"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "3"},
+	}
+
+	handler.transitionProduct(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_AddNewProduct_CorrectButCategoryNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	product := entities.ProductInfo{
+		ProductId:       1,
+		CategoryId:      3,
+		Description:     "корректненько",
+		Status:          "active",
+		ProductKeyWords: []string{"фильм"},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	jsonData, err := json.Marshal(product)
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/product", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewProduct(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, repository.ErrCategoryNotFound.Error(), response["reason"])
+}
+
+func TestHandler_AddNewProduct_CorrectButInvalidAttributes(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	product := entities.ProductInfo{
+		ProductId:       1,
+		CategoryId:      4,
+		Description:     "корректненько",
+		Status:          "active",
+		ProductKeyWords: []string{"фильм"},
+		Attributes:      map[string]any{"brand": "Sony"},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: 1})
+
+	jsonData, err := json.Marshal(product)
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	c.Request, err = http.NewRequest("POST", "/product", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewProduct(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid product attributes: attribute price is required", response["reason"])
+}
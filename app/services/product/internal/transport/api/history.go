@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getProductHistory(c *gin.Context) {
+	fi := "api.Handler.getProductHistory"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404 и 500
+	history, err := h.service.GetProductHistory(ctx, prdId)
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, history)
+}
+
+// восстановление продукта из версии, для подписчиков - обычное обновление продукта
+func (h *Handler) restoreProduct(c *gin.Context) {
+	var restore entities.VersionRestore
+	fi := "api.Handler.restoreProduct"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&restore); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := entities.ValidateVersion(restore.Version); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404, 409 (версия не подходит под текущую категорию) и 500
+	prdInfo, err := h.service.RestoreProductVersion(ctx, prdId, restore.Version)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrVersionNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrCategoryNotFound) || errors.Is(err, entities.ErrInvalidAttributes) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	action := "update"
+	if errk := h.kafka.SendMessage(ctx, *prdInfo, action); errk != nil {
+		logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, errk.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, prdInfo)
+}
+
+// id продукта из пути запроса
+func productIdFromPath(c *gin.Context) (int, error) {
+	prdIdStr := c.Param("productId")
+	if prdIdStr == "" {
+		return 0, errors.New("productId parametr does not exist in path")
+	}
+
+	prdId, err := strconv.Atoi(prdIdStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := entities.ValidateProductId(prdId); err != nil {
+		return 0, err
+	}
+
+	return prdId, nil
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestHandler_GetProductHistory_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/1/history", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProductHistory(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []entities.ProductVersion
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 2, len(response))
+	assert.Equal(t, entities.VersionActionUpdate, response[1].Action)
+}
+
+func TestHandler_GetProductHistory_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/3/history", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "3"},
+	}
+
+	handler.getProductHistory(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_GetProduct_CorrectAsOf(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/1?asOf=2024-05-01T10:00:00Z", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProduct(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.ProductInfo
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, response.Version)
+}
+
+func TestHandler_GetProduct_CorrectAsOfBeforeCreation(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/1?asOf=1990-01-01T00:00:00Z", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProduct(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_GetProduct_IncorrectAsOf(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/1?asOf=вчера", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProduct(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_RestoreProduct_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/1/restore",
+		bytes.NewReader([]byte(`{"version":2}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.restoreProduct(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.ProductInfo
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 3, response.Version)
+}
+
+func TestHandler_RestoreProduct_CorrectButVersionNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/1/restore",
+		bytes.NewReader([]byte(`{"version":5}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.restoreProduct(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_RestoreProduct_CorrectButCategoryNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/1/restore",
+		bytes.NewReader([]byte(`{"version":7}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.restoreProduct(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_RestoreProduct_IncorrectAdminToken(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+	t.Setenv("ADMIN_TOKEN", "секрет")
+	router := handler.InitRoutes()
+
+	for token, code := range map[string]int{
+		"":       http.StatusForbidden,
+		"другой": http.StatusForbidden,
+		"секрет": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "/admin/product/1/restore",
+			bytes.NewReader([]byte(`{"version":2}`)))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		req.Header.Set(adminTokenHeader, token)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Result().StatusCode)
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getProductKeyWords(c *gin.Context) {
+	fi := "api.Handler.getProductKeyWords"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404 и 500
+	keyWords, err := h.service.GetProductKeyWords(ctx, prdId)
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, keyWords)
+}
+
+// добавление одного ключевого слова, подписчики получают обновление с новым набором ключевых слов
+func (h *Handler) addProductKeyWord(c *gin.Context) {
+	var keyWord entities.ProductKeyWord
+	fi := "api.Handler.addProductKeyWord"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&keyWord); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := entities.ValidateProductKeyWord(keyWord.KeyWord); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404, 409 и 500
+	prdInfo, err := h.service.AddProductKeyWord(ctx, prdId, keyWord.KeyWord)
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrKeyWordAlreadyExists) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendKeyWordsUpdate(ctx, c, fi, prdInfo)
+}
+
+// удаление одного ключевого слова (query параметр keyWord)
+func (h *Handler) deleteProductKeyWord(c *gin.Context) {
+	fi := "api.Handler.deleteProductKeyWord"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	keyWord := c.Query("keyWord")
+	if err := entities.ValidateProductKeyWord(keyWord); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404, 409 (последнее ключевое слово) и 500
+	prdInfo, err := h.service.DeleteProductKeyWord(ctx, prdId, keyWord)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrKeyWordNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if errors.Is(err, repository.ErrLastKeyWord) {
+		logMassage(fi, h.log, err.Error(), http.StatusConflict)
+		newErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendKeyWordsUpdate(ctx, c, fi, prdInfo)
+}
+
+// подтверждение предложенных по описанию ключевых слов, одно событие обновления на все подтвержденные
+func (h *Handler) confirmProductKeyWords(c *gin.Context) {
+	var confirm entities.ConfirmKeyWords
+	fi := "api.Handler.confirmProductKeyWords"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	prdId, err := productIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&confirm); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	for _, keyWord := range confirm.KeyWords {
+		if err := entities.ValidateProductKeyWord(keyWord); err != nil {
+			logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	//404 и 500
+	prdInfo, err := h.service.ConfirmSuggestedKeyWords(ctx, prdId, confirm.KeyWords)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrKeyWordNotSuggested) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendKeyWordsUpdate(ctx, c, fi, prdInfo)
+}
+
+// одно событие обновления продукта на каждое изменение ключевых слов
+func (h *Handler) sendKeyWordsUpdate(ctx context.Context, c *gin.Context, fi string, prdInfo *entities.ProductInfo) {
+	action := "update"
+	if errk := h.kafka.SendMessage(ctx, *prdInfo, action); errk != nil {
+		logMassage(fi, h.log, errk.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, errk.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, prdInfo.ProductKeyWords)
+}
+
+// все ключевые слова с числом продуктов, в которых они используются
+func (h *Handler) getKeyWords(c *gin.Context) {
+	fi := "api.Handler.getKeyWords"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//500
+	keyWords, err := h.service.GetKeyWords(ctx)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, keyWords)
+}
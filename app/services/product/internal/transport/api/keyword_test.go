@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_GetProductKeyWords_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/1/keywords", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.getProductKeyWords(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"Сашими", "Футбол"}, response)
+}
+
+func TestHandler_GetProductKeyWords_CorrectButNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/product/3/keywords", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "3"},
+	}
+
+	handler.getProductKeyWords(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_AddProductKeyWord_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/keywords",
+		bytes.NewReader([]byte(`{"keyWord":"Сашими"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.addProductKeyWord(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"Футбол", "Сашими"}, response)
+}
+
+func TestHandler_AddProductKeyWord_CorrectButAlreadyExists(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/keywords",
+		bytes.NewReader([]byte(`{"keyWord":"Футбол"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.addProductKeyWord(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_AddProductKeyWord_IncorrectKeyWord(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/keywords",
+		bytes.NewReader([]byte(`{"keyWord":"123"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.addProductKeyWord(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_AddProductKeyWord_CorrectButKafkaError(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/5/keywords",
+		bytes.NewReader([]byte(`{"keyWord":"Сашими"}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "5"},
+	}
+
+	handler.addProductKeyWord(c)
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestHandler_DeleteProductKeyWord_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("DELETE", "/product/1/keywords?keyWord=Футбол", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.deleteProductKeyWord(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"Сашими"}, response)
+}
+
+func TestHandler_DeleteProductKeyWord_CorrectButLastKeyWord(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("DELETE", "/product/1/keywords?keyWord=фильм", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.deleteProductKeyWord(c)
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestHandler_DeleteProductKeyWord_CorrectButKeyWordNotFound(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("DELETE", "/product/1/keywords?keyWord=Кино", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.deleteProductKeyWord(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestHandler_DeleteProductKeyWord_IncorrectWithoutKeyWord(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("DELETE", "/product/1/keywords", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.deleteProductKeyWord(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_GetKeyWords_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("GET", "/keywords", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.getKeyWords(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []entities.KeyWordUsage
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []entities.KeyWordUsage{{KeyWord: "Футбол", Products: 2}, {KeyWord: "Сашими", Products: 1}}, response)
+}
+
+func TestHandler_GetKeyWords_Unauthorized(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+	router := handler.InitRoutes()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/keywords", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+}
+
+func TestHandler_ConfirmProductKeyWords_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/keywords/confirm", bytes.NewReader([]byte(`{}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.confirmProductKeyWords(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []string{"Футбол", "фильм"}, response)
+}
+
+func TestHandler_ConfirmProductKeyWords_CorrectButNotSuggested(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/product/1/keywords/confirm",
+		bytes.NewReader([]byte(`{"keyWords":["Кино"]}`)))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	c.Params = gin.Params{
+		gin.Param{Key: "productId", Value: "1"},
+	}
+
+	handler.confirmProductKeyWords(c)
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
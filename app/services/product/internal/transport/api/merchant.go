@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getMerchant(c *gin.Context) {
+	fi := "api.Handler.getMerchant"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	merchantId, err := merchantIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//403
+	if scope := entities.MerchantScope(ctx); scope != 0 && scope != merchantId {
+		logMassage(fi, h.log, "merchant can see only its own quota", http.StatusForbidden)
+		newErrorResponse(c, http.StatusForbidden, "merchant can see only its own quota")
+		return
+	}
+
+	//500
+	merchant, err := h.service.GetMerchant(ctx, merchantId)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, merchant)
+}
+
+func (h *Handler) setMerchantQuota(c *gin.Context) {
+	var quota entities.MerchantQuota
+	fi := "api.Handler.setMerchantQuota"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400
+	merchantId, err := merchantIdFromPath(c)
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := c.BindJSON(&quota); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := quota.ValidateMerchantQuota(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//500
+	if err := h.service.SetMerchantQuota(ctx, merchantId, quota.MaxProducts); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//200
+	c.AbortWithStatusJSON(http.StatusOK, map[string]interface{}{
+		"merchantId":  merchantId,
+		"maxProducts": quota.MaxProducts,
+	})
+}
+
+// владелец нового продукта: продукт мерчанта всегда принадлежит ему самому,
+// администратор указывает мерчанта в поле merchantId
+func assignMerchant(c *gin.Context, product *entities.ProductInfo) error {
+	if caller, ok := entities.CallerFrom(c); ok && !caller.Admin {
+		product.MerchantId = caller.MerchantId
+	}
+
+	return entities.ValidateMerchantId(product.MerchantId)
+}
+
+// id мерчанта из пути запроса
+func merchantIdFromPath(c *gin.Context) (int, error) {
+	merchantIdStr := c.Param("merchantId")
+	if merchantIdStr == "" {
+		return 0, errors.New("merchantId parametr does not exist in path")
+	}
+
+	merchantId, err := strconv.Atoi(merchantIdStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := entities.ValidateMerchantId(merchantId); err != nil {
+		return 0, err
+	}
+
+	return merchantId, nil
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Authenticate(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+	t.Setenv("ADMIN_TOKEN", "секрет")
+	t.Setenv("MERCHANT_TOKEN_SECRET", "секрет мерчантов")
+	router := handler.InitRoutes()
+
+	for _, tc := range []struct {
+		merchantId    string
+		merchantToken string
+		token         string
+		code          int
+	}{
+		{merchantId: "", token: "", code: http.StatusUnauthorized},
+		{merchantId: "abc", token: "", code: http.StatusUnauthorized},
+		{merchantId: "0", token: "", code: http.StatusUnauthorized},
+		{merchantId: "", token: "другой", code: http.StatusUnauthorized},
+		{merchantId: "1", token: "", code: http.StatusUnauthorized},
+		{merchantId: "1", merchantToken: merchantToken("секрет мерчантов", 2), code: http.StatusUnauthorized},
+		{merchantId: "1", merchantToken: merchantToken("другой", 1), code: http.StatusUnauthorized},
+		{merchantId: "1", merchantToken: merchantToken("секрет мерчантов", 1), code: http.StatusOK},
+		{merchantId: "", token: "секрет", code: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/product/1", nil)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		req.Header.Set(merchantIdHeader, tc.merchantId)
+		req.Header.Set(merchantTokenHeader, tc.merchantToken)
+		req.Header.Set(adminTokenHeader, tc.token)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Result().StatusCode)
+	}
+}
+
+func TestHandler_AddNewProduct_MerchantFromCaller(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	//мерчант не может добавить продукт другому мерчанту, поле merchantId заменяется
+	for merchantId, code := range map[int]int{
+		1: http.StatusOK,
+		3: http.StatusConflict,
+	} {
+		jsonData, err := json.Marshal(entities.ProductInfo{
+			ProductId:       1,
+			CategoryId:      1,
+			Description:     "корректненько",
+			Status:          "active",
+			ProductKeyWords: []string{"фильм"},
+			MerchantId:      2,
+		})
+		if err != nil {
+			t.Fatalf("Error marshalling json: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set(entities.CallerKey, entities.Caller{MerchantId: merchantId})
+
+		c.Request, err = http.NewRequest("POST", "/product", bytes.NewReader(jsonData))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+
+		handler.addNewProduct(c)
+		assert.Equal(t, code, w.Result().StatusCode)
+	}
+}
+
+func TestHandler_AddNewProduct_AdminWithoutMerchant(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	jsonData, err := json.Marshal(entities.ProductInfo{
+		ProductId:       1,
+		CategoryId:      1,
+		Description:     "корректненько",
+		Status:          "active",
+		ProductKeyWords: []string{"фильм"},
+	})
+	if err != nil {
+		t.Fatalf("Error marshalling json: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set(entities.CallerKey, entities.Caller{Admin: true})
+
+	c.Request, err = http.NewRequest("POST", "/product", bytes.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.addNewProduct(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandler_GetMerchant(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	for _, tc := range []struct {
+		caller     entities.Caller
+		merchantId string
+		code       int
+	}{
+		{caller: entities.Caller{MerchantId: 1}, merchantId: "1", code: http.StatusOK},
+		{caller: entities.Caller{MerchantId: 1}, merchantId: "2", code: http.StatusForbidden},
+		{caller: entities.Caller{Admin: true}, merchantId: "2", code: http.StatusOK},
+		{caller: entities.Caller{Admin: true}, merchantId: "4", code: http.StatusInternalServerError},
+		{caller: entities.Caller{Admin: true}, merchantId: "abc", code: http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set(entities.CallerKey, tc.caller)
+
+		var err error
+		c.Request, err = http.NewRequest("GET", "/merchant/"+tc.merchantId, nil)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		c.Params = gin.Params{
+			gin.Param{Key: "merchantId", Value: tc.merchantId},
+		}
+
+		handler.getMerchant(c)
+		assert.Equal(t, tc.code, w.Result().StatusCode)
+	}
+}
+
+func TestHandler_SetMerchantQuota(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+	t.Setenv("ADMIN_TOKEN", "секрет")
+	router := handler.InitRoutes()
+
+	for _, tc := range []struct {
+		merchantId string
+		body       string
+		token      string
+		code       int
+	}{
+		{merchantId: "1", body: `{"maxProducts":10}`, token: "секрет", code: http.StatusOK},
+		{merchantId: "1", body: `{"maxProducts":-1}`, token: "секрет", code: http.StatusBadRequest},
+		{merchantId: "4", body: `{"maxProducts":10}`, token: "секрет", code: http.StatusInternalServerError},
+		{merchantId: "1", body: `{"maxProducts":10}`, token: "", code: http.StatusForbidden},
+	} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("PUT", "/admin/merchant/"+tc.merchantId+"/quota",
+			bytes.NewReader([]byte(tc.body)))
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		req.Header.Set(adminTokenHeader, tc.token)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Result().StatusCode)
+	}
+}
+
+func TestHandler_CategoryRoutes_Access(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+	t.Setenv("ADMIN_TOKEN", "секрет")
+	t.Setenv("MERCHANT_TOKEN_SECRET", "секрет мерчантов")
+	router := handler.InitRoutes()
+
+	for _, tc := range []struct {
+		method   string
+		url      string
+		merchant bool
+		token    string
+		code     int
+	}{
+		// категории читает мерчант или администратор
+		{method: "GET", url: "/category", code: http.StatusUnauthorized},
+		{method: "GET", url: "/category/1", code: http.StatusUnauthorized},
+		{method: "GET", url: "/category", merchant: true, code: http.StatusOK},
+		{method: "GET", url: "/category", token: "секрет", code: http.StatusOK},
+		// меняет только администратор
+		{method: "POST", url: "/category", code: http.StatusForbidden},
+		{method: "POST", url: "/category", merchant: true, code: http.StatusForbidden},
+		{method: "PATCH", url: "/category/1", merchant: true, code: http.StatusForbidden},
+		{method: "DELETE", url: "/category/1", merchant: true, code: http.StatusForbidden},
+		{method: "DELETE", url: "/category/1", token: "секрет", code: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(tc.method, tc.url, nil)
+		if err != nil {
+			t.Fatalf("Error creating request: %v", err)
+		}
+		if tc.merchant {
+			req.Header.Set(merchantIdHeader, "1")
+			req.Header.Set(merchantTokenHeader, merchantToken("секрет мерчантов", 1))
+		}
+		req.Header.Set(adminTokenHeader, tc.token)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.code, w.Result().StatusCode, tc.method+" "+tc.url)
+	}
+}
@@ -1,9 +1,17 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/transport/kafka/envelope"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,3 +23,93 @@ func newErrorResponse(c *gin.Context, statusCode int, message string) {
 		Reason: message,
 	})
 }
+
+// trace id запроса берется из заголовка X-Trace-Id или генерируется,
+// передается в заголовках событий кафки и возвращается клиенту
+func traceId(c *gin.Context) {
+	id := c.GetHeader(envelope.TraceIdHeader)
+	if id == "" {
+		id = envelope.NewId()
+	}
+
+	c.Set(envelope.TraceIdKey, id)
+	c.Header(envelope.TraceIdHeader, id)
+	c.Next()
+}
+
+// заголовок с токеном администратора, токен задается переменной окружения ADMIN_TOKEN
+const adminTokenHeader = "X-Admin-Token"
+
+// заголовок с id мерчанта, от имени которого выполняется запрос
+const merchantIdHeader = "X-Merchant-Id"
+
+// заголовок с токеном мерчанта: hex HMAC-SHA256 от id мерчанта на секрете из переменной
+// окружения MERCHANT_TOKEN_SECRET, токен выдается мерчанту (или выставляется шлюзом) вместе с id
+const merchantTokenHeader = "X-Merchant-Token"
+
+// доступ только для администратора, если ADMIN_TOKEN не задан - админские запросы запрещены
+func (h *Handler) adminOnly(c *gin.Context) {
+	fi := "api.Handler.adminOnly"
+
+	//403
+	if !isAdmin(c) {
+		logMassage(fi, h.log, "admin token is missing or incorrect", http.StatusForbidden)
+		newErrorResponse(c, http.StatusForbidden, "admin token is missing or incorrect")
+		return
+	}
+
+	c.Next()
+}
+
+// вызывающая сторона запроса: администратор (X-Admin-Token) или мерчант (X-Merchant-Id
+// и X-Merchant-Token), мерчанту доступны только его продукты
+func (h *Handler) authenticate(c *gin.Context) {
+	fi := "api.Handler.authenticate"
+
+	if isAdmin(c) {
+		c.Set(entities.CallerKey, entities.Caller{Admin: true})
+		c.Next()
+		return
+	}
+
+	//401
+	merchantId, err := strconv.Atoi(c.GetHeader(merchantIdHeader))
+	if err != nil || entities.ValidateMerchantId(merchantId) != nil {
+		logMassage(fi, h.log, "merchant id is missing or incorrect", http.StatusUnauthorized)
+		newErrorResponse(c, http.StatusUnauthorized, "merchant id is missing or incorrect")
+		return
+	}
+	//401
+	if !isMerchant(c, merchantId) {
+		logMassage(fi, h.log, "merchant token is missing or incorrect", http.StatusUnauthorized)
+		newErrorResponse(c, http.StatusUnauthorized, "merchant token is missing or incorrect")
+		return
+	}
+
+	c.Set(entities.CallerKey, entities.Caller{MerchantId: merchantId})
+	c.Next()
+}
+
+// запрос выполняется администратором - токен задан и совпадает с ADMIN_TOKEN
+func isAdmin(c *gin.Context) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	given := c.GetHeader(adminTokenHeader)
+
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(given)) == 1
+}
+
+// запрос выполняется мерчантом merchantId - токен совпадает с токеном мерчанта,
+// если MERCHANT_TOKEN_SECRET не задан - запросы мерчантов запрещены
+func isMerchant(c *gin.Context, merchantId int) bool {
+	secret := os.Getenv("MERCHANT_TOKEN_SECRET")
+	given := c.GetHeader(merchantTokenHeader)
+
+	return secret != "" && hmac.Equal([]byte(merchantToken(secret, merchantId)), []byte(given))
+}
+
+// токен мерчанта - hex HMAC-SHA256 от его id
+func merchantToken(secret string, merchantId int) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.Itoa(merchantId)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+)
+
+// повторная публикация снимков всех продуктов, в ответ построчно (ndjson) возвращается
+// прогресс после каждой пачки, при обрыве публикацию можно продолжить с fromId = lastId
+func (h *Handler) republishProducts(c *gin.Context) {
+	fi := "api.Handler.republishProducts"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	var (
+		republish entities.Republish
+		err       error
+	)
+
+	//400
+	if republish.FromId, err = queryInt(c, "fromId"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if republish.Rate, err = queryInt(c, "rate"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400
+	if err := republish.ValidateRepublish(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//200, дальше ошибка возвращается в последней строке прогресса
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	progress := entities.RepublishProgress{LastId: republish.FromId}
+	err = h.service.RepublishProducts(ctx, &republish, func(products []entities.ProductInfo) error {
+		if err := h.kafka.SendMessages(ctx, products, "snapshot"); err != nil {
+			return err
+		}
+
+		progress.Published += len(products)
+		progress.LastId = products[len(products)-1].ProductId
+		encoder.Encode(progress)
+		c.Writer.Flush()
+		return nil
+	})
+
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		progress.Error = err.Error()
+	} else {
+		progress.Done = true
+	}
+	encoder.Encode(progress)
+	c.Writer.Flush()
+}
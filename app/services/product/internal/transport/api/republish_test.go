@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeRepublishProgress(t *testing.T, body *bytes.Buffer) []entities.RepublishProgress {
+	progress := make([]entities.RepublishProgress, 0)
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var p entities.RepublishProgress
+		if err := decoder.Decode(&p); err != nil {
+			t.Fatalf("Error decoding progress: %v", err)
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
+
+func TestHandler_RepublishProducts_Correct(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/republish?fromId=10&rate=100", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.republishProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	progress := decodeRepublishProgress(t, w.Body)
+	assert.Equal(t, []entities.RepublishProgress{
+		{Published: 2, LastId: 12},
+		{Published: 4, LastId: 14},
+		{Published: 4, LastId: 14, Done: true},
+	}, progress)
+}
+
+func TestHandler_RepublishProducts_CorrectButInternalError(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/republish?fromId=3", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.republishProducts(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	// последняя строка содержит ошибку и id, с которого можно продолжить
+	progress := decodeRepublishProgress(t, w.Body)
+	assert.Equal(t, 1, len(progress))
+	assert.Equal(t, 3, progress[0].LastId)
+	assert.Equal(t, false, progress[0].Done)
+	assert.NotEmpty(t, progress[0].Error)
+}
+
+func TestHandler_RepublishProducts_IncorrectRate(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		&MockKafka{},
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/product/republish?rate=-1", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.republishProducts(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
@@ -4,17 +4,65 @@ import "github.com/gin-gonic/gin"
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	//контекст обработчика отменяется вместе с запросом клиента, это прерывает и запросы к базе
+	router.ContextWithFallback = true
+	router.Use(traceId)
 
-	//product
-	product := router.Group("/product")
+	//product, запросы с заголовками X-Merchant-Id и X-Merchant-Token или X-Admin-Token
+	product := router.Group("/product", h.authenticate)
 	{
 		product.POST("", h.addNewProduct)
+		product.GET("", h.getProducts)
+		product.GET("/search", h.searchProducts)
+		product.POST("/bulk", h.importProducts)
+		product.GET("/export", h.exportProducts)
 
 		//product/{productId}
 		productId := product.Group("/:productId")
 		{
+			productId.GET("", h.getProduct)
 			productId.PATCH("", h.updateProduct)
 			productId.DELETE("", h.deleteProduct)
+			productId.POST("/transition", h.transitionProduct)
+			productId.GET("/history", h.getProductHistory)
+			productId.GET("/keywords", h.getProductKeyWords)
+			productId.POST("/keywords", h.addProductKeyWord)
+			productId.DELETE("/keywords", h.deleteProductKeyWord)
+			productId.POST("/keywords/confirm", h.confirmProductKeyWords)
+			productId.GET("/duplicates", h.getProductDuplicates)
+		}
+	}
+
+	//admin, запросы с заголовком X-Admin-Token
+	admin := router.Group("/admin", h.adminOnly)
+	{
+		admin.POST("/product/republish", h.republishProducts)
+		admin.POST("/product/:productId/restore", h.restoreProduct)
+		admin.POST("/product/:productId/merge", h.mergeProducts)
+		admin.PUT("/merchant/:merchantId/quota", h.setMerchantQuota)
+	}
+
+	//merchant, мерчант видит только свою квоту
+	merchant := router.Group("/merchant", h.authenticate)
+	{
+		merchant.GET("/:merchantId", h.getMerchant)
+	}
+
+	//keywords, статистика использования ключевых слов, доступ как к ключевым словам продуктов
+	router.GET("/keywords", h.authenticate, h.getKeyWords)
+
+	//category, дерево категорий и схемы атрибутов читают мерчанты, меняет только администратор
+	category := router.Group("/category")
+	{
+		category.POST("", h.adminOnly, h.addNewCategory)
+		category.GET("", h.authenticate, h.getCategories)
+
+		//category/{categoryId}
+		categoryId := category.Group("/:categoryId")
+		{
+			categoryId.GET("", h.authenticate, h.getCategory)
+			categoryId.PATCH("", h.adminOnly, h.updateCategory)
+			categoryId.DELETE("", h.adminOnly, h.deleteCategory)
 		}
 	}
 
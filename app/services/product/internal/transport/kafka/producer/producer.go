@@ -1,24 +1,33 @@
 package kafka
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/transport/kafka/pb"
 	"github.com/IBM/sarama"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type KafkaProducer interface {
-	SendMessage(prdInfo entities.ProductInfo, action string) error
+	SendMessage(ctx context.Context, prdInfo entities.ProductInfo, action string) error
+	SendMessages(ctx context.Context, prdInfos []entities.ProductInfo, action string) error
 	Close() error
 }
 
+// имя сервиса в заголовке producer
+const producerName = "product"
+
 type Producer struct {
 	Producer sarama.SyncProducer
 	log      *slog.Logger
@@ -45,39 +54,22 @@ func InitConfig() *sarama.Config {
 	return config
 }
 
-func (p *Producer) SendMessage(prdInfo entities.ProductInfo, action string) error {
+func (p *Producer) SendMessage(ctx context.Context, prdInfo entities.ProductInfo, action string) error {
 	fi := "transport.kafka.Producer.SendMessage"
 
-	topic := os.Getenv("KAFKA_TOPIC")
-
-	if topic == "" {
-		p.log.Error("%s: Error Getting topic: %v", fi, errors.New("env KAFKA_TOPIC not set"))
-		return fmt.Errorf("environment KAFKA_TOPIC not set")
-	}
-
-	productKeyWords := make([]string, len(prdInfo.ProductKeyWords))
-
-	for _, elem := range prdInfo.ProductKeyWords {
-		productKeyWords = append(productKeyWords, fmt.Sprintf("%v", elem))
-	}
-
-	userMassage := myproto.ProductAction{
-		ProductId:       int64(prdInfo.ProductId),
-		ProductKeyWords: productKeyWords,
-		Action:          action,
+	topic, err := productTopic()
+	if err != nil {
+		p.log.Error("%s: Error Getting topic: %v", fi, err)
+		return err
 	}
 
-	data, err := proto.Marshal(&userMassage)
+	message, err := newProductMessage(ctx, topic, prdInfo, action)
 	if err != nil {
 		p.log.Error("%s: Error Marshal struct userMassage to protobuf: %v", fi, err)
 		return err
 	}
 
-	partition, offset, err := p.Producer.SendMessage(&sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.ByteEncoder(data),
-	})
-
+	partition, offset, err := p.Producer.SendMessage(message)
 	if err != nil {
 		p.log.Error("%s: Error sending userMassage to kafka: %v", fi, err)
 		return err
@@ -90,6 +82,112 @@ func (p *Producer) SendMessage(prdInfo entities.ProductInfo, action string) erro
 	return nil
 }
 
+// отправка событий по нескольким продуктам одной пачкой
+func (p *Producer) SendMessages(ctx context.Context, prdInfos []entities.ProductInfo, action string) error {
+	fi := "transport.kafka.Producer.SendMessages"
+
+	if len(prdInfos) == 0 {
+		return nil
+	}
+
+	topic, err := productTopic()
+	if err != nil {
+		p.log.Error("%s: Error Getting topic: %v", fi, err)
+		return err
+	}
+
+	messages := make([]*sarama.ProducerMessage, 0, len(prdInfos))
+	for _, prdInfo := range prdInfos {
+		message, err := newProductMessage(ctx, topic, prdInfo, action)
+		if err != nil {
+			p.log.Error("%s: Error Marshal struct userMassage to protobuf: %v", fi, err)
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := p.Producer.SendMessages(messages); err != nil {
+		p.log.Error("%s: Error sending messages to kafka: %v", fi, err)
+		return err
+	}
+	p.log.Info(fmt.Sprintf("%d messages are sent to topic %s", len(messages), topic))
+
+	return nil
+}
+
+func productTopic() (string, error) {
+	topic := os.Getenv("KAFKA_TOPIC")
+	if topic == "" {
+		return "", errors.New("environment KAFKA_TOPIC not set")
+	}
+	return topic, nil
+}
+
+// типы событий по строковым действиям обработчиков, строка также
+// передается в устаревшем поле action для старых консьюмеров
+var actionTypes = map[string]myproto.Action{
+	"add":      myproto.Action_CREATED,
+	"update":   myproto.Action_UPDATED,
+	"delete":   myproto.Action_DELETED,
+	"snapshot": myproto.Action_SNAPSHOT,
+}
+
+// типы событий в заголовке event-type
+var eventTypes = map[myproto.Action]string{
+	myproto.Action_CREATED:  envelope.EventProductCreated,
+	myproto.Action_UPDATED:  envelope.EventProductUpdated,
+	myproto.Action_DELETED:  envelope.EventProductDeleted,
+	myproto.Action_SNAPSHOT: envelope.EventProductSnapshot,
+}
+
+// сообщение о продукте в общем конверте, ключ сообщения - id продукта
+func newProductMessage(ctx context.Context, topic string, prdInfo entities.ProductInfo, action string) (*sarama.ProducerMessage, error) {
+	actionType, ok := actionTypes[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown product action %s", action)
+	}
+
+	env := envelope.New(ctx,
+		strconv.Itoa(prdInfo.ProductId), eventTypes[actionType], envelope.ProductSchemaVersion, producerName,
+	)
+
+	//полный снимок ключевых слов продукта
+	productKeyWords := make([]string, len(prdInfo.ProductKeyWords))
+	copy(productKeyWords, prdInfo.ProductKeyWords)
+
+	//атрибуты продукта, пустые атрибуты не передаются
+	var attributes *structpb.Struct
+	if len(prdInfo.Attributes) != 0 {
+		var err error
+		if attributes, err = structpb.NewStruct(prdInfo.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	userMassage := myproto.ProductAction{
+		ProductId:       int64(prdInfo.ProductId),
+		ProductKeyWords: productKeyWords,
+		Action:          action,
+		ActionType:      actionType,
+		Status:          prdInfo.Status,
+		CategoryId:      int64(prdInfo.CategoryId),
+		Category:        prdInfo.Category,
+		CategoryPath:    prdInfo.CategoryPath,
+		Description:     prdInfo.Description,
+		EventTime:       timestamppb.New(env.OccurredAt),
+		Version:         int64(prdInfo.Version),
+		Attributes:      attributes,
+		MerchantId:      int64(prdInfo.MerchantId),
+	}
+
+	data, err := proto.Marshal(&userMassage)
+	if err != nil {
+		return nil, err
+	}
+
+	return env.Message(topic, data), nil
+}
+
 func (p *Producer) Close() error {
 	fi := "transport.kafka.Producer.Close"
 
@@ -1,12 +1,16 @@
 package kafka
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"testing"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/transport/kafka/envelope"
+	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/product/internal/transport/kafka/pb"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestKafka_ConnectToKafka_Correct(t *testing.T) {
@@ -68,7 +72,7 @@ func TestKafka_TestSendMessage_Correct(t *testing.T) {
 		}
 	}()
 
-	err := Producer.SendMessage(product, "delete")
+	err := Producer.SendMessage(context.Background(), product, "delete")
 	assert.NoError(t, err)
 }
 
@@ -94,6 +98,87 @@ func TestKafka_SendMessage_Incorrect(t *testing.T) {
 		}
 	}()
 
-	err := Producer.SendMessage(product, "update")
+	err := Producer.SendMessage(context.Background(), product, "update")
 	assert.Error(t, err)
 }
+
+func TestKafka_NewProductMessage_Correct(t *testing.T) {
+	product := entities.ProductInfo{
+		ProductId:       1,
+		CategoryId:      2,
+		Category:        "комедия",
+		CategoryPath:    []string{"кино", "комедия"},
+		Description:     "корректно",
+		Status:          entities.StatusActive,
+		ProductKeyWords: []string{"режиссер", "актёр"},
+		Attributes:      map[string]any{"brand": "Sony", "price": 1500.5, "used": false},
+		Version:         3,
+		MerchantId:      7,
+	}
+
+	message, err := newProductMessage(context.Background(), "product_updates", product, "update")
+	assert.NoError(t, err)
+
+	data, err := message.Value.Encode()
+	assert.NoError(t, err)
+
+	var event myproto.ProductAction
+	assert.NoError(t, proto.Unmarshal(data, &event))
+
+	// ключевые слова передаются как есть, без пустых строк в начале
+	assert.Equal(t, product.ProductKeyWords, event.ProductKeyWords)
+	assert.Equal(t, myproto.Action_UPDATED, event.ActionType)
+	assert.Equal(t, "update", event.Action)
+	assert.Equal(t, "комедия", event.Category)
+	assert.Equal(t, "корректно", event.Description)
+	assert.Equal(t, int64(3), event.Version)
+	assert.NotNil(t, event.EventTime)
+	assert.Equal(t, product.Attributes, event.Attributes.AsMap())
+	assert.Equal(t, int64(7), event.MerchantId)
+}
+
+func TestKafka_NewProductMessage_Envelope(t *testing.T) {
+	ctx := context.WithValue(context.Background(), envelope.TraceIdKey, "trace")
+
+	message, err := newProductMessage(ctx, "product_updates", entities.ProductInfo{ProductId: 42}, "delete")
+	assert.NoError(t, err)
+
+	key, err := message.Key.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, "42", string(key))
+
+	headers := make(map[string]string)
+	for _, header := range message.Headers {
+		headers[string(header.Key)] = string(header.Value)
+	}
+	assert.Equal(t, envelope.EventProductDeleted, headers[envelope.HeaderEventType])
+	assert.Equal(t, "5", headers[envelope.HeaderSchemaVersion])
+	assert.Equal(t, "product", headers[envelope.HeaderProducer])
+	assert.Equal(t, "trace", headers[envelope.HeaderTraceId])
+	assert.Len(t, headers[envelope.HeaderEventId], 36)
+	assert.NotEmpty(t, headers[envelope.HeaderOccurredAt])
+}
+
+func TestKafka_NewProductMessage_UnknownAction(t *testing.T) {
+	message, err := newProductMessage(context.Background(), "product_updates", entities.ProductInfo{ProductId: 1}, "move")
+	assert.Error(t, err)
+	assert.Nil(t, message)
+}
+
+func TestKafka_NewProductMessage_Snapshot(t *testing.T) {
+	message, err := newProductMessage(context.Background(), "product_updates", entities.ProductInfo{ProductId: 5}, "snapshot")
+	assert.NoError(t, err)
+
+	data, err := message.Value.Encode()
+	assert.NoError(t, err)
+
+	var event myproto.ProductAction
+	assert.NoError(t, proto.Unmarshal(data, &event))
+	assert.Equal(t, myproto.Action_SNAPSHOT, event.ActionType)
+
+	for _, header := range message.Headers {
+		if string(header.Key) == envelope.HeaderEventType {
+			assert.Equal(t, envelope.EventProductSnapshot, string(header.Value))
+		}
+	}
+}
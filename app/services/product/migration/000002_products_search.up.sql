@@ -0,0 +1,21 @@
+-- ключевые слова продукта одной строкой для полнотекстового поиска
+ALTER TABLE products ADD COLUMN IF NOT EXISTS prd_keywords TEXT NOT NULL DEFAULT '';
+
+-- ключевые слова существующих продуктов переносятся из product_keyWord
+UPDATE products p SET prd_keywords = kw.names
+FROM (
+    SELECT pk.product_id, STRING_AGG(k.kw_name, ' ' ORDER BY pk.id) AS names
+    FROM product_keyWord pk
+    JOIN keyWords k ON k.id = pk.kw_id
+    GROUP BY pk.product_id
+) kw
+WHERE kw.product_id = p.id;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', prd_keywords), 'A') ||
+    setweight(to_tsvector('english', prd_keywords), 'A') ||
+    setweight(to_tsvector('russian', prd_description), 'B') ||
+    setweight(to_tsvector('english', prd_description), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS products_search_vector_idx ON products USING GIN (search_vector);
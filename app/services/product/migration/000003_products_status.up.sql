@@ -0,0 +1,12 @@
+-- до жизненного цикла статус был произвольной строкой: приводим его к draft, active, out_of_stock, archived.
+-- Неизвестные значения считаются active - такие продукты и раньше показывались пользователям
+UPDATE products SET prd_status = CASE
+    WHEN LOWER(TRIM(prd_status)) IN ('draft', 'new') THEN 'draft'
+    WHEN LOWER(TRIM(prd_status)) IN ('out_of_stock', 'out of stock', 'unavailable', 'unavailible', 'sold out') THEN 'out_of_stock'
+    WHEN LOWER(TRIM(prd_status)) IN ('archived', 'archive', 'deleted') THEN 'archived'
+    ELSE 'active'
+END
+WHERE prd_status NOT IN ('draft', 'active', 'out_of_stock', 'archived');
+
+ALTER TABLE products ADD CONSTRAINT products_status_check
+    CHECK (prd_status IN ('draft', 'active', 'out_of_stock', 'archived'));
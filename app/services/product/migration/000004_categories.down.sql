@@ -0,0 +1,13 @@
+-- продукты возвращаются к строковой категории с именем их категории
+ALTER TABLE products ADD COLUMN category VARCHAR(255);
+
+UPDATE products p SET category = c.cat_name
+FROM categories c
+WHERE c.id = p.category_id;
+
+ALTER TABLE products
+    ALTER COLUMN category SET NOT NULL,
+    ADD CHECK (LENGTH(TRIM(category)) > 0),
+    DROP COLUMN category_id;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,31 @@
+-- path - материализованный путь: id категорий от корня до текущей включительно
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    cat_name VARCHAR(32) NOT NULL,
+    parent_id INTEGER,
+    cat_path INTEGER[] NOT NULL DEFAULT '{}',
+    FOREIGN KEY (parent_id) REFERENCES categories(id) ON DELETE RESTRICT,
+    CHECK (LENGTH(TRIM(cat_name)) > 0)
+);
+
+-- имя категории уникально в пределах родителя (в том числе среди корневых)
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_idx ON categories (COALESCE(parent_id, 0), cat_name);
+CREATE INDEX IF NOT EXISTS categories_path_idx ON categories USING GIN (cat_path);
+
+-- строковые категории существующих продуктов становятся корневыми категориями
+INSERT INTO categories (cat_name)
+SELECT DISTINCT LEFT(TRIM(category), 32) FROM products
+ON CONFLICT DO NOTHING;
+
+UPDATE categories SET cat_path = ARRAY[id] WHERE parent_id IS NULL AND cat_path = '{}';
+
+ALTER TABLE products ADD COLUMN category_id INTEGER;
+
+UPDATE products p SET category_id = c.id
+FROM categories c
+WHERE c.parent_id IS NULL AND c.cat_name = LEFT(TRIM(p.category), 32);
+
+ALTER TABLE products
+    ALTER COLUMN category_id SET NOT NULL,
+    ADD FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT,
+    DROP COLUMN category;
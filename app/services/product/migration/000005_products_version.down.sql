@@ -0,0 +1 @@
+ALTER TABLE products DROP COLUMN IF EXISTS prd_version;
@@ -0,0 +1,2 @@
+-- версия продукта увеличивается при каждом изменении, существующие продукты начинают с 1
+ALTER TABLE products ADD COLUMN IF NOT EXISTS prd_version INTEGER NOT NULL DEFAULT 1;
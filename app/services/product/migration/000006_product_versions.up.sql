@@ -0,0 +1,12 @@
+-- полный снимок продукта после каждого изменения, история не удаляется вместе с продуктом.
+-- История существующих продуктов начинается с их следующего изменения
+CREATE TABLE IF NOT EXISTS product_versions (
+    product_id INTEGER NOT NULL,
+    prd_version INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    snapshot JSONB NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_id, prd_version)
+);
+
+CREATE INDEX IF NOT EXISTS product_versions_changed_at_idx ON product_versions (product_id, changed_at);
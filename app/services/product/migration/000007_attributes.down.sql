@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN IF EXISTS prd_attributes;
+ALTER TABLE categories DROP COLUMN IF EXISTS cat_attributes;
@@ -0,0 +1,3 @@
+-- схема атрибутов категории и значения атрибутов продукта
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS cat_attributes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS prd_attributes JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS products_merchant_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS merchant_id;
+DROP TABLE IF EXISTS merchants;
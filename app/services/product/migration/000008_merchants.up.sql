@@ -0,0 +1,20 @@
+-- квота продуктов мерчанта, строка создается при добавлении первого продукта мерчанта
+CREATE TABLE IF NOT EXISTS merchants (
+    id INTEGER PRIMARY KEY,
+    max_products INTEGER NOT NULL,
+    CHECK (id > 0),
+    CHECK (max_products > 0)
+);
+
+-- продукты, созданные до появления мерчантов, принадлежат мерчанту 1
+ALTER TABLE products ADD COLUMN IF NOT EXISTS merchant_id INTEGER NOT NULL DEFAULT 1 CHECK (merchant_id > 0);
+ALTER TABLE products ALTER COLUMN merchant_id DROP DEFAULT;
+
+-- квота мерчанта 1 не меньше числа его продуктов, иначе он не сможет добавить ни одного продукта
+-- (1000 - entities.DefaultMerchantQuota)
+INSERT INTO merchants (id, max_products)
+SELECT 1, GREATEST(COUNT(*), 1000) FROM products
+HAVING COUNT(*) > 0
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS products_merchant_idx ON products (merchant_id, id);
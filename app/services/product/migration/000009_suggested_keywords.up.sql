@@ -0,0 +1,2 @@
+-- ключевые слова, предложенные по описанию продукта и еще не подтвержденные мерчантом
+ALTER TABLE products ADD COLUMN IF NOT EXISTS prd_suggested_keywords TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_duplicates;
+DROP TABLE IF EXISTS product_signatures;
@@ -0,0 +1,21 @@
+-- MinHash-сигнатура описания продукта, bands - хэши полос сигнатуры (LSH):
+-- продукты с общей полосой - кандидаты в дубликаты.
+-- Сигнатуры существующих продуктов появляются при их следующем изменении
+CREATE TABLE IF NOT EXISTS product_signatures (
+    product_id INTEGER PRIMARY KEY,
+    signature BIGINT[] NOT NULL,
+    bands BIGINT[] NOT NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS product_signatures_bands_idx ON product_signatures USING GIN (bands);
+
+-- пары почти одинаковых продуктов, каждая пара хранится в обе стороны
+CREATE TABLE IF NOT EXISTS product_duplicates (
+    product_id INTEGER NOT NULL,
+    duplicate_id INTEGER NOT NULL,
+    similarity DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (product_id, duplicate_id),
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    FOREIGN KEY (duplicate_id) REFERENCES products(id) ON DELETE CASCADE
+);
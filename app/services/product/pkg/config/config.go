@@ -14,9 +14,17 @@ import (
 type ServiceConfig struct {
 	SrvConf ServerConfig
 	DBConf  DBConfig
+	KWConf  KeyWordsConfig
 	Env     string `yaml:"env" env-default:"local"`
 }
 
+// конфигурация извлечения ключевых слов из описания продукта, при AutoApply
+// предложенные ключевые слова сразу добавляются к ключевым словам продукта
+type KeyWordsConfig struct {
+	AutoApply    bool `yaml:"autoApply"`
+	MaxSuggested int  `yaml:"maxSuggested"`
+}
+
 // кофигурация базы данных
 type DBConfig struct {
 	Username string `yaml:"username"`
@@ -25,6 +33,8 @@ type DBConfig struct {
 	Port     string `yaml:"port"`
 	Dbname   string `yaml:"dbname"`
 	Sslmode  string `yaml:"sslmode"`
+	//таймаут одного обращения к базе (запроса или транзакции), 0 - без таймаута
+	QueryTimeout time.Duration `yaml:"queryTimeout"`
 }
 
 // конфигурация сервера
@@ -125,6 +135,7 @@ func LoadConfig(path string, name string) (*ServiceConfig, error) {
 	var (
 		dbConf  DBConfig
 		srvConf ServerConfig
+		kwConf  KeyWordsConfig
 	)
 
 	//инициализируем имя, папку и тип конфига
@@ -152,9 +163,15 @@ func LoadConfig(path string, name string) (*ServiceConfig, error) {
 		return nil, err
 	}
 
+	//заполняем структуру извлечения ключевых слов
+	if err := viper.UnmarshalKey("keywords", &kwConf); err != nil {
+		return nil, err
+	}
+
 	return &ServiceConfig{
 		SrvConf: srvConf,
 		DBConf:  dbConf,
+		KWConf:  kwConf,
 	}, nil
 
 }
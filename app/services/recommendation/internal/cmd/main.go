@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/jobs"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/service"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/api"
@@ -42,10 +43,10 @@ func main() {
 	defer kvConn.KVDB.Close()
 
 	// слой репозитория
-	repository := repository.NewRecomRepository(dbConn, kvConn, logger)
+	repository := repository.NewRecomRepository(dbConn, kvConn, logger, cfg.SimConf)
 
 	// слой сервиса
-	service := service.NewRecommendationService(repository, logger)
+	service := service.NewRecommendationService(repository, logger, cfg.DivConf)
 
 	// обработка остановки по сигналу
 	ctxSig, stop := signal.NotifyContext(
@@ -61,6 +62,12 @@ func main() {
 		}
 	}()
 
+	//пересчет похожих продуктов для коллаборативной фильтрации
+	go jobs.NewSimilarityJob(repository, logger, cfg.SimConf).Run(ctxSig)
+
+	//пересчет рейтингов популярности для пользователей без персональных рекомендаций
+	go jobs.NewPopularityJob(repository, logger, cfg.PopConf).Run(ctxSig)
+
 	// Закрываем коннект
 	defer func() {
 		if err := kafkaConn.Consumer.Close(); err != nil {
@@ -69,7 +76,7 @@ func main() {
 	}()
 
 	// транспортный слой
-	handlers := api.NewHandler(service, logger)
+	handlers := api.NewHandler(service, logger, cfg.PgConf)
 
 	// инициализация сервера
 	srv, err := server.NewServer(cfg.SrvConf, handlers.InitRoutes(), logger)
@@ -0,0 +1,90 @@
+// переранжирование выдачи по разнообразию: maximal marginal relevance по ключевым словам продуктов
+
+package entities
+
+import (
+	"errors"
+	"math"
+)
+
+// число первых продуктов выдачи, которые переранжируются, если не задано в конфигурации
+const DefaultDiversityWindow = MaxPageLimit
+
+func ValidateDiversity(diversity float64) error {
+
+	if math.IsNaN(diversity) || diversity < 0 || diversity > 1 {
+		return errors.New("invalid diversity: must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// переранжирование первых window продуктов выдачи: на каждом шаге выбирается продукт с наибольшим
+// (1 - diversity) * оценка / максимальная оценка - diversity * наибольшая близость к уже выбранным,
+// близость - коэффициент Жаккара ключевых слов. diversity 0 - порядок по оценке, 1 - только разнообразие.
+// При равных значениях сохраняется исходный порядок, продукты после window остаются на своих местах
+func DiversifyRecommendations(recommendations []Recommendation, diversity float64, window int) []Recommendation {
+	window = min(window, len(recommendations))
+	if diversity <= 0 || window < 2 {
+		return recommendations
+	}
+
+	maxScore := 0.0
+	sets := make([]map[string]struct{}, window)
+	for i, recommendation := range recommendations[:window] {
+		maxScore = max(maxScore, recommendation.Score)
+		sets[i] = make(map[string]struct{}, len(recommendation.KeyWords))
+		for _, keyWord := range recommendation.KeyWords {
+			sets[i][keyWord] = struct{}{}
+		}
+	}
+
+	relevance := func(i int) float64 {
+		if maxScore <= 0 {
+			return 0
+		}
+		return recommendations[i].Score / maxScore
+	}
+
+	//наибольшая близость каждого кандидата к выбранным продуктам обновляется после каждого выбора
+	similarity := make([]float64, window)
+	selected := make([]bool, window)
+	reranked := make([]Recommendation, 0, len(recommendations))
+	for len(reranked) < window {
+		best, bestValue := -1, math.Inf(-1)
+		for i := range window {
+			if selected[i] {
+				continue
+			}
+			if value := (1-diversity)*relevance(i) - diversity*similarity[i]; value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		selected[best] = true
+		reranked = append(reranked, recommendations[best])
+		for i := range window {
+			if !selected[i] {
+				similarity[i] = max(similarity[i], jaccard(sets[i], sets[best]))
+			}
+		}
+	}
+
+	return append(reranked, recommendations[window:]...)
+}
+
+// коэффициент Жаккара двух множеств ключевых слов, 0 если оба пусты
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	for keyWord := range a {
+		if _, ok := b[keyWord]; ok {
+			common++
+		}
+	}
+
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,83 @@
+// объяснения рекомендаций: какие интересы совпали и из чего сложилась оценка продукта
+
+package entities
+
+import "math"
+
+// стратегия, которой получен продукт выдачи
+const (
+	StrategyKeyWords      = "keywords"
+	StrategyCollaborative = "collaborative"
+	StrategyBlended       = "blended"
+	StrategyPopular       = "popular"
+)
+
+// вклад каждой составляющей в оценку продукта: ключевые слова (сумма IDF совпавших ключевых слов),
+// коллаборативная фильтрация (близость к последним продуктам пользователя с учетом веса) и популярность
+type ScoreComponents struct {
+	KeyWords      float64 `json:"keyWords"`
+	Collaborative float64 `json:"collaborative"`
+	Popularity    float64 `json:"popularity"`
+}
+
+// объяснение рекомендации, считается вместе с оценкой и кэшируется вместе с выдачей,
+// поэтому из кэша и из базы возвращается одинаковым
+type Explanation struct {
+	Strategy        string          `json:"strategy"`
+	MatchedKeyWords []string        `json:"matchedKeyWords"`
+	Components      ScoreComponents `json:"components"`
+}
+
+// объяснение продукта, найденного по общим с пользователем ключевым словам
+func KeyWordsExplanation(score float64, matchedKeyWords []string) *Explanation {
+	if matchedKeyWords == nil {
+		matchedKeyWords = []string{}
+	}
+	return &Explanation{
+		Strategy:        StrategyKeyWords,
+		MatchedKeyWords: matchedKeyWords,
+		Components:      ScoreComponents{KeyWords: score},
+	}
+}
+
+// объяснение соседа последних продуктов пользователя, вклад - сумма близостей без учета веса
+func CollaborativeExplanation(score float64) *Explanation {
+	return &Explanation{
+		Strategy:        StrategyCollaborative,
+		MatchedKeyWords: []string{},
+		Components:      ScoreComponents{Collaborative: score},
+	}
+}
+
+// объяснение продукта из рейтинга популярности
+func PopularExplanation(score float64) *Explanation {
+	return &Explanation{
+		Strategy:        StrategyPopular,
+		MatchedKeyWords: []string{},
+		Components:      ScoreComponents{Popularity: score},
+	}
+}
+
+// у всех ли продуктов выдачи есть объяснение, выдача без объяснений закэширована до их появления
+func Explained(recommendations []Recommendation) bool {
+	for _, recommendation := range recommendations {
+		if recommendation.Explanation == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// копия выдачи без объяснений - они возвращаются только по запросу
+func WithoutExplanations(recommendations []Recommendation) []Recommendation {
+	result := make([]Recommendation, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		recommendation.Explanation = nil
+		result = append(result, recommendation)
+	}
+	return result
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*10000) / 10000
+}
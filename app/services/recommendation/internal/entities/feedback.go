@@ -0,0 +1,37 @@
+// отзывы пользователей на рекомендованные продукты
+
+package entities
+
+import (
+	"errors"
+)
+
+// причины отказа от продукта
+const (
+	FeedbackNotInterested = "not_interested"
+	FeedbackAlreadyOwned  = "already_owned"
+	FeedbackOffensive     = "offensive"
+)
+
+// во сколько раз уменьшается вклад ключевых слов отклоненного продукта в оценку других продуктов.
+// already_owned только исключает продукт - похожие продукты пользователю по-прежнему интересны
+const FeedbackKeyWordPenalty = 0.5
+
+// отзыв пользователя: продукт больше не рекомендуется этому пользователю
+type Feedback struct {
+	ProductId int    `json:"productId" binding:"required"`
+	Reason    string `json:"reason" binding:"required"`
+}
+
+func (f *Feedback) ValidateFeedback() error {
+
+	if f.ProductId <= 0 {
+		return errors.New("invalid product id: can`t be less or equel 0")
+	}
+
+	if f.Reason != FeedbackNotInterested && f.Reason != FeedbackAlreadyOwned && f.Reason != FeedbackOffensive {
+		return errors.New("invalid reason: must be not_interested, already_owned or offensive")
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+// события взаимодействия пользователя с продуктами
+
+package entities
+
+import (
+	"errors"
+
+	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
+)
+
+// вес сигнала по типу, если продюсер не задал вес
+var interactionWeights = map[myproto.InteractionType]float64{
+	myproto.InteractionType_VIEW:     1,
+	myproto.InteractionType_CLICK:    2,
+	myproto.InteractionType_PURCHASE: 5,
+}
+
+func ValidateInteraction(interaction *myproto.Interaction) error {
+
+	if interaction.UserId <= 0 || interaction.ProductId <= 0 {
+		return errors.New("invalid interaction: user id and product id must be greater than 0")
+	}
+
+	if _, ok := interactionWeights[interaction.Type]; !ok {
+		return errors.New("invalid interaction: unknown type " + interaction.Type.String())
+	}
+
+	if interaction.Weight < 0 {
+		return errors.New("invalid interaction: weight can`t be less than 0")
+	}
+
+	return nil
+}
+
+// вес взаимодействия, у события без веса - вес его типа
+func InteractionWeight(interaction *myproto.Interaction) float64 {
+	if interaction.Weight == 0 {
+		return interactionWeights[interaction.Type]
+	}
+	return interaction.Weight
+}
@@ -0,0 +1,9 @@
+// рейтинги популярности продуктов для пользователей без персональных рекомендаций
+
+package entities
+
+// число продуктов в общем рейтинге и в рейтинге категории, если не задано в конфигурации
+const DefaultPopularityTopK = MaxPageLimit
+
+// рейтинг без категории - общий, у продуктов без категории в событии категория 0
+const GlobalCategory = 0
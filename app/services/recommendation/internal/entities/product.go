@@ -0,0 +1,38 @@
+// события о продуктах
+
+package entities
+
+import (
+	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
+)
+
+// статус продукта, который может рекомендоваться, остальные статусы исключаются из выдачи
+const StatusActive = "active"
+
+// тип события о продукте, у старых сообщений без actionType тип определяется по строковому action
+func ProductActionType(product *myproto.ProductAction) myproto.Action {
+	if product.ActionType != myproto.Action_ACTION_UNSPECIFIED {
+		return product.ActionType
+	}
+
+	switch product.Action {
+	case "add":
+		return myproto.Action_CREATED
+	case "update":
+		return myproto.Action_UPDATED
+	case "delete":
+		return myproto.Action_DELETED
+	case "snapshot":
+		return myproto.Action_SNAPSHOT
+	}
+
+	return myproto.Action_ACTION_UNSPECIFIED
+}
+
+// статус продукта из события, у старых сообщений без статуса продукт считается активным
+func ProductStatus(product *myproto.ProductAction) string {
+	if product.Status == "" {
+		return StatusActive
+	}
+	return product.Status
+}
@@ -0,0 +1,184 @@
+// рекомендации пользователю и формат ответа
+
+package entities
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	//формат ответа: продукты с оценкой (по умолчанию) или только id продуктов, как раньше
+	FormatScored = "scored"
+	FormatIds    = "ids"
+)
+
+const (
+	//источник выдачи: персональные рекомендации или рейтинг популярности,
+	//если персональных рекомендаций нет
+	SourcePersonal = "personal"
+	SourcePopular  = "popular"
+)
+
+const (
+	//размер страницы по умолчанию и максимальный, если не заданы в конфигурации
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// рекомендуемый продукт и его оценка - сумма IDF общих с пользователем ключевых слов,
+// редкие ключевые слова весят больше частых. Explanation возвращается только при explain=true.
+// KeyWords - ключевые слова продукта для переранжирования по разнообразию, хранятся в кэше
+// вместе с выдачей и в ответ не попадают
+type Recommendation struct {
+	ProductId   int          `json:"productId"`
+	Score       float64      `json:"score"`
+	Explanation *Explanation `json:"explanation,omitempty"`
+	KeyWords    []string     `json:"keyWords,omitempty"`
+}
+
+// параметры страницы рекомендаций: Cursor - курсор из предыдущей страницы
+// или Offset - число пропускаемых продуктов от начала выдачи. Diversity - степень
+// разнообразия выдачи от 0 до 1, nil - значение из конфигурации. Explain - вернуть объяснения
+type RecommendationQuery struct {
+	Cursor    string
+	Offset    int
+	Limit     int
+	Diversity *float64
+	Explain   bool
+	//курсор предыдущей страницы, заполняется при валидации курсора
+	After *RecommendationCursor
+}
+
+// курсор следующей страницы: последний продукт предыдущей страницы, число продуктов до следующей
+// страницы и степень разнообразия, с которой переранжирована выдача - переранжированная выдача
+// не упорядочена по оценке. У курсора прежнего формата (оценка и id) нет позиции и разнообразия
+type RecommendationCursor struct {
+	Last      Recommendation
+	Position  int
+	Diversity *float64
+}
+
+// страница рекомендаций, NextCursor пуст если страница последняя
+type RecommendationPage struct {
+	Recommendations []Recommendation `json:"recommendations"`
+	NextCursor      string           `json:"nextCursor,omitempty"`
+	Source          string           `json:"source"`
+}
+
+func ValidateFormat(format string) error {
+
+	if format != "" && format != FormatScored && format != FormatIds {
+		return errors.New("invalid format: must be scored or ids")
+	}
+
+	return nil
+}
+
+// id рекомендуемых продуктов в порядке убывания оценки
+func ProductIds(recommendations []Recommendation) []int {
+	productIds := make([]int, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		productIds = append(productIds, recommendation.ProductId)
+	}
+	return productIds
+}
+
+// копия выдачи без ключевых слов продуктов - они нужны только для переранжирования
+func WithoutKeyWords(recommendations []Recommendation) []Recommendation {
+	result := make([]Recommendation, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		recommendation.KeyWords = nil
+		result = append(result, recommendation)
+	}
+	return result
+}
+
+func (q *RecommendationQuery) ValidateRecommendationQuery(defaultLimit, maxLimit int) error {
+
+	if q.Offset < 0 {
+		return errors.New("invalid offset: can`t be less than 0")
+	}
+
+	if q.Cursor != "" && q.Offset != 0 {
+		return errors.New("invalid query: cursor and offset can`t be used together")
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultLimit
+	}
+
+	if q.Limit < 0 || q.Limit > maxLimit {
+		return errors.New("invalid limit: must be between 1 and " + strconv.Itoa(maxLimit))
+	}
+
+	if q.Diversity != nil {
+		if err := ValidateDiversity(*q.Diversity); err != nil {
+			return err
+		}
+	}
+
+	if q.Cursor != "" {
+		after, err := DecodeRecommendationCursor(q.Cursor)
+		if err != nil {
+			return err
+		}
+		//страницы одной выдачи переранжируются с одним разнообразием
+		if q.Diversity != nil && after.Diversity != nil && *q.Diversity != *after.Diversity {
+			return errors.New("invalid query: diversity differs from the cursor")
+		}
+		q.After = after
+	}
+
+	return nil
+}
+
+// курсор следующей страницы - оценка и id последнего продукта страницы, число продуктов
+// до следующей страницы и степень разнообразия выдачи
+func EncodeRecommendationCursor(last Recommendation, position int, diversity float64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join([]string{
+		strconv.FormatFloat(last.Score, 'g', -1, 64),
+		strconv.Itoa(last.ProductId),
+		strconv.Itoa(position),
+		strconv.FormatFloat(diversity, 'g', -1, 64),
+	}, ":")))
+}
+
+// курсор прежнего формата (оценка и id) тоже корректен
+func DecodeRecommendationCursor(cursor string) (*RecommendationCursor, error) {
+	errCursor := errors.New("invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errCursor
+	}
+
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 2 && len(parts) != 4 {
+		return nil, errCursor
+	}
+
+	var after RecommendationCursor
+	if after.Last.Score, err = strconv.ParseFloat(parts[0], 64); err != nil {
+		return nil, errCursor
+	}
+	if after.Last.ProductId, err = strconv.Atoi(parts[1]); err != nil || after.Last.ProductId <= 0 {
+		return nil, errCursor
+	}
+	if len(parts) == 2 {
+		return &after, nil
+	}
+
+	if after.Position, err = strconv.Atoi(parts[2]); err != nil || after.Position <= 0 {
+		return nil, errCursor
+	}
+	diversity, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || ValidateDiversity(diversity) != nil {
+		return nil, errCursor
+	}
+	after.Diversity = &diversity
+
+	return &after, nil
+}
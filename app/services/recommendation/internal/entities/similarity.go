@@ -0,0 +1,71 @@
+// коллаборативная фильтрация: похожие продукты по взаимодействиям пользователей
+
+package entities
+
+import (
+	"sort"
+)
+
+const (
+	//минимальное число общих пользователей у пары продуктов и число соседей продукта,
+	//если не заданы в конфигурации
+	DefaultSimilarityMinSupport = 2
+	DefaultSimilarityTopK       = 20
+)
+
+// объединение выдачи по ключевым словам и соседей последних продуктов пользователя:
+// оценка продукта - оценка по ключевым словам плюс сумма близостей соседей с весом weight.
+// В объяснении продукта, найденного обеими стратегиями, сохраняются обе составляющие
+func BlendRecommendations(keyWords, neighbours []Recommendation, weight float64) []Recommendation {
+	if len(neighbours) == 0 || weight <= 0 {
+		return keyWords
+	}
+
+	scores := make(map[int]float64, len(keyWords)+len(neighbours))
+	explanations := make(map[int]*Explanation, len(keyWords)+len(neighbours))
+	productKeyWords := make(map[int][]string, len(keyWords)+len(neighbours))
+	for _, recommendation := range keyWords {
+		scores[recommendation.ProductId] += recommendation.Score
+		productKeyWords[recommendation.ProductId] = recommendation.KeyWords
+
+		explanation := KeyWordsExplanation(recommendation.Score, nil)
+		if recommendation.Explanation != nil {
+			copied := *recommendation.Explanation
+			explanation = &copied
+		}
+		explanations[recommendation.ProductId] = explanation
+	}
+	for _, recommendation := range neighbours {
+		scores[recommendation.ProductId] += weight * recommendation.Score
+		productKeyWords[recommendation.ProductId] = recommendation.KeyWords
+
+		explanation, ok := explanations[recommendation.ProductId]
+		if ok {
+			explanation.Strategy = StrategyBlended
+		} else {
+			explanation = CollaborativeExplanation(0)
+			explanations[recommendation.ProductId] = explanation
+		}
+		explanation.Components.Collaborative = roundScore(weight * recommendation.Score)
+	}
+
+	recommendations := make([]Recommendation, 0, len(scores))
+	for productId, score := range scores {
+		recommendations = append(recommendations, Recommendation{
+			ProductId:   productId,
+			Score:       roundScore(score),
+			Explanation: explanations[productId],
+			KeyWords:    productKeyWords[productId],
+		})
+	}
+
+	//тот же порядок, что и у выдачи из базы: по убыванию оценки, при равной оценке по id
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].ProductId < recommendations[j].ProductId
+	})
+
+	return recommendations
+}
@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
+)
+
+type PopularityBuilder interface {
+	RebuildPopularity(ctx context.Context, topK int) (int, error)
+}
+
+// задача пересчета общего рейтинга популярности и рейтингов категорий,
+// пустой размер рейтинга заменяется значением по умолчанию
+func NewPopularityJob(builder PopularityBuilder, log *slog.Logger, cfg config.PopularityConfig) *Runner {
+	if cfg.TopK <= 0 {
+		cfg.TopK = entities.DefaultPopularityTopK
+	}
+
+	return NewRunner("popularity", cfg.Interval, cfg.Timeout, func(ctx context.Context) (int, error) {
+		return builder.RebuildPopularity(ctx, cfg.TopK)
+	}, log)
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// мок, запоминающий параметры пересчета
+type MockPopularityBuilder struct {
+	topK int
+}
+
+func (m *MockPopularityBuilder) RebuildPopularity(ctx context.Context, topK int) (int, error) {
+	m.topK = topK
+	return 30, nil
+}
+
+func TestNewPopularityJob(t *testing.T) {
+	builder := &MockPopularityBuilder{}
+	job := NewPopularityJob(builder, newTestLogger(), config.PopularityConfig{})
+
+	products, err := job.rebuild(context.Background())
+
+	// пустой размер рейтинга заменен значением по умолчанию
+	assert.NoError(t, err)
+	assert.Equal(t, 30, products)
+	assert.Equal(t, entities.DefaultPopularityTopK, builder.topK)
+}
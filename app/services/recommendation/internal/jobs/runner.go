@@ -0,0 +1,90 @@
+// фоновые задачи сервиса
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
+)
+
+// пересчет таблицы, возвращает число сохраненных строк
+type Rebuild func(ctx context.Context) (int, error)
+
+// периодическая задача пересчета таблицы: запуск раз в interval, каждый пересчет ограничен timeout
+type Runner struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	rebuild  Rebuild
+	log      *slog.Logger
+}
+
+func NewRunner(name string, interval, timeout time.Duration, rebuild Rebuild, log *slog.Logger) *Runner {
+	return &Runner{
+		name:     name,
+		interval: interval,
+		timeout:  timeout,
+		rebuild:  rebuild,
+		log:      log,
+	}
+}
+
+// пересчет при запуске и затем раз в interval до отмены контекста, при interval 0 задача не запускается
+func (r *Runner) Run(ctx context.Context) {
+	fi := "jobs.Runner.Run"
+
+	if r.interval <= 0 {
+		r.log.Info(fmt.Sprintf("%s: %s job disabled", fi, r.name))
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.run(ctx, ticker.C)
+}
+
+// пересчет при запуске и затем на каждый тик до отмены контекста
+func (r *Runner) run(ctx context.Context, tick <-chan time.Time) {
+	fi := "jobs.Runner.run"
+
+	for {
+		r.runOnce(ctx)
+
+		select {
+		case <-tick:
+		case <-ctx.Done():
+			r.log.Info(fmt.Sprintf("%s: %s job stopped", fi, r.name))
+			return
+		}
+	}
+}
+
+// ошибка пересчета логируется, таблица остается прежней до следующего запуска.
+// Пока таблицу пересчитывает другая реплика сервиса, запуск пропускается
+func (r *Runner) runOnce(ctx context.Context) {
+	fi := "jobs.Runner.runOnce"
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	rows, err := r.rebuild(ctx)
+	if errors.Is(err, repository.ErrRebuildLocked) {
+		r.log.Info(fmt.Sprintf("%s: %s is rebuilt by another replica, skipped", fi, r.name))
+		return
+	}
+	if err != nil {
+		r.log.Error(fmt.Sprintf("%s: Error rebuilding %s: %v", fi, r.name, err))
+		return
+	}
+
+	r.log.Info(fmt.Sprintf("%s: %s rebuilt, %d rows", fi, r.name, rows))
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// пересчет, возвращающий по очереди ошибки из errs и сообщающий о каждом вызове
+func newTestRebuild(errs ...error) (Rebuild, <-chan context.Context) {
+	calls := make(chan context.Context)
+	return func(ctx context.Context) (int, error) {
+		calls <- ctx
+		if len(errs) == 0 {
+			return 10, nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return 0, err
+	}, calls
+}
+
+func TestRunner_Run(t *testing.T) {
+	// ошибка и занятая другой репликой блокировка не останавливают задачу
+	rebuild, calls := newTestRebuild(errors.New("some rel error"), repository.ErrRebuildLocked)
+	runner := NewRunner("test", time.Hour, time.Minute, rebuild, newTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		runner.run(ctx, tick)
+		close(done)
+	}()
+
+	// пересчет при запуске и на каждый тик, каждый с таймаутом
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			tick <- time.Now()
+		}
+		rebuildCtx := <-calls
+		_, ok := rebuildCtx.Deadline()
+		assert.True(t, ok)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestRunner_Run_WithoutTimeout(t *testing.T) {
+	rebuild, calls := newTestRebuild()
+	runner := NewRunner("test", time.Hour, 0, rebuild, newTestLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.run(ctx, nil)
+		close(done)
+	}()
+
+	_, ok := (<-calls).Deadline()
+	assert.False(t, ok)
+
+	cancel()
+	<-done
+}
+
+func TestRunner_Run_Disabled(t *testing.T) {
+	rebuild, _ := newTestRebuild()
+	runner := NewRunner("test", 0, 0, rebuild, newTestLogger())
+
+	// без интервала пересчет не вызывается (иначе вызов заблокировался бы на канале)
+	runner.Run(context.Background())
+}
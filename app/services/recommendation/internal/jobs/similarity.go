@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
+)
+
+type SimilarityBuilder interface {
+	RebuildItemSimilarity(ctx context.Context, minSupport, topK int) (int, error)
+}
+
+// задача пересчета матрицы похожих продуктов по взаимодействиям пользователей,
+// пустые минимальная поддержка и число соседей заменяются значениями по умолчанию
+func NewSimilarityJob(builder SimilarityBuilder, log *slog.Logger, cfg config.SimilarityConfig) *Runner {
+	if cfg.MinSupport <= 0 {
+		cfg.MinSupport = entities.DefaultSimilarityMinSupport
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = entities.DefaultSimilarityTopK
+	}
+
+	return NewRunner("item similarity", cfg.Interval, cfg.Timeout, func(ctx context.Context) (int, error) {
+		return builder.RebuildItemSimilarity(ctx, cfg.MinSupport, cfg.TopK)
+	}, log)
+}
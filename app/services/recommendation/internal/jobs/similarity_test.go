@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// мок, запоминающий параметры пересчета
+type MockSimilarityBuilder struct {
+	minSupport int
+	topK       int
+}
+
+func (m *MockSimilarityBuilder) RebuildItemSimilarity(ctx context.Context, minSupport, topK int) (int, error) {
+	m.minSupport, m.topK = minSupport, topK
+	return 10, nil
+}
+
+func TestNewSimilarityJob(t *testing.T) {
+	builder := &MockSimilarityBuilder{}
+	job := NewSimilarityJob(builder, newTestLogger(), config.SimilarityConfig{TopK: 5})
+
+	pairs, err := job.rebuild(context.Background())
+
+	// пустая поддержка заменена значением по умолчанию
+	assert.NoError(t, err)
+	assert.Equal(t, 10, pairs)
+	assert.Equal(t, entities.DefaultSimilarityMinSupport, builder.minSupport)
+	assert.Equal(t, 5, builder.topK)
+}
@@ -18,6 +18,9 @@ const (
 const (
 	//таблица
 	productsTable = "products"
+	//её поля
+	statusField     = "status"
+	categoryIdField = "category_id"
 )
 
 const (
@@ -33,3 +36,42 @@ const (
 	//её поля
 	kwNameField = "kw_name"
 )
+
+const (
+	//таблица
+	interactionsTable = "interactions"
+	//её поля
+	interactionTypeField = "interaction_type"
+	weightField          = "weight"
+	occurredAtField      = "occurred_at"
+	sessionIdField       = "session_id"
+	eventIdField         = "event_id"
+)
+
+const (
+	//таблица
+	itemSimilarityTable = "item_similarity"
+	//её поля
+	neighbourIdField = "neighbour_id"
+	scoreField       = "score"
+	supportField     = "support"
+)
+
+const (
+	//ключи advisory блокировок пересчетов, общие для всех реплик сервиса
+	similarityLockKey int64 = 1001
+	popularityLockKey int64 = 1002
+)
+
+const (
+	//таблица
+	feedbackTable = "feedback"
+	//её поля
+	reasonField    = "reason"
+	createdAtField = "created_at"
+)
+
+const (
+	//таблица
+	popularProductsTable = "popular_products"
+)
@@ -3,7 +3,8 @@ package repository
 import "errors"
 
 var (
-	ErrNotFound = errors.New("user not found")
+	ErrNotFound      = errors.New("user not found")
+	ErrRebuildLocked = errors.New("rebuild is running on another replica")
 )
 
 // var (
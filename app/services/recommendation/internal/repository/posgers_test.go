@@ -8,6 +8,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/stretchr/testify/assert"
@@ -313,7 +314,7 @@ func TestPostgreDB_AddProductUpdate_Correct(t *testing.T) {
 		ProductKeyWords: []string{"машины", "рыба", "анчоусы"},
 	}
 
-	err := dbConn.AddProductUpdate(context.Background(), &product)
+	_, err := dbConn.AddProductUpdate(context.Background(), &product)
 
 	// проверка, что запрос выполнен без ошибок
 	assert.NoError(t, err)
@@ -374,7 +375,7 @@ func TestPostgreDB_AddProductUpdate_CorrectAlreadyExist(t *testing.T) {
 		ProductKeyWords: []string{"машины", "рыба"},
 	}
 
-	err := dbConn.AddProductUpdate(context.Background(), &product)
+	_, err := dbConn.AddProductUpdate(context.Background(), &product)
 
 	// проверка, что запрос выполнен без ошибок
 	assert.NoError(t, err)
@@ -435,13 +436,13 @@ func TestPostgreDB_AddProductUpdate_Incorrect2(t *testing.T) {
 		ProductKeyWords: []string{},
 	}
 
-	err := dbConn.AddProductUpdate(context.Background(), &product)
+	_, err := dbConn.AddProductUpdate(context.Background(), &product)
 
 	// проверка, что запрос выполнен без ошибок
 	assert.Error(t, err)
 }
 
-func TestPostgreDB_AddProductUpdate_Incorrect3(t *testing.T) {
+func TestPostgreDB_AddProductUpdate_CorrectNoKeyWords(t *testing.T) {
 
 	// Подключение к Базе данных
 	dbConn := NewPostgresDB(
@@ -463,10 +464,10 @@ func TestPostgreDB_AddProductUpdate_Incorrect3(t *testing.T) {
 		ProductKeyWords: []string{},
 	}
 
-	err := dbConn.AddProductUpdate(context.Background(), &product)
+	_, err := dbConn.AddProductUpdate(context.Background(), &product)
 
-	// проверка, что запрос выполнен без ошибок
-	assert.Error(t, err)
+	// продукт без ключевых слов сохраняется, консьюмер не останавливается
+	assert.NoError(t, err)
 }
 
 func TestPostgreDB_AddProductUpdate_Incorrect4(t *testing.T) {
@@ -491,7 +492,7 @@ func TestPostgreDB_AddProductUpdate_Incorrect4(t *testing.T) {
 		ProductKeyWords: []string{""},
 	}
 
-	err := dbConn.AddProductUpdate(context.Background(), &product)
+	_, err := dbConn.AddProductUpdate(context.Background(), &product)
 
 	// проверка, что запрос выполнен без ошибок
 	assert.Error(t, err)
@@ -564,6 +565,401 @@ func TestPostgreDB_GetProductsByUserId_CorrectButNoRecom(t *testing.T) {
 
 	recom, err := dbConn.GetProductsByUserId(context.Background(), 2)
 	assert.NoError(t, err)
-	assert.Equal(t, recom, []int{})
+	assert.Equal(t, recom, []entities.Recommendation{})
+
+}
+
+func TestPostgreDB_DeleteProduct_Correct(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	err := dbConn.AddUserUpdate(context.Background(), &myproto.UserUpdate{
+		UserId:        7,
+		UserInterests: []string{"удочки"},
+	})
+	assert.NoError(t, err)
+
+	keyWords, err := dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+		ProductId:       7,
+		ProductKeyWords: []string{"удочки"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"удочки"}, keyWords)
+
+	recom, err := dbConn.GetProductsByUserId(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, recom[0].ProductId)
+
+	userKeyWords, err := dbConn.GetUserKeyWords(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"удочки"}, userKeyWords)
+
+	// удаленный продукт не рекомендуется, возвращаются его ключевые слова
+	keyWords, err = dbConn.DeleteProduct(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"удочки"}, keyWords)
 
+	recom, err = dbConn.GetProductsByUserId(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Recommendation{}, recom)
+}
+
+func TestPostgreDB_AddProductUpdate_CorrectInactive(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	err := dbConn.AddUserUpdate(context.Background(), &myproto.UserUpdate{
+		UserId:        8,
+		UserInterests: []string{"лески"},
+	})
+	assert.NoError(t, err)
+
+	// продукт в архиве не рекомендуется
+	_, err = dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+		ProductId:       8,
+		ProductKeyWords: []string{"лески"},
+		Status:          "archived",
+	})
+	assert.NoError(t, err)
+
+	recom, err := dbConn.GetProductsByUserId(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Recommendation{}, recom)
+}
+
+func TestPostgreDB_AddInteraction_Correct(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	// событие без веса и времени сохраняется с весом типа и временем получения,
+	// первое взаимодействие с продуктом меняет последние продукты пользователя
+	changed, err := dbConn.AddInteraction(context.Background(), &myproto.Interaction{
+		UserId:    9,
+		ProductId: 9,
+		Type:      myproto.InteractionType_PURCHASE,
+		SessionId: "s1",
+	}, "", 10)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	var weight float64
+	err = dbConn.DB.Get(&weight, `SELECT weight FROM interactions WHERE user_id = 9 ORDER BY id DESC LIMIT 1`)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(5), weight)
+
+	// повторный просмотр продукта из последних их не меняет
+	changed, err = dbConn.AddInteraction(context.Background(), &myproto.Interaction{
+		UserId:    9,
+		ProductId: 9,
+		Type:      myproto.InteractionType_VIEW,
+	}, "", 10)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestPostgreDB_AddInteraction_CorrectDuplicateEvent(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	// событие, повторно прочитанное из топика, сохраняется один раз и последние продукты не меняет
+	interaction := &myproto.Interaction{UserId: 10, ProductId: 10, Type: myproto.InteractionType_CLICK}
+	changed, err := dbConn.AddInteraction(context.Background(), interaction, "event-10", 10)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = dbConn.AddInteraction(context.Background(), interaction, "event-10", 10)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	var count int
+	err = dbConn.DB.Get(&count, `SELECT COUNT(*) FROM interactions WHERE event_id = 'event-10'`)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestPostgreDB_AddInteraction_Incorrect(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	_, err := dbConn.AddInteraction(context.Background(), &myproto.Interaction{
+		UserId:    0,
+		ProductId: 9,
+		Type:      myproto.InteractionType_VIEW,
+	}, "", 10)
+	assert.Error(t, err)
+}
+
+func TestPostgreDB_RebuildItemSimilarity_Correct(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	_, err := dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+		ProductId:       22,
+		ProductKeyWords: []string{"катушки"},
+	})
+	assert.NoError(t, err)
+
+	// продукты 21 и 22 смотрели два пользователя, продукт 23 - один
+	for _, interaction := range []*myproto.Interaction{
+		{UserId: 21, ProductId: 21, Type: myproto.InteractionType_VIEW},
+		{UserId: 21, ProductId: 22, Type: myproto.InteractionType_PURCHASE},
+		{UserId: 21, ProductId: 23, Type: myproto.InteractionType_VIEW},
+		{UserId: 22, ProductId: 21, Type: myproto.InteractionType_CLICK},
+		{UserId: 22, ProductId: 22, Type: myproto.InteractionType_VIEW},
+		{UserId: 23, ProductId: 21, Type: myproto.InteractionType_VIEW},
+	} {
+		_, err := dbConn.AddInteraction(context.Background(), interaction, "", 0)
+		assert.NoError(t, err)
+	}
+
+	pairs, err := dbConn.RebuildItemSimilarity(context.Background(), 2, 20)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, pairs, 2)
+
+	// сосед продукта 21 с поддержкой 2 - продукт 22, продукт 23 отсечен минимальной поддержкой
+	neighbours, err := dbConn.GetNeighbourProducts(context.Background(), 23, 10)
+	assert.NoError(t, err)
+	assert.Len(t, neighbours, 1)
+	assert.Equal(t, 22, neighbours[0].ProductId)
+	assert.Greater(t, neighbours[0].Score, float64(0))
+}
+
+func TestPostgreDB_AddFeedback_Correct(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	err := dbConn.AddUserUpdate(context.Background(), &myproto.UserUpdate{
+		UserId:        31,
+		UserInterests: []string{"блесны", "сети"},
+	})
+	assert.NoError(t, err)
+
+	for productId, keyWords := range map[int64][]string{31: {"блесны"}, 32: {"блесны"}, 34: {"сети"}} {
+		_, err := dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+			ProductId:       productId,
+			ProductKeyWords: keyWords,
+		})
+		assert.NoError(t, err)
+	}
+
+	scores := func() map[int]float64 {
+		recom, err := dbConn.GetProductsByUserId(context.Background(), 31)
+		assert.NoError(t, err)
+		result := make(map[int]float64, len(recom))
+		for _, recommendation := range recom {
+			result[recommendation.ProductId] = recommendation.Score
+		}
+		return result
+	}
+
+	before := scores()
+	assert.NoError(t, dbConn.AddFeedback(context.Background(), 31, &entities.Feedback{
+		ProductId: 31, Reason: entities.FeedbackNotInterested,
+	}))
+	after := scores()
+
+	// отклоненный продукт исключен, продукт с его ключевым словом понижен, остальные без изменений
+	assert.NotContains(t, after, 31)
+	assert.Less(t, after[32], before[32])
+	assert.Equal(t, before[34], after[34])
+
+	err = dbConn.AddFeedback(context.Background(), 100500, &entities.Feedback{
+		ProductId: 31, Reason: entities.FeedbackOffensive,
+	})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPostgreDB_RebuildPopularity_Correct(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	for productId, categoryId := range map[int64]int64{41: 4, 42: 4, 43: 5} {
+		_, err := dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+			ProductId:       productId,
+			CategoryId:      categoryId,
+			ProductKeyWords: []string{"воблеры"},
+		})
+		assert.NoError(t, err)
+	}
+
+	// продукт 42 покупали, с продуктом 43 пользователь 41 взаимодействовал больше всего
+	for _, interaction := range []*myproto.Interaction{
+		{UserId: 41, ProductId: 43, Type: myproto.InteractionType_PURCHASE},
+		{UserId: 42, ProductId: 42, Type: myproto.InteractionType_PURCHASE},
+		{UserId: 42, ProductId: 42, Type: myproto.InteractionType_PURCHASE},
+	} {
+		_, err := dbConn.AddInteraction(context.Background(), interaction, "", 0)
+		assert.NoError(t, err)
+	}
+
+	products, err := dbConn.RebuildPopularity(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, products, 6)
+
+	// неизвестный сервису пользователь получает общий рейтинг
+	recom, err := dbConn.GetPopularProducts(context.Background(), 100500)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, recom)
+	assert.Contains(t, entities.ProductIds(recom), 42)
+	assert.Contains(t, entities.ProductIds(recom), 43)
+
+	// пользователь 41 получает рейтинг категории 5
+	recom, err = dbConn.GetPopularProducts(context.Background(), 41)
+	assert.NoError(t, err)
+	assert.Contains(t, entities.ProductIds(recom), 43)
+	assert.NotContains(t, entities.ProductIds(recom), 42)
+}
+
+func TestPostgreDB_GetProductsByUserId_CorrectKeyWords(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	for productId, keyWords := range map[int64][]string{51: {"мормышки", "зима"}, 52: {"мормышки"}} {
+		_, err := dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+			ProductId:       productId,
+			ProductKeyWords: keyWords,
+		})
+		assert.NoError(t, err)
+	}
+	err := dbConn.AddUserUpdate(context.Background(), &myproto.UserUpdate{
+		UserId:        51,
+		UserInterests: []string{"мормышки"},
+	})
+	assert.NoError(t, err)
+
+	// у продуктов выдачи все их ключевые слова, а не только совпавшие с интересами
+	recom, err := dbConn.GetProductsByUserId(context.Background(), 51)
+	assert.NoError(t, err)
+	keyWords := make(map[int][]string, len(recom))
+	for _, recommendation := range recom {
+		keyWords[recommendation.ProductId] = recommendation.KeyWords
+	}
+	assert.Equal(t, []string{"зима", "мормышки"}, keyWords[51])
+	assert.Equal(t, []string{"мормышки"}, keyWords[52])
+}
+
+func TestPostgreDB_GetProductsByUserId_CorrectExplanation(t *testing.T) {
+	// Подключение к Базе данных
+	dbConn := NewPostgresDB(
+		loadConf(), slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+	)
+
+	// отключение
+	defer func() {
+		err := dbConn.DB.Close()
+		assert.NoError(t, err)
+	}()
+
+	err := dbConn.AddUserUpdate(context.Background(), &myproto.UserUpdate{
+		UserId:        61,
+		UserInterests: []string{"спиннинги", "лодки"},
+	})
+	assert.NoError(t, err)
+
+	_, err = dbConn.AddProductUpdate(context.Background(), &myproto.ProductAction{
+		ProductId:       61,
+		ProductKeyWords: []string{"спиннинги", "лодки", "палатки"},
+	})
+	assert.NoError(t, err)
+
+	// в объяснении только общие с пользователем ключевые слова, вклад ключевых слов равен оценке
+	recom, err := dbConn.GetProductsByUserId(context.Background(), 61)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, recom)
+	assert.Equal(t, 61, recom[0].ProductId)
+	assert.Equal(t, entities.StrategyKeyWords, recom[0].Explanation.Strategy)
+	assert.Equal(t, []string{"лодки", "спиннинги"}, recom[0].Explanation.MatchedKeyWords)
+	assert.Equal(t, recom[0].Score, recom[0].Explanation.Components.KeyWords)
 }
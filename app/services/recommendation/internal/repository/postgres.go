@@ -6,17 +6,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // имплементация RelationalDataBase интерфейса
 type PostgresDB struct {
-	DB  *sqlx.DB
-	log *slog.Logger
+	DB      *sqlx.DB
+	log     *slog.Logger
+	timeout time.Duration
 }
 
 // установка соединения с базой, паника в случае ошиби
@@ -27,160 +30,617 @@ func NewPostgresDB(cfg config.DBConfig, log *slog.Logger) *PostgresDB {
 		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.Dbname, cfg.Sslmode))
 
 	return &PostgresDB{
-		DB:  db,
-		log: log,
+		DB:      db,
+		log:     log,
+		timeout: cfg.QueryTimeout,
 	}
 }
 
-// функция поиска id продуктов, в которых может быть заинтересован пользователь
-func (p *PostgresDB) GetProductsByUserId(ctx context.Context, userId int) ([]int, error) {
+// функция поиска активных продуктов, в которых может быть заинтересован пользователь, по убыванию оценки.
+// Оценка - сумма ln(1 + N/df) общих с пользователем ключевых слов, где N - число активных продуктов,
+// df - число активных продуктов с ключевым словом, при равной оценке продукты упорядочены по id.
+// Отклоненные пользователем продукты не возвращаются, вклад их ключевых слов уменьшается.
+// В объяснении продукта - совпавшие ключевые слова, в KeyWords - все ключевые слова продукта
+func (p *PostgresDB) GetProductsByUserId(ctx context.Context, userId int) ([]entities.Recommendation, error) {
 
-	//начинаем транзакцию
-	trx, err := p.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
 
 	//проверка, существует ли пользователь с таким id
-	rowCheck := trx.QueryRow(`SELECT id FROM users WHERE id = $1`, userId)
+	rowCheck := p.DB.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1`, userId)
 	if err := rowCheck.Scan(&userId); err != nil {
-		trx.Rollback()
 		if errors.Is(err, sql.ErrNoRows) {
 			err = ErrNotFound
 		}
 		return nil, err
 	}
 
-	//формируем запрос для получения всех kw_id пользователя (id его интересов)
+	//ключевые слова пользователя и активных продуктов без повторов, частота ключевых слов по активным продуктам,
+	//ключевые слова продуктов, отклоненных пользователем не по причине already_owned
 	query := fmt.Sprintf(
-		`SELECT %s FROM %s WHERE %s = $1`,
-		kwIdField, userKwTable, userIdField,
+		`WITH user_kws AS (
+			SELECT DISTINCT %[1]s FROM %[2]s WHERE %[3]s = $1
+		), active_kws AS (
+			SELECT DISTINCT pk.%[4]s, pk.%[1]s FROM %[5]s pk
+			JOIN %[6]s p ON p.%[7]s = pk.%[4]s AND p.%[8]s = $2
+			WHERE pk.%[1]s IN (SELECT %[1]s FROM user_kws)
+		), df AS (
+			SELECT %[1]s, COUNT(*) AS n FROM active_kws GROUP BY %[1]s
+		), rejected_kws AS (
+			SELECT DISTINCT pk.%[1]s FROM %[5]s pk
+			JOIN %[9]s f ON f.%[4]s = pk.%[4]s AND f.%[3]s = $1 AND f.%[10]s <> $3
+		)
+		SELECT pk.%[4]s, ROUND(SUM(
+			LN(1 + (SELECT COUNT(*) FROM %[6]s WHERE %[8]s = $2)::float8 / df.n) *
+			CASE WHEN pk.%[1]s IN (SELECT %[1]s FROM rejected_kws) THEN $4::float8 ELSE 1 END
+		)::numeric, 4)::float8 AS score, ARRAY_AGG(kw.%[11]s ORDER BY kw.%[11]s) AS matched, %[13]s
+		FROM active_kws pk JOIN df ON df.%[1]s = pk.%[1]s
+		JOIN %[12]s kw ON kw.%[7]s = pk.%[1]s
+		WHERE pk.%[4]s NOT IN (SELECT %[4]s FROM %[9]s WHERE %[3]s = $1)
+		GROUP BY pk.%[4]s
+		ORDER BY score DESC, pk.%[4]s`,
+		kwIdField, userKwTable, userIdField, productIdField, productsKwTable, productsTable, idField, statusField,
+		feedbackTable, reasonField, kwNameField, kwTable, productKeyWordsColumn("pk."+productIdField),
 	)
 
-	//выполняем запрос
-	rowsU, err := trx.Query(query, userId)
+	rows, err := p.DB.QueryContext(ctx, query,
+		userId, entities.StatusActive, entities.FeedbackAlreadyOwned, entities.FeedbackKeyWordPenalty,
+	)
 	if err != nil {
-		trx.Rollback()
 		return nil, err
-	} else if err := rowsU.Err(); err != nil {
-		trx.Rollback()
+	}
+	defer rows.Close()
+
+	recommendations := make([]entities.Recommendation, 0)
+	for rows.Next() {
+		var (
+			recommendation entities.Recommendation
+			matched        []string
+		)
+		if err := rows.Scan(
+			&recommendation.ProductId, &recommendation.Score, pq.Array(&matched), pq.Array(&recommendation.KeyWords),
+		); err != nil {
+			return nil, err
+		}
+		recommendation.Explanation = entities.KeyWordsExplanation(recommendation.Score, matched)
+		recommendations = append(recommendations, recommendation)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
+	}
+
+	p.log.Info(fmt.Sprintf("User with id (%d) have %d recommended products", userId, len(recommendations)))
+
+	return recommendations, nil
+}
+
+// отзыв пользователя на продукт, повторный отзыв на тот же продукт заменяет причину
+func (p *PostgresDB) AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error {
+	fi := "repository.AddFeedback"
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка, существует ли пользователь с таким id
+		query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`, idField, usersTable, idField)
+		if err := tgx.QueryRowContext(ctx, query, userId).Scan(&userId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
+
+		query = fmt.Sprintf(
+			`INSERT INTO %[1]s (%[2]s, %[3]s, %[4]s) VALUES ($1, $2, $3)
+			 ON CONFLICT (%[2]s, %[3]s) DO UPDATE SET %[4]s = EXCLUDED.%[4]s, %[5]s = CURRENT_TIMESTAMP`,
+			feedbackTable, userIdField, productIdField, reasonField, createdAtField,
+		)
+		if _, err := tgx.ExecContext(ctx, query, userId, feedback.ProductId, feedback.Reason); err != nil {
+			return err
+		}
+
+		p.log.Info(fmt.Sprintf("%s: User %d rejected product %d: %s", fi, userId, feedback.ProductId, feedback.Reason))
+		return nil
+	})
+}
+
+// соседи recentItems последних продуктов, с которыми взаимодействовал пользователь.
+// Оценка соседа - сумма близостей к этим продуктам, сами продукты, неактивные и отклоненные
+// пользователем соседи не возвращаются
+func (p *PostgresDB) GetNeighbourProducts(ctx context.Context, userId int, recentItems int) ([]entities.Recommendation, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
 
+	query := fmt.Sprintf(
+		`WITH recent AS (
+			SELECT %[1]s FROM %[2]s WHERE %[3]s = $1
+			GROUP BY %[1]s ORDER BY MAX(%[4]s) DESC, %[1]s LIMIT $2
+		)
+		SELECT s.%[5]s, ROUND(SUM(s.%[6]s)::numeric, 4)::float8 AS score, %[12]s
+		FROM %[7]s s
+		JOIN recent r ON r.%[1]s = s.%[1]s
+		JOIN %[8]s p ON p.%[9]s = s.%[5]s AND p.%[10]s = $3
+		WHERE s.%[5]s NOT IN (SELECT %[1]s FROM recent)
+			AND s.%[5]s NOT IN (SELECT %[1]s FROM %[11]s WHERE %[3]s = $1)
+		GROUP BY s.%[5]s
+		ORDER BY score DESC, s.%[5]s`,
+		productIdField, interactionsTable, userIdField, occurredAtField,
+		neighbourIdField, scoreField, itemSimilarityTable, productsTable, idField, statusField,
+		feedbackTable, productKeyWordsColumn("s."+neighbourIdField),
+	)
+
+	rows, err := p.DB.QueryContext(ctx, query, userId, recentItems, entities.StatusActive)
+	if err != nil {
+		return nil, err
 	}
-	defer rowsU.Close()
+	defer rows.Close()
 
-	//заполняем слайс интересами пользователя
-	userInterests := make([]int, 0)
-	for rowsU.Next() {
-		var interestId int
-		if err := rowsU.Scan(&interestId); err != nil {
-			trx.Rollback()
+	recommendations := make([]entities.Recommendation, 0)
+	for rows.Next() {
+		var recommendation entities.Recommendation
+		if err := rows.Scan(&recommendation.ProductId, &recommendation.Score, pq.Array(&recommendation.KeyWords)); err != nil {
 			return nil, err
 		}
-		if interestId != 1 {
-			userInterests = append(userInterests, interestId)
-		} else {
-			continue
-		}
+		recommendation.Explanation = entities.CollaborativeExplanation(recommendation.Score)
+		recommendations = append(recommendations, recommendation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	p.log.Info(fmt.Sprintf("User with id (%d) have interests with id %v", int(userId), userInterests))
+	return recommendations, nil
+}
 
-	userRecommendations := make([]int, 0)
-	for _, interestId := range userInterests {
-		//формируем запрос для получения id продуктов, в которых есть ключевые слова пользователя
-		query := fmt.Sprintf(
-			`SELECT %s FROM %s WHERE %s = $1`,
-			productIdField, productsKwTable, kwIdField,
+// пересчет матрицы похожих продуктов по всем взаимодействиям: вектор продукта - суммарные веса
+// взаимодействий по пользователям, близость - косинус между векторами. У продукта сохраняется
+// topK самых близких соседей с не меньше minSupport общих пользователей, возвращается число пар.
+// Пересчет идет дольше обычного запроса, поэтому его таймаут задает задача через контекст.
+// Если матрицу уже пересчитывает другая реплика сервиса, возвращается ErrRebuildLocked
+func (p *PostgresDB) RebuildItemSimilarity(ctx context.Context, minSupport, topK int) (int, error) {
+	fi := "repository.RebuildItemSimilarity"
+
+	var pairs int64
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		if err := lockRebuild(ctx, tgx, similarityLockKey); err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`DELETE FROM %s`, itemSimilarityTable)
+		if _, err := tgx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf(
+			`WITH ui AS (
+				SELECT %[1]s, %[2]s, SUM(%[3]s) AS w FROM %[4]s GROUP BY %[1]s, %[2]s
+			), norms AS (
+				SELECT %[2]s, SQRT(SUM(w * w)) AS norm FROM ui GROUP BY %[2]s
+			), pairs AS (
+				SELECT a.%[2]s, b.%[2]s AS %[5]s, SUM(a.w * b.w) AS dot, COUNT(*) AS %[7]s
+				FROM ui a JOIN ui b ON a.%[1]s = b.%[1]s AND a.%[2]s <> b.%[2]s
+				GROUP BY a.%[2]s, b.%[2]s
+				HAVING COUNT(*) >= $1
+			), ranked AS (
+				SELECT pr.%[2]s, pr.%[5]s, pr.dot / NULLIF(na.norm * nb.norm, 0) AS %[6]s, pr.%[7]s,
+					ROW_NUMBER() OVER (
+						PARTITION BY pr.%[2]s ORDER BY pr.dot / NULLIF(na.norm * nb.norm, 0) DESC, pr.%[5]s
+					) AS rn
+				FROM pairs pr
+				JOIN norms na ON na.%[2]s = pr.%[2]s
+				JOIN norms nb ON nb.%[2]s = pr.%[5]s
+			)
+			INSERT INTO %[8]s (%[2]s, %[5]s, %[6]s, %[7]s)
+			SELECT %[2]s, %[5]s, %[6]s, %[7]s FROM ranked WHERE rn <= $2 AND %[6]s IS NOT NULL`,
+			userIdField, productIdField, weightField, interactionsTable,
+			neighbourIdField, scoreField, supportField, itemSimilarityTable,
 		)
-		//выполняем запрос
-		rowsP, err := p.DB.Query(query, interestId)
+		result, err := tgx.ExecContext(ctx, query, minSupport, topK)
 		if err != nil {
-			trx.Rollback()
-			return nil, err
+			return err
 		}
-		defer rowsP.Close()
-
-		//заполняем слайс интересными пользователю продуктами
-		for rowsP.Next() {
-			var productId int
-			if err := rowsP.Scan(&productId); err != nil {
-				trx.Rollback()
-				return nil, err
-			}
-			userRecommendations = append(userRecommendations, productId)
+		if pairs, err = result.RowsAffected(); err != nil {
+			return err
 		}
+
+		p.log.Info(fmt.Sprintf("%s: SUCCESS rebuilt item similarity, %d pairs", fi, pairs))
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	trx.Commit()
+	return int(pairs), nil
+}
+
+// блокировка пересчета до конца транзакции, чтобы реплики сервиса не пересчитывали
+// одну таблицу одновременно. Если блокировку держит другая реплика - ErrRebuildLocked
+func lockRebuild(ctx context.Context, tgx *sql.Tx, key int64) error {
+	var locked bool
+	if err := tgx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, key).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return ErrRebuildLocked
+	}
 
-	return userRecommendations, nil
+	return nil
 }
 
-func (p *PostgresDB) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
-	fi := "repository.AddUserUpdate"
-	tgx, err := p.DB.Begin()
+// пересчет общего рейтинга популярности и рейтингов категорий активных продуктов, в каждом TopK продуктов.
+// Оценка - сумма весов взаимодействий с продуктом, продукты без взаимодействий (или с равной суммой)
+// упорядочиваются по частоте их ключевых слов среди активных продуктов - она добавляется дробной частью.
+// Таймаут пересчета задает задача, если рейтинги уже пересчитывает другая реплика - ErrRebuildLocked
+func (p *PostgresDB) RebuildPopularity(ctx context.Context, topK int) (int, error) {
+	fi := "repository.RebuildPopularity"
+
+	var products int64
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		if err := lockRebuild(ctx, tgx, popularityLockKey); err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`DELETE FROM %s`, popularProductsTable)
+		if _, err := tgx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf(
+			`WITH active AS (
+				SELECT %[1]s, %[2]s FROM %[3]s WHERE %[4]s = $1
+			), iw AS (
+				SELECT %[5]s, SUM(%[6]s) AS w FROM %[7]s GROUP BY %[5]s
+			), active_kws AS (
+				SELECT DISTINCT pk.%[5]s, pk.%[8]s FROM %[9]s pk JOIN active a ON a.%[1]s = pk.%[5]s
+			), df AS (
+				SELECT %[8]s, COUNT(*) AS n FROM active_kws GROUP BY %[8]s
+			), kf AS (
+				SELECT ak.%[5]s, SUM(df.n) AS n FROM active_kws ak JOIN df ON df.%[8]s = ak.%[8]s GROUP BY ak.%[5]s
+			), scored AS (
+				SELECT a.%[1]s AS %[5]s, a.%[2]s, ROUND((
+					COALESCE(iw.w, 0) + COALESCE(kf.n, 0)::float8 / (1 + MAX(COALESCE(kf.n, 0)) OVER ())::float8
+				)::numeric, 4)::float8 AS %[10]s
+				FROM active a
+				LEFT JOIN iw ON iw.%[5]s = a.%[1]s
+				LEFT JOIN kf ON kf.%[5]s = a.%[1]s
+			), ranked AS (
+				SELECT $2::int AS %[2]s, %[5]s, %[10]s,
+					ROW_NUMBER() OVER (ORDER BY %[10]s DESC, %[5]s) AS rn
+				FROM scored
+				UNION ALL
+				SELECT %[2]s, %[5]s, %[10]s,
+					ROW_NUMBER() OVER (PARTITION BY %[2]s ORDER BY %[10]s DESC, %[5]s) AS rn
+				FROM scored WHERE %[2]s <> $2
+			)
+			INSERT INTO %[11]s (%[2]s, %[5]s, %[10]s)
+			SELECT %[2]s, %[5]s, %[10]s FROM ranked WHERE rn <= $3`,
+			idField, categoryIdField, productsTable, statusField,
+			productIdField, weightField, interactionsTable, kwIdField, productsKwTable,
+			scoreField, popularProductsTable,
+		)
+		result, err := tgx.ExecContext(ctx, query, entities.StatusActive, entities.GlobalCategory, topK)
+		if err != nil {
+			return err
+		}
+		if products, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		p.log.Info(fmt.Sprintf("%s: SUCCESS rebuilt popularity, %d products", fi, products))
+		return nil
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	//добавление id пользователя в табблицу users
+	return int(products), nil
+}
+
+// популярные продукты для пользователя без персональных рекомендаций по убыванию оценки:
+// рейтинг категории, с продуктами которой пользователь больше всего взаимодействовал, или общий рейтинг.
+// Пользователь может быть еще неизвестен сервису, отклоненные им продукты исключаются
+func (p *PostgresDB) GetPopularProducts(ctx context.Context, userId int) ([]entities.Recommendation, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
 	query := fmt.Sprintf(
-		`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
-		usersTable, idField,
+		`WITH user_category AS (
+			SELECT p.%[1]s FROM %[2]s i JOIN %[3]s p ON p.%[4]s = i.%[5]s
+			WHERE i.%[6]s = $1 AND p.%[1]s <> $2
+			GROUP BY p.%[1]s ORDER BY SUM(i.%[7]s) DESC, p.%[1]s LIMIT 1
+		), category AS (
+			SELECT COALESCE((
+				SELECT pp.%[1]s FROM %[8]s pp WHERE pp.%[1]s IN (SELECT %[1]s FROM user_category) LIMIT 1
+			), $2) AS %[1]s
+		)
+		SELECT pp.%[5]s, pp.%[9]s, %[12]s FROM %[8]s pp
+		JOIN %[3]s p ON p.%[4]s = pp.%[5]s AND p.%[10]s = $3
+		WHERE pp.%[1]s = (SELECT %[1]s FROM category)
+			AND pp.%[5]s NOT IN (SELECT %[5]s FROM %[11]s WHERE %[6]s = $1)
+		ORDER BY pp.%[9]s DESC, pp.%[5]s`,
+		categoryIdField, interactionsTable, productsTable, idField, productIdField, userIdField,
+		weightField, popularProductsTable, scoreField, statusField, feedbackTable,
+		productKeyWordsColumn("pp."+productIdField),
 	)
-	if _, err := tgx.Exec(query, user.UserId); err != nil {
-		tgx.Rollback()
-		return fmt.Errorf("%s: %s %v", fi, query, err)
+
+	rows, err := p.DB.QueryContext(ctx, query, userId, entities.GlobalCategory, entities.StatusActive)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	//Добавление ключевых слов (интересов) пользователя в таблицу keyWords и таблицу-связку
-	if err := addKeyWords(int(user.UserId), tgx, user.UserInterests, userKwTable); err != nil {
-		tgx.Rollback()
-		p.log.Error("%s: Error adding User KeyWords (userId %d): %v", fi, user.UserId, err.Error(), err)
-		return err
-	} else {
-		p.log.Info(fmt.Sprintf("%s: User KeyWords %v (userId %d) added", fi, user.UserInterests, user.UserId))
+	recommendations := make([]entities.Recommendation, 0)
+	for rows.Next() {
+		var recommendation entities.Recommendation
+		if err := rows.Scan(&recommendation.ProductId, &recommendation.Score, pq.Array(&recommendation.KeyWords)); err != nil {
+			return nil, err
+		}
+		recommendation.Explanation = entities.PopularExplanation(recommendation.Score)
+		recommendations = append(recommendations, recommendation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	tgx.Commit()
-	return nil
+	return recommendations, nil
+}
+
+func (p *PostgresDB) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
+	fi := "repository.AddUserUpdate"
+
+	//у пользователя должен быть хотя бы один интерес
+	if len(user.UserInterests) == 0 {
+		return fmt.Errorf("%s: user %d has no interests", fi, user.UserId)
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//добавление id пользователя в табблицу users
+		query := fmt.Sprintf(
+			`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
+			usersTable, idField,
+		)
+		if _, err := tgx.ExecContext(ctx, query, user.UserId); err != nil {
+			return fmt.Errorf("%s: %s %v", fi, query, err)
+		}
+
+		//Добавление ключевых слов (интересов) пользователя в таблицу keyWords и таблицу-связку
+		if err := addKeyWords(ctx, int(user.UserId), tgx, user.UserInterests, userKwTable); err != nil {
+			p.log.Error("%s: Error adding User KeyWords (userId %d): %v", fi, user.UserId, err.Error(), err)
+			return err
+		}
+		p.log.Info(fmt.Sprintf("%s: User KeyWords %v (userId %d) added", fi, user.UserInterests, user.UserId))
+		return nil
+	})
 }
 
-func (p *PostgresDB) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error {
+// сохранение продукта, его статуса и ключевых слов. Возвращает ключевые слова продукта до и после
+// изменения - рекомендации пользователей с этими интересами могли измениться
+func (p *PostgresDB) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) ([]string, error) {
 	fi := "repository.AddProductUpdate"
 
-	tgx, err := p.DB.Begin()
+	var changed []string
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//добавление продукта в таблицу products или обновление его статуса и категории
+		query := fmt.Sprintf(
+			`INSERT INTO %[1]s (%[2]s, %[3]s, %[4]s) VALUES ($1, $2, $3)
+			 ON CONFLICT (%[2]s) DO UPDATE SET %[3]s = EXCLUDED.%[3]s, %[4]s = EXCLUDED.%[4]s`,
+			productsTable, idField, statusField, categoryIdField,
+		)
+		if _, err := tgx.ExecContext(ctx, query,
+			product.ProductId, entities.ProductStatus(product), product.CategoryId); err != nil {
+			return err
+		}
+
+		//старые ключевые слова продукта
+		before, err := productKeyWords(ctx, tgx, int(product.ProductId))
+		if err != nil {
+			return err
+		}
+
+		//Добавление ключевых слов продукта в таблицу keyWords и таблицу-связку
+		if err := addKeyWords(ctx,
+			int(product.ProductId), tgx, product.ProductKeyWords, productsKwTable); err != nil {
+			p.log.Error("%s: Error adding Product KeyWords (productId %d): %s", fi, product.ProductId, err.Error(), err)
+			return err
+		}
+		p.log.Info(fmt.Sprintf("%s: Product KeyWords %v (ProductId %d) added", fi, product.ProductKeyWords, product.ProductId))
+
+		changed = mergeKeyWords(before, product.ProductKeyWords)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// удаление продукта и его ключевых слов. Возвращает ключевые слова, которые были у продукта
+func (p *PostgresDB) DeleteProduct(ctx context.Context, productId int) ([]string, error) {
+	fi := "repository.DeleteProduct"
+
+	var keyWords []string
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		var err error
+		if keyWords, err = productKeyWords(ctx, tgx, productId); err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, productsKwTable, productIdField)
+		if _, err := tgx.ExecContext(ctx, query, productId); err != nil {
+			return err
+		}
+
+		query = fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, productsTable, idField)
+		if _, err := tgx.ExecContext(ctx, query, productId); err != nil {
+			return err
+		}
+
+		p.log.Info(fmt.Sprintf("%s: Product %d deleted", fi, productId))
+		return nil
+	})
 	if err != nil {
+		return nil, err
+	}
+
+	return keyWords, nil
+}
+
+// сохранение взаимодействия пользователя с продуктом, у события без времени - время получения.
+// Событие с уже сохраненным eventId (повторно прочитанное из топика) пропускается, пустой eventId не проверяется.
+// Возвращает true, если продукта не было среди recentItems последних продуктов пользователя -
+// тогда меняются соседи в его выдаче. При recentItems 0 последние продукты не проверяются
+func (p *PostgresDB) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string, recentItems int) (bool, error) {
+	fi := "repository.AddInteraction"
+
+	var occurredAt sql.NullTime
+	if interaction.Timestamp != nil {
+		occurredAt = sql.NullTime{Time: interaction.Timestamp.AsTime(), Valid: true}
+	}
+
+	var (
+		recent bool
+		added  int64
+	)
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		if recentItems > 0 {
+			query := fmt.Sprintf(
+				`SELECT EXISTS (
+					SELECT 1 FROM (
+						SELECT %[1]s FROM %[2]s WHERE %[3]s = $1
+						GROUP BY %[1]s ORDER BY MAX(%[4]s) DESC, %[1]s LIMIT $2
+					) recent WHERE %[1]s = $3
+				)`,
+				productIdField, interactionsTable, userIdField, occurredAtField,
+			)
+			if err := tgx.QueryRowContext(ctx, query,
+				interaction.UserId, recentItems, interaction.ProductId,
+			).Scan(&recent); err != nil {
+				return err
+			}
+		}
+
+		query := fmt.Sprintf(
+			`INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s) 
+			 VALUES ($1, $2, $3, $4, COALESCE($5, CURRENT_TIMESTAMP), $6, $7)
+			 ON CONFLICT (%s) DO NOTHING`,
+			interactionsTable,
+			userIdField, productIdField, interactionTypeField, weightField, occurredAtField, sessionIdField, eventIdField,
+			eventIdField,
+		)
+		result, err := tgx.ExecContext(ctx, query,
+			interaction.UserId, interaction.ProductId, interaction.Type.String(),
+			entities.InteractionWeight(interaction), occurredAt, interaction.SessionId,
+			sql.NullString{String: eventId, Valid: eventId != ""},
+		)
+		if err != nil {
+			return err
+		}
+		added, err = result.RowsAffected()
 		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if added == 0 {
+		p.log.Info(fmt.Sprintf("%s: duplicate interaction event %s skipped", fi, eventId))
+		return false, nil
 	}
 
-	//добавление id продукта в таблицу products
+	p.log.Info(fmt.Sprintf("%s: SUCCESS added interaction of user %d with product %d", fi, interaction.UserId, interaction.ProductId))
+	return recentItems > 0 && !recent, nil
+}
+
+// функция возвращает интересы пользователя
+func (p *PostgresDB) GetUserKeyWords(ctx context.Context, userId int) ([]string, error) {
+
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
 	query := fmt.Sprintf(
-		`INSERT INTO %s (%s) VALUES ($1) ON CONFLICT DO NOTHING`,
-		productsTable, idField,
+		`SELECT DISTINCT kw.%[1]s FROM %[2]s uk JOIN %[3]s kw ON kw.%[4]s = uk.%[5]s WHERE uk.%[6]s = $1`,
+		kwNameField, userKwTable, kwTable, idField, kwIdField, userIdField,
 	)
-	if _, err := tgx.Exec(query, product.ProductId); err != nil {
-		tgx.Rollback()
-		return err
+
+	keyWords := make([]string, 0)
+	if err := p.DB.SelectContext(ctx, &keyWords, query, userId); err != nil {
+		return nil, err
 	}
+	return keyWords, nil
+}
 
-	//Добавление ключевых слов продукта в таблицу keyWords и таблицу-связку
-	if err := addKeyWords(
-		int(product.ProductId), tgx, product.ProductKeyWords, productsKwTable); err != nil {
-		tgx.Rollback()
-		p.log.Error("%s: Error adding Product KeyWords (productId %d): %s", fi, product.ProductId, err.Error(), err)
-		return err
-	} else {
-		p.log.Info(fmt.Sprintf("%s: Product KeyWords %v (ProductId %d) added", fi, product.ProductKeyWords, product.ProductId))
+// ключевые слова продукта из колонки column в порядке названий - хранятся в выдаче вместе с оценкой,
+// чтобы переранжирование по разнообразию не обращалось в базу на каждый запрос
+func productKeyWordsColumn(column string) string {
+	return fmt.Sprintf(
+		`ARRAY(SELECT DISTINCT pkw_kw.%[1]s FROM %[2]s pkw JOIN %[3]s pkw_kw ON pkw_kw.%[4]s = pkw.%[5]s
+			WHERE pkw.%[6]s = %[7]s ORDER BY pkw_kw.%[1]s) AS keywords`,
+		kwNameField, productsKwTable, kwTable, idField, kwIdField, productIdField, column,
+	)
+}
+
+// названия ключевых слов продукта
+func productKeyWords(ctx context.Context, trx *sql.Tx, productId int) ([]string, error) {
+	query := fmt.Sprintf(
+		`SELECT DISTINCT kw.%[1]s FROM %[2]s pk JOIN %[3]s kw ON kw.%[4]s = pk.%[5]s WHERE pk.%[6]s = $1`,
+		kwNameField, productsKwTable, kwTable, idField, kwIdField, productIdField,
+	)
+
+	rows, err := trx.QueryContext(ctx, query, productId)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	tgx.Commit()
-	return nil
+	keyWords := make([]string, 0)
+	for rows.Next() {
+		var keyWord string
+		if err := rows.Scan(&keyWord); err != nil {
+			return nil, err
+		}
+		keyWords = append(keyWords, keyWord)
+	}
+	return keyWords, rows.Err()
+}
+
+// объединение ключевых слов без повторов
+func mergeKeyWords(a, b []string) []string {
+	seen := make(map[string]struct{}, len(a)+len(b))
+	result := make([]string, 0, len(a)+len(b))
+	for _, keyWords := range [][]string{a, b} {
+		for _, keyWord := range keyWords {
+			if _, ok := seen[keyWord]; !ok {
+				seen[keyWord] = struct{}{}
+				result = append(result, keyWord)
+			}
+		}
+	}
+	return result
 }
 
-// функция для добавления kw в таблицу keyWords и таблицу-связку userKw или productKw в зависимости от параметра table
-func addKeyWords(id int, trx *sql.Tx, kw []string, table string) error {
+// функция для добавления kw в таблицу keyWords и таблицу-связку userKw или productKw в зависимости от параметра table,
+// старые связи удаляются, пустой kw только удаляет их
+func addKeyWords(ctx context.Context, id int, trx *sql.Tx, kw []string, table string) error {
 	fi := "repository.addKeyWords"
 	var idToinsert string
 
@@ -194,7 +654,7 @@ func addKeyWords(id int, trx *sql.Tx, kw []string, table string) error {
 		`DELETE FROM %s WHERE %s = $1`,
 		table, idToinsert,
 	)
-	if _, err := trx.Exec(query, id); err != nil || kw == nil || len(kw) == 0 {
+	if _, err := trx.ExecContext(ctx, query, id); err != nil {
 		return fmt.Errorf("%s: %s %v", fi, query, err)
 	}
 
@@ -205,17 +665,15 @@ func addKeyWords(id int, trx *sql.Tx, kw []string, table string) error {
 			`SELECT %s FROM %s WHERE %s = $1`,
 			idField, kwTable, kwNameField,
 		)
-		row := trx.QueryRow(query, keyWord)
+		row := trx.QueryRowContext(ctx, query, keyWord)
 		//получем id интереса
 		if err := row.Scan(&keyWordId); err != nil {
 			//	произошла ошибка - возвращаем ее
 			if err != sql.ErrNoRows {
 				return fmt.Errorf("%s: %s %v", fi, query, err)
-			} else if keyWordId == 1 {
-				continue
 			}
 			//	если нет в таблице такого keyWord - добавляем
-			keyWordId, err = addKeyWord(trx, keyWord)
+			keyWordId, err = addKeyWord(ctx, trx, keyWord)
 			if err != nil {
 				return fmt.Errorf("%s: INSERT %v", fi, err)
 			}
@@ -226,14 +684,14 @@ func addKeyWords(id int, trx *sql.Tx, kw []string, table string) error {
 			table,
 			kwIdField, idToinsert,
 		)
-		if _, err := trx.Exec(querryKeyWordsProduct, keyWordId, id); err != nil {
+		if _, err := trx.ExecContext(ctx, querryKeyWordsProduct, keyWordId, id); err != nil {
 			return fmt.Errorf("%s: %s %v", fi, querryKeyWordsProduct, err)
 		}
 	}
 	return nil
 }
 
-func addKeyWord(trx *sql.Tx, keyWord string) (int, error) {
+func addKeyWord(ctx context.Context, trx *sql.Tx, keyWord string) (int, error) {
 	var kwId int
 	//формируем запрос для добавления новой записи в таблицу keyWords
 	queryAddKeyWords := fmt.Sprintf(
@@ -242,7 +700,7 @@ func addKeyWord(trx *sql.Tx, keyWord string) (int, error) {
 		kwNameField, idField,
 	)
 	//выполняем запрос
-	row := trx.QueryRow(queryAddKeyWords, keyWord)
+	row := trx.QueryRowContext(ctx, queryAddKeyWords, keyWord)
 
 	//получем id интереса
 	if err := row.Scan(&kwId); err != nil {
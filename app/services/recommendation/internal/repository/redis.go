@@ -8,10 +8,29 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/go-redis/redis"
 )
 
+// ключи рекомендаций: recom:user:{id} - рекомендации пользователя, recom:ver:{id} - версия его
+// рекомендаций, увеличивается при каждом сбросе кэша, recom:kw:ключевое слово - обратный индекс,
+// множество пользователей с этим интересом. {id} - hash tag, ключи пользователя в одном слоте
+// Redis Cluster, поэтому меняются в одной транзакции
+const (
+	userKeyPrefix    = "recom:user:"
+	versionKeyPrefix = "recom:ver:"
+	keyWordKeyPrefix = "recom:kw:"
+	recomTTL         = time.Hour
+)
+
+// атомарное чтение и удаление множества обратного индекса KEYS[1], возвращает пользователей
+var popKeyWordScript = redis.NewScript(`
+local userIds = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return userIds
+`)
+
 type RedisRepository struct {
 	KVDB *redis.Client
 }
@@ -24,55 +43,138 @@ func NewRedisDB(cfg *config.KeyValueConfig) *RedisRepository {
 	}
 }
 
-func (r *RedisRepository) GetRecom(ctx context.Context, userId int) ([]int, error) {
-	userIdKey := strconv.Itoa(userId)
-	jsonData, err := r.KVDB.Get(userIdKey).Bytes()
+func userKey(userId int) string {
+	return userKeyPrefix + "{" + strconv.Itoa(userId) + "}"
+}
+
+func versionKey(userId int) string {
+	return versionKeyPrefix + "{" + strconv.Itoa(userId) + "}"
+}
+
+func keyWordKey(keyWord string) string {
+	return keyWordKeyPrefix + keyWord
+}
+
+func (r *RedisRepository) GetRecom(ctx context.Context, userId int) ([]entities.Recommendation, error) {
+	jsonData, err := r.KVDB.Get(userKey(userId)).Bytes()
 	if errors.Is(err, redis.Nil) {
 		return nil, nil
 	} else if err != nil {
 		return nil, fmt.Errorf("error getting product ids for user %d: %w", userId, err)
 	}
 
-	var productIds []int
-	err = json.Unmarshal(jsonData, &productIds)
+	var recommendations []entities.Recommendation
+	err = json.Unmarshal(jsonData, &recommendations)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling product ids: %w", err)
+		return nil, fmt.Errorf("error unmarshalling recommendations: %w", err)
 	}
-	return productIds, nil
+	return recommendations, nil
 }
 
-func (r *RedisRepository) SetRecom(ctx context.Context, userId int, productIds []int) error {
-	userIdKey := strconv.Itoa(userId)
+// запись пользователя в обратный индекс интересов keyWords и текущая версия его рекомендаций.
+// Вызывается до расчета рекомендаций: сброс кэша во время расчета найдет пользователя в индексе
+// и увеличит версию, тогда SetRecom не сохранит устаревшие рекомендации
+func (r *RedisRepository) WatchRecom(ctx context.Context, userId int, keyWords []string) (int64, error) {
+	pipe := r.KVDB.Pipeline()
+	defer pipe.Close()
 
-	jsonData, err := json.Marshal(productIds)
-	if err != nil {
-		return fmt.Errorf("error marshalling product ids: %w", err)
+	for _, keyWord := range keyWords {
+		pipe.SAdd(keyWordKey(keyWord), userId)
+		pipe.Expire(keyWordKey(keyWord), recomTTL)
+	}
+	version := pipe.Get(versionKey(userId))
+
+	if _, err := pipe.Exec(); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("error watching product ids for user %d: %w", userId, err)
 	}
 
-	err = r.KVDB.Set(userIdKey, jsonData, time.Hour).Err()
+	return recomVersion(version)
+}
+
+// рекомендации сохраняются, только если версия не изменилась с WatchRecom,
+// иначе кэш за время расчета сбросили и рекомендации не сохраняются
+func (r *RedisRepository) SetRecom(ctx context.Context, userId int, version int64, recommendations []entities.Recommendation) error {
+	jsonData, err := json.Marshal(recommendations)
 	if err != nil {
+		return fmt.Errorf("error marshalling recommendations: %w", err)
+	}
+
+	err = r.KVDB.Watch(func(tx *redis.Tx) error {
+		current, err := recomVersion(tx.Get(versionKey(userId)))
+		if err != nil || current != version {
+			return err
+		}
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(userKey(userId), jsonData, recomTTL)
+			return nil
+		})
+		return err
+	}, versionKey(userId))
+
+	//версия изменилась между проверкой и записью
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil
+	} else if err != nil {
 		return fmt.Errorf("error setting product ids for user %d: %w", userId, err)
 	}
 
 	return nil
 }
 
+// сброс рекомендаций пользователя и увеличение их версии в одной транзакции
 func (r *RedisRepository) DelRecom(ctx context.Context, userId int) error {
-	userIdKey := strconv.Itoa(userId)
+	pipe := r.KVDB.TxPipeline()
+	defer pipe.Close()
 
-	_, err := r.KVDB.Del(userIdKey).Result()
+	pipe.Del(userKey(userId))
+	pipe.Incr(versionKey(userId))
+	pipe.Expire(versionKey(userId), recomTTL)
 
-	if err != nil {
+	if _, err := pipe.Exec(); err != nil {
 		return fmt.Errorf("error deleting product ids for user %d: %w", userId, err)
 	}
 	return nil
 }
 
-func (r *RedisRepository) DelAll(ctx context.Context) error {
-	_, err := r.KVDB.FlushAll().Result()
+// удаление рекомендаций всех пользователей с интересами из keyWords: множества индекса
+// читаются и удаляются скриптом по одному ключу, рекомендации сбрасываются транзакцией на пользователя
+func (r *RedisRepository) DelKeyWordsRecom(ctx context.Context, keyWords []string) error {
+	if len(keyWords) == 0 {
+		return nil
+	}
+
+	userIds := make(map[int]struct{})
+	for _, keyWord := range keyWords {
+		members, err := popKeyWordScript.Run(r.KVDB, []string{keyWordKey(keyWord)}).Result()
+		if err != nil {
+			return fmt.Errorf("error deleting product ids for keyword %s: %w", keyWord, err)
+		}
 
-	if err != nil {
-		return fmt.Errorf("error deleting all product ids: %w", err)
+		for _, member := range members.([]interface{}) {
+			userId, err := strconv.Atoi(member.(string))
+			if err != nil {
+				return fmt.Errorf("error deleting product ids for keyword %s: %w", keyWord, err)
+			}
+			userIds[userId] = struct{}{}
+		}
+	}
+
+	for userId := range userIds {
+		if err := r.DelRecom(ctx, userId); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// версия рекомендаций, 0 если рекомендации не сбрасывались
+func recomVersion(cmd *redis.StringCmd) (int64, error) {
+	version, err := cmd.Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error getting recommendations version: %w", err)
+	}
+	return version, nil
+}
@@ -5,10 +5,20 @@ import (
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
 
+// запись рекомендаций в кэш так же, как при расчете: WatchRecom до расчета, затем SetRecom
+func setRecom(redisConn *RedisRepository, userId int, keyWords []string, recommendations []entities.Recommendation) error {
+	version, err := redisConn.WatchRecom(context.Background(), userId, keyWords)
+	if err != nil {
+		return err
+	}
+	return redisConn.SetRecom(context.Background(), userId, version, recommendations)
+}
+
 // Тестирование RedisRepository как KV databse
 func TestRedisRepository_SetGet_GetRecom_Correct(t *testing.T) {
 	addr := os.Getenv("REDIS_ADDR")
@@ -18,12 +28,12 @@ func TestRedisRepository_SetGet_GetRecom_Correct(t *testing.T) {
 
 	redisConn := NewRedisDB(&cfg)
 
-	err1 := redisConn.SetRecom(context.Background(), 1, []int{1, 2, 3})
+	err1 := setRecom(redisConn, 1, []string{"рыба"}, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}})
 	assert.NoError(t, err1)
 
 	recom, err2 := redisConn.GetRecom(context.Background(), 1)
 	assert.NoError(t, err2)
-	assert.Equal(t, []int{1, 2, 3}, recom)
+	assert.Equal(t, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}}, recom)
 }
 
 func TestRedisRepository_GetRecom_CorrectButNothing(t *testing.T) {
@@ -46,7 +56,7 @@ func TestRedisRepository_SetRecom_Incorrect(t *testing.T) {
 	}
 
 	redisConn := NewRedisDB(&cfg)
-	err1 := redisConn.SetRecom(context.Background(), 1, nil)
+	err1 := setRecom(redisConn, 1, []string{"рыба"}, nil)
 	assert.NoError(t, err1)
 }
 
@@ -57,10 +67,10 @@ func TestRedisRepository_SetRecom_Incorrect2(t *testing.T) {
 	}
 
 	redisConn := NewRedisDB(&cfg)
-	err1 := redisConn.SetRecom(context.Background(), 1, []int{1, 2, 3})
+	err1 := setRecom(redisConn, 1, []string{"рыба"}, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}})
 	assert.NoError(t, err1)
 
-	err2 := redisConn.SetRecom(context.Background(), 1, []int{1, 2, 3})
+	err2 := setRecom(redisConn, 1, []string{"рыба"}, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}})
 	assert.NoError(t, err2)
 }
 
@@ -72,12 +82,12 @@ func TestRedisRepository_DelRecom_Correct(t *testing.T) {
 
 	redisConn := NewRedisDB(&cfg)
 
-	err1 := redisConn.SetRecom(context.Background(), 1, []int{1, 2, 3})
+	err1 := setRecom(redisConn, 1, []string{"рыба"}, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}})
 	assert.NoError(t, err1)
 
 	recom, err2 := redisConn.GetRecom(context.Background(), 1)
 	assert.NoError(t, err2)
-	assert.Equal(t, []int{1, 2, 3}, recom)
+	assert.Equal(t, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}}, recom)
 
 	err3 := redisConn.DelRecom(context.Background(), 1)
 	assert.NoError(t, err3)
@@ -86,6 +96,39 @@ func TestRedisRepository_DelRecom_Correct(t *testing.T) {
 	assert.Nil(t, recom)
 }
 
+func TestRedisRepository_DelKeyWordsRecom_Correct(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	var cfg config.KeyValueConfig = config.KeyValueConfig{
+		Addr: addr,
+	}
+
+	redisConn := NewRedisDB(&cfg)
+
+	recoms := []entities.Recommendation{{ProductId: 1, Score: 3}}
+	assert.NoError(t, setRecom(redisConn, 1, []string{"машины", "рыба"}, recoms))
+	assert.NoError(t, setRecom(redisConn, 2, []string{"анчоусы"}, recoms))
+	assert.NoError(t, setRecom(redisConn, 3, []string{"удочки"}, recoms))
+
+	// удаляются только рекомендации пользователей с этими интересами
+	err := redisConn.DelKeyWordsRecom(context.Background(), []string{"рыба", "анчоусы"})
+	assert.NoError(t, err)
+
+	recom, err := redisConn.GetRecom(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Nil(t, recom)
+	recom, err = redisConn.GetRecom(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Nil(t, recom)
+	recom, err = redisConn.GetRecom(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, recoms, recom)
+
+	// ключи рекомендаций в своем пространстве имен
+	assert.Equal(t, int64(1), redisConn.KVDB.Exists("recom:user:{3}").Val())
+
+	assert.NoError(t, redisConn.DelKeyWordsRecom(context.Background(), nil))
+}
+
 func TestRedisRepository_DelRecom_Incorrect(t *testing.T) {
 	addr := os.Getenv("REDIS_ADDR")
 	var cfg config.KeyValueConfig = config.KeyValueConfig{
@@ -98,7 +141,7 @@ func TestRedisRepository_DelRecom_Incorrect(t *testing.T) {
 	assert.NoError(t, err3)
 }
 
-func TestRedisRepository_DelAll_Correct(t *testing.T) {
+func TestRedisRepository_SetGet_GetRecom_CorrectExplanation(t *testing.T) {
 	addr := os.Getenv("REDIS_ADDR")
 	var cfg config.KeyValueConfig = config.KeyValueConfig{
 		Addr: addr,
@@ -106,21 +149,41 @@ func TestRedisRepository_DelAll_Correct(t *testing.T) {
 
 	redisConn := NewRedisDB(&cfg)
 
-	err1 := redisConn.SetRecom(context.Background(), 1, []int{1, 2, 3})
-	assert.NoError(t, err1)
+	// объяснения из кэша совпадают с посчитанными
+	recoms := entities.BlendRecommendations(
+		[]entities.Recommendation{{ProductId: 1, Score: 2.0794, Explanation: entities.KeyWordsExplanation(2.0794, []string{"рыба"})}},
+		[]entities.Recommendation{{ProductId: 1, Score: 0.5, Explanation: entities.CollaborativeExplanation(0.5)}},
+		1,
+	)
+	assert.NoError(t, setRecom(redisConn, 4, []string{"рыба"}, recoms))
+
+	recom, err := redisConn.GetRecom(context.Background(), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, recoms, recom)
+	assert.Equal(t, entities.StrategyBlended, recom[0].Explanation.Strategy)
+}
 
-	err2 := redisConn.SetRecom(context.Background(), 2, []int{1, 2, 3})
-	assert.NoError(t, err2)
+func TestRedisRepository_SetRecom_CorrectButStale(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	var cfg config.KeyValueConfig = config.KeyValueConfig{
+		Addr: addr,
+	}
 
-	err3 := redisConn.DelAll(context.Background())
+	redisConn := NewRedisDB(&cfg)
 
-	assert.NoError(t, err3)
+	// кэш сброшен по интересу пользователя во время расчета - рекомендации не сохраняются
+	version, err := redisConn.WatchRecom(context.Background(), 5, []string{"снасти"})
+	assert.NoError(t, err)
+	assert.NoError(t, redisConn.DelKeyWordsRecom(context.Background(), []string{"снасти"}))
+	assert.NoError(t, redisConn.SetRecom(context.Background(), 5, version, []entities.Recommendation{{ProductId: 1, Score: 3}}))
 
-	recom, err4 := redisConn.GetRecom(context.Background(), 1)
-	assert.NoError(t, err4)
-	assert.Nil(t, recom)
-	recom, err5 := redisConn.GetRecom(context.Background(), 2)
-	assert.NoError(t, err5)
+	recom, err := redisConn.GetRecom(context.Background(), 5)
+	assert.NoError(t, err)
 	assert.Nil(t, recom)
 
+	// следующий расчет сохраняется с новой версией
+	assert.NoError(t, setRecom(redisConn, 5, []string{"снасти"}, []entities.Recommendation{{ProductId: 1, Score: 3}}))
+	recom, err = redisConn.GetRecom(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Recommendation{{ProductId: 1, Score: 3}}, recom)
 }
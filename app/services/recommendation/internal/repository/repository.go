@@ -2,88 +2,145 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 )
 
 type Repository interface {
-	GetRecommendations(ctx context.Context, userId int) ([]int, error)
+	GetRecommendations(ctx context.Context, userId int) ([]entities.Recommendation, error)
+	GetPopularRecommendations(ctx context.Context, userId int) ([]entities.Recommendation, error)
 	AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error
+	DeleteProduct(ctx context.Context, productId int) error
 	AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error
+	AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error
+	AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error
 }
 
 type KeyValueDatabse interface {
-	GetRecom(ctx context.Context, userId int) ([]int, error)
-	SetRecom(ctx context.Context, userId int, productIds []int) error
+	GetRecom(ctx context.Context, userId int) ([]entities.Recommendation, error)
+	WatchRecom(ctx context.Context, userId int, keyWords []string) (int64, error)
+	SetRecom(ctx context.Context, userId int, version int64, recommendations []entities.Recommendation) error
 	DelRecom(ctx context.Context, userId int) error
-	DelAll(ctx context.Context) error
+	DelKeyWordsRecom(ctx context.Context, keyWords []string) error
 }
 
 type RelationalDataBase interface {
-	GetProductsByUserId(ctx context.Context, userId int) ([]int, error)
-	AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error
+	GetProductsByUserId(ctx context.Context, userId int) ([]entities.Recommendation, error)
+	GetUserKeyWords(ctx context.Context, userId int) ([]string, error)
+	AddProductUpdate(ctx context.Context, product *myproto.ProductAction) ([]string, error)
+	DeleteProduct(ctx context.Context, productId int) ([]string, error)
 	AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error
+	AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string, recentItems int) (bool, error)
+	GetNeighbourProducts(ctx context.Context, userId int, recentItems int) ([]entities.Recommendation, error)
+	RebuildItemSimilarity(ctx context.Context, minSupport, topK int) (int, error)
+	AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error
+	GetPopularProducts(ctx context.Context, userId int) ([]entities.Recommendation, error)
+	RebuildPopularity(ctx context.Context, topK int) (int, error)
 }
 
 // имплементация Repository интерфейса
 type RecomRepository struct {
-	relDB RelationalDataBase
-	kvDB  KeyValueDatabse
-	log   *slog.Logger
+	relDB      RelationalDataBase
+	kvDB       KeyValueDatabse
+	log        *slog.Logger
+	similarity config.SimilarityConfig
 }
 
 // слой репощитория - взаимодействие с Базами данных
-func NewRecomRepository(db RelationalDataBase, kvDB KeyValueDatabse, log *slog.Logger) *RecomRepository {
+func NewRecomRepository(db RelationalDataBase, kvDB KeyValueDatabse, log *slog.Logger, similarity config.SimilarityConfig) *RecomRepository {
 	return &RecomRepository{
-		relDB: db,
-		kvDB:  kvDB,
-		log:   log,
+		relDB:      db,
+		kvDB:       kvDB,
+		log:        log,
+		similarity: similarity,
 	}
 }
 
-func (r *RecomRepository) GetRecommendations(ctx context.Context, userId int) ([]int, error) {
+// рекомендации пользователя по убыванию оценки: из кэша или из реляционной базы
+func (r *RecomRepository) GetRecommendations(ctx context.Context, userId int) ([]entities.Recommendation, error) {
 	fi := "repository.RecomRepository.GetRecommendations"
 
 	//проверяем есть ли в кэше продукты для пользователя
-	prodictIds, err := r.kvDB.GetRecom(ctx, userId)
+	recommendations, err := r.kvDB.GetRecom(ctx, userId)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 	}
-	//если есть возращаем
-	if prodictIds != nil {
-		r.log.Info(fmt.Sprintf("%s: Recom Get From redis UserID %d, Recommendations %v", fi, userId, prodictIds))
-		return prodictIds, nil
+	//если есть возращаем, выдача без объяснений закэширована до их появления и считается заново
+	if recommendations != nil && entities.Explained(recommendations) {
+		r.log.Info(fmt.Sprintf("%s: Recom Get From redis UserID %d, Recommendations %v", fi, userId, recommendations))
+		return recommendations, nil
+	}
+
+	//до обращения в Базу пользователь записывается в обратный индекс своих интересов,
+	//чтобы сброс кэша во время расчета не дал сохранить устаревшие рекомендации
+	keyWords, err := r.relDB.GetUserKeyWords(ctx, userId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+	version, err := r.kvDB.WatchRecom(ctx, userId, keyWords)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
 	}
 
 	//если в кэше нет, обращаемся в Базу
-	prodictIds, err = r.relDB.GetProductsByUserId(ctx, userId)
+	recommendations, err = r.relDB.GetProductsByUserId(ctx, userId)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return nil, err
 	}
-	result := removeDuplicates(prodictIds)
 
-	//добавляем в кэш полученную из реляцонной базы информацию
-	err = r.kvDB.SetRecom(ctx, userId, result)
+	//к выдаче по ключевым словам добавляются соседи последних продуктов пользователя
+	if r.similarity.Weight > 0 && r.similarity.RecentItems > 0 {
+		neighbours, err := r.relDB.GetNeighbourProducts(ctx, userId, r.similarity.RecentItems)
+		if err != nil {
+			r.log.Error(fi + ": " + err.Error())
+			return nil, err
+		}
+		recommendations = entities.BlendRecommendations(recommendations, neighbours, r.similarity.Weight)
+	}
+
+	//добавляем в кэш полученную из реляцонной базы информацию, если ее версия не устарела
+	err = r.kvDB.SetRecom(ctx, userId, version, recommendations)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return nil, err
 	}
 	r.log.Info("%s: Recom GetFrom Postgres", fi, fi)
-	return result, nil
+	return recommendations, nil
+}
+
+// популярные продукты для пользователя без персональных рекомендаций. Рейтинги уже посчитаны
+// задачей, поэтому выдача не кэшируется - так она не устаревает при отзывах и изменениях продуктов
+func (r *RecomRepository) GetPopularRecommendations(ctx context.Context, userId int) ([]entities.Recommendation, error) {
+	fi := "repository.RecomRepository.GetPopularRecommendations"
+
+	recommendations, err := r.relDB.GetPopularProducts(ctx, userId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return recommendations, nil
 }
 
+// сохранение продукта, из кэша удаляются рекомендации только тех пользователей,
+// у которых есть общие ключевые слова с продуктом до или после изменения
 func (r *RecomRepository) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error {
 	fi := "repository.RecomRepository.AddProductUpdate"
 
-	err := r.relDB.AddProductUpdate(ctx, product)
+	keyWords, err := r.relDB.AddProductUpdate(ctx, product)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return err
 	}
-	err = r.kvDB.DelAll(ctx)
+	err = r.kvDB.DelKeyWordsRecom(ctx, keyWords)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return err
@@ -92,34 +149,117 @@ func (r *RecomRepository) AddProductUpdate(ctx context.Context, product *myproto
 	return nil
 }
 
-func (r *RecomRepository) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
-	fi := "repository.RecomRepository.AddUserUpdate"
+// удаление продукта, из кэша удаляются рекомендации пользователей, которым он мог рекомендоваться
+func (r *RecomRepository) DeleteProduct(ctx context.Context, productId int) error {
+	fi := "repository.RecomRepository.DeleteProduct"
 
-	err := r.relDB.AddUserUpdate(ctx, user)
+	keyWords, err := r.relDB.DeleteProduct(ctx, productId)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+	err = r.kvDB.DelKeyWordsRecom(ctx, keyWords)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return err
 	}
 
-	//удаляем информацию о рекомендациях пользователя из кэша
-	err = r.kvDB.DelRecom(ctx, int(user.UserId))
+	return nil
+}
+
+// кэш пользователя сбрасывается, только если взаимодействие добавило продукт в его последние продукты -
+// от них зависят соседи в выдаче. Повторные просмотры и клики кэш не сбрасывают
+func (r *RecomRepository) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error {
+	fi := "repository.RecomRepository.AddInteraction"
+
+	//без коллаборативной фильтрации взаимодействия не влияют на выдачу
+	recentItems := r.similarity.RecentItems
+	if r.similarity.Weight <= 0 {
+		recentItems = 0
+	}
+
+	changed, err := r.relDB.AddInteraction(ctx, interaction, eventId, recentItems)
 	if err != nil {
 		r.log.Error(fi + ": " + err.Error())
 		return err
 	}
+	if !changed {
+		return nil
+	}
+
+	if err := r.kvDB.DelRecom(ctx, int(interaction.UserId)); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// отзыв пользователя меняет его выдачу, поэтому его кэш сбрасывается
+func (r *RecomRepository) AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error {
+	fi := "repository.RecomRepository.AddFeedback"
+
+	if err := r.relDB.AddFeedback(ctx, userId, feedback); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+
+	if err := r.kvDB.DelRecom(ctx, userId); err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+
 	return nil
 }
 
-func removeDuplicates(slice []int) []int {
-	keys := make(map[int]bool)
-	var result []int
+// пересчет матрицы похожих продуктов, закэшированные рекомендации обновятся по истечении TTL
+func (r *RecomRepository) RebuildItemSimilarity(ctx context.Context, minSupport, topK int) (int, error) {
+	fi := "repository.RecomRepository.RebuildItemSimilarity"
 
-	for _, value := range slice {
-		if _, exists := keys[value]; !exists {
-			keys[value] = true
-			result = append(result, value)
-		}
+	pairs, err := r.relDB.RebuildItemSimilarity(ctx, minSupport, topK)
+	if errors.Is(err, ErrRebuildLocked) {
+		r.log.Info(fi + ": " + err.Error())
+		return 0, err
+	}
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return 0, err
+	}
+
+	return pairs, nil
+}
+
+// пересчет рейтингов популярности
+func (r *RecomRepository) RebuildPopularity(ctx context.Context, topK int) (int, error) {
+	fi := "repository.RecomRepository.RebuildPopularity"
+
+	products, err := r.relDB.RebuildPopularity(ctx, topK)
+	if errors.Is(err, ErrRebuildLocked) {
+		r.log.Info(fi + ": " + err.Error())
+		return 0, err
+	}
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return 0, err
 	}
 
-	return result
+	return products, nil
+}
+
+func (r *RecomRepository) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
+	fi := "repository.RecomRepository.AddUserUpdate"
+
+	err := r.relDB.AddUserUpdate(ctx, user)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+
+	//удаляем информацию о рекомендациях пользователя из кэша
+	err = r.kvDB.DelRecom(ctx, int(user.UserId))
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return err
+	}
+	return nil
 }
@@ -7,7 +7,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,20 +19,29 @@ import (
 // Моки:
 type MockRelDB struct{}
 
-func (m *MockRelDB) GetProductsByUserId(ctx context.Context, userId int) ([]int, error) {
+func (m *MockRelDB) GetProductsByUserId(ctx context.Context, userId int) ([]entities.Recommendation, error) {
 	if userId == 4 {
 		return nil, errors.New("some rel error")
 	}
-	return []int{1, 2, 2, 3}, nil
+	return []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}}, nil
 }
-func (m *MockRelDB) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error {
+func (m *MockRelDB) GetUserKeyWords(ctx context.Context, userId int) ([]string, error) {
+	return []string{"рыба"}, nil
+}
+func (m *MockRelDB) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) ([]string, error) {
 	if product.Action == "ok" {
-		return nil
+		return []string{"рыба"}, nil
 	} else {
-		return errors.New("some rel error")
+		return nil, errors.New("some rel error")
 	}
 
 }
+func (m *MockRelDB) DeleteProduct(ctx context.Context, productId int) ([]string, error) {
+	if productId == 4 {
+		return nil, errors.New("some rel error")
+	}
+	return []string{"рыба"}, nil
+}
 func (m *MockRelDB) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
 	if user.UserInterests[0] == "ok" {
 		return nil
@@ -39,11 +50,63 @@ func (m *MockRelDB) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate)
 	}
 }
 
+// продукт 2 уже среди последних продуктов пользователя
+func (m *MockRelDB) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string, recentItems int) (bool, error) {
+	if interaction.UserId == 4 {
+		return false, errors.New("some rel error")
+	}
+	return recentItems > 0 && interaction.ProductId != 2, nil
+}
+
+func (m *MockRelDB) GetNeighbourProducts(ctx context.Context, userId int, recentItems int) ([]entities.Recommendation, error) {
+	if userId == 7 {
+		return nil, errors.New("some rel error")
+	}
+	return []entities.Recommendation{{ProductId: 4, Score: 0.5}, {ProductId: 1, Score: 0.5}}, nil
+}
+func (m *MockRelDB) RebuildItemSimilarity(ctx context.Context, minSupport, topK int) (int, error) {
+	if topK == 0 {
+		return 0, errors.New("some rel error")
+	}
+	if topK < 0 {
+		return 0, ErrRebuildLocked
+	}
+	return 10, nil
+}
+
+func (m *MockRelDB) AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error {
+	if userId == 4 {
+		return errors.New("some rel error")
+	}
+	return nil
+}
+
+func (m *MockRelDB) GetPopularProducts(ctx context.Context, userId int) ([]entities.Recommendation, error) {
+	if userId == 4 {
+		return nil, errors.New("some rel error")
+	}
+	return []entities.Recommendation{{ProductId: 5, Score: 12.5}, {ProductId: 2, Score: 3.25}}, nil
+}
+func (m *MockRelDB) RebuildPopularity(ctx context.Context, topK int) (int, error) {
+	if topK == 0 {
+		return 0, errors.New("some rel error")
+	}
+	if topK < 0 {
+		return 0, ErrRebuildLocked
+	}
+	return 30, nil
+}
+
 type MockKVDB struct{}
 
-func (m *MockKVDB) GetRecom(ctx context.Context, userId int) ([]int, error) {
+func (m *MockKVDB) GetRecom(ctx context.Context, userId int) ([]entities.Recommendation, error) {
 	if userId == 1 {
-		return []int{1, 2, 3}, nil
+		return []entities.Recommendation{
+			{ProductId: 1, Score: 3, Explanation: entities.KeyWordsExplanation(3, []string{"рыба"})},
+			{ProductId: 2, Score: 2, Explanation: entities.KeyWordsExplanation(2, []string{"рыба"})},
+		}, nil
+	} else if userId == 8 {
+		return []entities.Recommendation{{ProductId: 9, Score: 1}}, nil
 	} else if userId == 2 {
 		return nil, nil
 	} else if userId == 3 {
@@ -52,7 +115,14 @@ func (m *MockKVDB) GetRecom(ctx context.Context, userId int) ([]int, error) {
 	return nil, nil
 }
 
-func (m *MockKVDB) SetRecom(ctx context.Context, userId int, productIds []int) error {
+func (m *MockKVDB) WatchRecom(ctx context.Context, userId int, keyWords []string) (int64, error) {
+	if userId == 9 {
+		return 0, errors.New("some KV error")
+	}
+	return 1, nil
+}
+
+func (m *MockKVDB) SetRecom(ctx context.Context, userId int, version int64, recommendations []entities.Recommendation) error {
 	if userId == 5 {
 		return errors.New("some KV error")
 	}
@@ -66,7 +136,7 @@ func (m *MockKVDB) DelRecom(ctx context.Context, userId int) error {
 	return nil
 }
 
-func (m *MockKVDB) DelAll(ctx context.Context) error {
+func (m *MockKVDB) DelKeyWordsRecom(ctx context.Context, keyWords []string) error {
 	if ctx.Value([4]string{"code"}) == 1 {
 		return errors.New("some KV error")
 	}
@@ -78,17 +148,30 @@ func TestRecomRepository_GetRecommendations_CorrectGetFromKV(t *testing.T) {
 
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	products, err := r.GetRecommendations(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.NotNil(t, products)
+	assert.Equal(t, []int{1, 2}, entities.ProductIds(products))
+}
+
+func TestRecomRepository_GetRecommendations_CorrectStaleKV(t *testing.T) {
+
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	// выдача, закэшированная без объяснений, считается заново
+	products, err := r.GetRecommendations(context.Background(), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, entities.ProductIds(products))
 }
 
 func TestRecomRepository_GetRecommendations_CorrectGetFromRelDB(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	products, err := r.GetRecommendations(context.Background(), 2)
 	assert.NoError(t, err)
@@ -98,7 +181,7 @@ func TestRecomRepository_GetRecommendations_CorrectGetFromRelDB(t *testing.T) {
 func TestRecomRepository_GetRecommendations_KVError(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	products, err := r.GetRecommendations(context.Background(), 3)
 	assert.NoError(t, err)
@@ -108,7 +191,7 @@ func TestRecomRepository_GetRecommendations_KVError(t *testing.T) {
 func TestRecomRepository_GetRecommendations_RelError(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	products, err := r.GetRecommendations(context.Background(), 4)
 	assert.Error(t, err)
@@ -118,17 +201,27 @@ func TestRecomRepository_GetRecommendations_RelError(t *testing.T) {
 func TestRecomRepository_GetRecomendations_KVErrorSet(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	products, err := r.GetRecommendations(context.Background(), 5)
 	assert.Error(t, err)
 	assert.Nil(t, products)
 }
 
+func TestRecomRepository_GetRecomendations_KVErrorWatch(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	products, err := r.GetRecommendations(context.Background(), 9)
+	assert.Error(t, err)
+	assert.Nil(t, products)
+}
+
 func TestRecomRepository_AddProductUpdate_Correct(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	err := r.AddProductUpdate(context.Background(), &myproto.ProductAction{
 		Action: "ok",
@@ -140,7 +233,7 @@ func TestRecomRepository_AddProductUpdate_Correct(t *testing.T) {
 func TestRecomRepository_AddProductUpdate_Incorrect(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	err := r.AddProductUpdate(context.Background(), &myproto.ProductAction{
 		Action: "error",
@@ -152,7 +245,7 @@ func TestRecomRepository_AddProductUpdate_Incorrect(t *testing.T) {
 func TestRecomRepository_AddProductUpdate_IncorrectErrorFromKvV(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 	err := r.AddProductUpdate(context.WithValue(context.Background(), [4]string{"code"}, 1), &myproto.ProductAction{
 		Action: "ok",
 	})
@@ -160,10 +253,40 @@ func TestRecomRepository_AddProductUpdate_IncorrectErrorFromKvV(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestRecomRepository_DeleteProduct_Correct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.DeleteProduct(context.Background(), 1)
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_DeleteProduct_Incorrect(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.DeleteProduct(context.Background(), 4)
+
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_DeleteProduct_IncorrectErrorFromKV(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.DeleteProduct(context.WithValue(context.Background(), [4]string{"code"}, 1), 1)
+
+	assert.Error(t, err)
+}
+
 func TestRecomRepository_AddUserUpdate_Correct(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	err := r.AddUserUpdate(context.Background(), &myproto.UserUpdate{
 		UserInterests: []string{"ok"},
@@ -175,7 +298,7 @@ func TestRecomRepository_AddUserUpdate_Correct(t *testing.T) {
 func TestRecomRepository_AddUserUpdate_Incorrect(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	err := r.AddUserUpdate(context.Background(), &myproto.UserUpdate{
 		UserInterests: []string{"error"},
@@ -187,7 +310,7 @@ func TestRecomRepository_AddUserUpdate_Incorrect(t *testing.T) {
 func TestRecomRepository_AddUserUpdate_IncorrectKVError(t *testing.T) {
 	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
 		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-	))
+	), config.SimilarityConfig{})
 
 	err := r.AddUserUpdate(context.Background(), &myproto.UserUpdate{
 		UserId:        6,
@@ -196,3 +319,196 @@ func TestRecomRepository_AddUserUpdate_IncorrectKVError(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestRecomRepository_AddInteraction_Correct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 1, ProductId: 1}, "1")
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_AddInteraction_Incorrect(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 4, ProductId: 1}, "")
+
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_AddInteraction_IncorrectKVError(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{RecentItems: 10, Weight: 1})
+
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 6, ProductId: 1}, "")
+
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_AddInteraction_CorrectRecentProduct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{RecentItems: 10, Weight: 1})
+
+	// продукт уже среди последних, кэш не сбрасывается - ошибки redis нет
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 6, ProductId: 2}, "")
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_AddInteraction_CorrectWithoutSimilarity(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{RecentItems: 10})
+
+	// без коллаборативной фильтрации взаимодействие не меняет выдачу и кэш не сбрасывается
+	err := r.AddInteraction(context.Background(), &myproto.Interaction{UserId: 6, ProductId: 1}, "")
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_GetRecommendations_CorrectBlended(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{RecentItems: 10, Weight: 2})
+
+	// соседи последних продуктов добавляются к оценке по ключевым словам с весом
+	products, err := r.GetRecommendations(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Recommendation{
+		{ProductId: 1, Score: 4, Explanation: &entities.Explanation{
+			Strategy:        entities.StrategyBlended,
+			MatchedKeyWords: []string{},
+			Components:      entities.ScoreComponents{KeyWords: 3, Collaborative: 1},
+		}},
+		{ProductId: 2, Score: 2, Explanation: entities.KeyWordsExplanation(2, nil)},
+		{ProductId: 3, Score: 1, Explanation: entities.KeyWordsExplanation(1, nil)},
+		{ProductId: 4, Score: 1, Explanation: &entities.Explanation{
+			Strategy:        entities.StrategyCollaborative,
+			MatchedKeyWords: []string{},
+			Components:      entities.ScoreComponents{Collaborative: 1},
+		}},
+	}, products)
+}
+
+func TestRecomRepository_GetRecommendations_NeighboursError(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{RecentItems: 10, Weight: 1})
+
+	products, err := r.GetRecommendations(context.Background(), 7)
+	assert.Error(t, err)
+	assert.Nil(t, products)
+}
+
+func TestRecomRepository_RebuildItemSimilarity_Correct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	pairs, err := r.RebuildItemSimilarity(context.Background(), 2, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, pairs)
+}
+
+func TestRecomRepository_RebuildItemSimilarity_Incorrect(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	_, err := r.RebuildItemSimilarity(context.Background(), 2, 0)
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_RebuildItemSimilarity_Locked(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	_, err := r.RebuildItemSimilarity(context.Background(), 2, -1)
+	assert.ErrorIs(t, err, ErrRebuildLocked)
+}
+
+func TestRecomRepository_AddFeedback_Correct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.AddFeedback(context.Background(), 1, &entities.Feedback{ProductId: 1, Reason: entities.FeedbackNotInterested})
+
+	assert.NoError(t, err)
+}
+
+func TestRecomRepository_AddFeedback_Incorrect(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	err := r.AddFeedback(context.Background(), 4, &entities.Feedback{ProductId: 1, Reason: entities.FeedbackNotInterested})
+
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_AddFeedback_IncorrectKVError(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	// кэш пользователя не сброшен - ошибка, иначе отклоненный продукт останется в выдаче
+	err := r.AddFeedback(context.Background(), 6, &entities.Feedback{ProductId: 1, Reason: entities.FeedbackOffensive})
+
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_GetPopularRecommendations_Correct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	recom, err := r.GetPopularRecommendations(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Recommendation{{ProductId: 5, Score: 12.5}, {ProductId: 2, Score: 3.25}}, recom)
+}
+
+func TestRecomRepository_GetPopularRecommendations_Incorrect(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	recom, err := r.GetPopularRecommendations(context.Background(), 4)
+	assert.Error(t, err)
+	assert.Nil(t, recom)
+}
+
+func TestRecomRepository_RebuildPopularity_Correct(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	products, err := r.RebuildPopularity(context.Background(), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, products)
+}
+
+func TestRecomRepository_RebuildPopularity_Incorrect(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	_, err := r.RebuildPopularity(context.Background(), 0)
+	assert.Error(t, err)
+}
+
+func TestRecomRepository_RebuildPopularity_Locked(t *testing.T) {
+	r := NewRecomRepository(&MockRelDB{}, &MockKVDB{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	), config.SimilarityConfig{})
+
+	_, err := r.RebuildPopularity(context.Background(), -1)
+	assert.ErrorIs(t, err, ErrRebuildLocked)
+}
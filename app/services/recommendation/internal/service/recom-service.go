@@ -2,42 +2,136 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/IBM/sarama"
 	"google.golang.org/protobuf/proto"
 )
 
 // имплементация интерфейса Service
 type RecommendationService struct {
-	repo repository.Repository
-	log  *slog.Logger
+	repo      repository.Repository
+	log       *slog.Logger
+	diversity config.DiversityConfig
 }
 
-func NewRecommendationService(repo repository.Repository, log *slog.Logger) *RecommendationService {
+// степень разнообразия из конфигурации ограничивается отрезком [0, 1],
+// пустое число переранжируемых продуктов заменяется значением по умолчанию
+func NewRecommendationService(repo repository.Repository, log *slog.Logger, diversity config.DiversityConfig) *RecommendationService {
+	diversity.Factor = min(max(diversity.Factor, 0), 1)
+	if diversity.Window <= 0 {
+		diversity.Window = entities.DefaultDiversityWindow
+	}
+
 	return &RecommendationService{
-		repo: repo,
-		log:  log,
+		repo:      repo,
+		log:       log,
+		diversity: diversity,
 	}
 }
 
-// функция вызывает метод репозитория по добавлению нового продукта
-func (s *RecommendationService) GetRecommendations(ctx context.Context, userId int) ([]int, error) {
+// функция возвращает страницу рекомендаций пользователя по убыванию оценки, nil - рекомендаций нет.
+// Полный список берется из кэша, страница вырезается из него без повторного расчета.
+// Пользователю без персональных рекомендаций (нет интересов, интересы ни с чем не совпали
+// или пользователь еще неизвестен сервису) возвращается рейтинг популярности с источником popular
+func (s *RecommendationService) GetRecommendations(ctx context.Context, userId int, query *entities.RecommendationQuery) (*entities.RecommendationPage, error) {
 	fi := "service.RecommendationService.GetRecommendations"
-	productIds, err := s.repo.GetRecommendations(ctx, userId)
-	if err != nil {
+	source := entities.SourcePersonal
+	recommendations, err := s.repo.GetRecommendations(ctx, userId)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
 		s.log.Error("%s: Error trying get product ids: %v", fi, err)
 		return nil, err
 	}
 	s.log.Info("%s: Got request about user with id %d", fi, userId)
-	if len(productIds) == 0 {
-		productIds = nil
+
+	if len(recommendations) == 0 {
+		source = entities.SourcePopular
+		recommendations, err = s.repo.GetPopularRecommendations(ctx, userId)
+		if err != nil {
+			s.log.Error("%s: Error trying get popular products: %v", fi, err)
+			return nil, err
+		}
+	}
+	if len(recommendations) == 0 {
+		return nil, nil
+	}
+
+	//переранжирование по разнообразию ключевых слов продуктов из выдачи, порядок детерминирован - страницы согласованы.
+	//Следующие страницы переранжируются с разнообразием из курсора, даже если конфигурация изменилась
+	diversity := s.diversity.Factor
+	if query.Diversity != nil {
+		diversity = *query.Diversity
+	} else if query.After != nil && query.After.Diversity != nil {
+		diversity = *query.After.Diversity
+	}
+	recommendations = entities.DiversifyRecommendations(recommendations, diversity, s.diversity.Window)
+
+	//объяснения посчитаны вместе с оценками и хранятся в кэше, в ответ попадают только по запросу
+	page := pageRecommendations(recommendations, query, diversity)
+	page.Source = source
+	page.Recommendations = entities.WithoutKeyWords(page.Recommendations)
+	if !query.Explain {
+		page.Recommendations = entities.WithoutExplanations(page.Recommendations)
+	}
+	return page, nil
+}
+
+// отзыв пользователя на продукт: продукт исключается из его выдачи
+func (s *RecommendationService) AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error {
+	fi := "service.RecommendationService.AddFeedback"
+
+	if err := s.repo.AddFeedback(ctx, userId, feedback); err != nil {
+		s.log.Error("%s: Error trying add feedback: %v", fi, err)
+		return err
+	}
+
+	return nil
+}
+
+// страница выдачи после курсора или смещения, diversity - разнообразие, с которым переранжирована выдача.
+// Если продукта из курсора уже нет в выдаче, страница начинается с первого продукта, который стоял бы
+// после него при сортировке по оценке. Переранжированная выдача по оценке не упорядочена, поэтому
+// в ней страница начинается с позиции из курсора
+func pageRecommendations(recommendations []entities.Recommendation, query *entities.RecommendationQuery, diversity float64) *entities.RecommendationPage {
+	start := min(query.Offset, len(recommendations))
+	if after := query.After; after != nil {
+		start = afterCursor(recommendations, after, diversity)
+	}
+	end := start + min(query.Limit, len(recommendations)-start)
+
+	page := &entities.RecommendationPage{Recommendations: recommendations[start:end]}
+	if end < len(recommendations) && end > start {
+		page.NextCursor = entities.EncodeRecommendationCursor(recommendations[end-1], end, diversity)
+	}
+	return page
+}
+
+// индекс первого продукта после курсора
+func afterCursor(recommendations []entities.Recommendation, after *entities.RecommendationCursor, diversity float64) int {
+	for i, recommendation := range recommendations {
+		if recommendation.ProductId == after.Last.ProductId {
+			return i + 1
+		}
 	}
 
-	return productIds, nil
+	if diversity > 0 && after.Position > 0 {
+		return min(after.Position, len(recommendations))
+	}
+
+	for i, recommendation := range recommendations {
+		if recommendation.Score < after.Last.Score ||
+			recommendation.Score == after.Last.Score && recommendation.ProductId > after.Last.ProductId {
+			return i
+		}
+	}
+	return len(recommendations)
 }
 
 func (s *RecommendationService) AddProductData(ctx context.Context, msg *sarama.ConsumerMessage) error {
@@ -54,7 +148,16 @@ func (s *RecommendationService) AddProductData(ctx context.Context, msg *sarama.
 		fmt.Sprintf("%s: Got user id %d, user interests %v", fi, product.ProductId, product.ProductKeyWords),
 	)
 
-	//отправляем структуру в бд
+	//удаленный продукт больше не рекомендуется
+	if entities.ProductActionType(&product) == myproto.Action_DELETED {
+		if err := s.repo.DeleteProduct(ctx, int(product.ProductId)); err != nil {
+			s.log.Error("%s: Error trying delete product data: %v", fi, err)
+			return err
+		}
+		return nil
+	}
+
+	//отправляем структуру в бд, неактивный продукт сохраняется, но не рекомендуется
 	if err := s.repo.AddProductUpdate(ctx, &product); err != nil {
 		s.log.Error("%s: Error trying add product data: %v", fi, err)
 		return err
@@ -91,3 +194,32 @@ func (s *RecommendationService) AddUserData(ctx context.Context, msg *sarama.Con
 
 	return nil
 }
+
+func (s *RecommendationService) AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	fi := "service.RecommendationService.AddInteractionData"
+	var interaction myproto.Interaction
+
+	//из слайса байт в структуру
+	if err := proto.Unmarshal(msg.Value, &interaction); err != nil {
+		s.log.Error("%s: Error trying Unmarshal interaction data: %v", fi, err)
+		return err
+	}
+
+	s.log.Info(
+		fmt.Sprintf("%s: Got interaction %s of user %d with product %d", fi, interaction.Type, interaction.UserId, interaction.ProductId),
+	)
+
+	//некорректное событие пропускается, чтобы не останавливать чтение топика
+	if err := entities.ValidateInteraction(&interaction); err != nil {
+		s.log.Error("%s: Skip invalid interaction: %v", fi, err)
+		return nil
+	}
+
+	//отправляем структуру в бд вместе с id события - повторно прочитанное событие не сохраняется
+	if err := s.repo.AddInteraction(ctx, &interaction, envelope.FromMessage(msg).EventId); err != nil {
+		s.log.Error("%s: Error trying add interaction data: %v", fi, err)
+		return err
+	}
+
+	return nil
+}
@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/pb"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
@@ -22,22 +27,47 @@ import (
 
 type MockRepository struct{}
 
-func (m *MockRepository) GetRecommendations(ctx context.Context, userId int) ([]int, error) {
+func (m *MockRepository) GetRecommendations(ctx context.Context, userId int) ([]entities.Recommendation, error) {
 	if userId == 1 {
 		return nil, errors.New("some error")
 	} else if userId == 2 {
-		return []int{1, 2, 3}, nil
+		return []entities.Recommendation{
+			{ProductId: 1, Score: 3, KeyWords: []string{"рыба", "удочки"}},
+			{ProductId: 2, Score: 2, KeyWords: []string{"рыба", "удочки"}},
+			{ProductId: 3, Score: 1, KeyWords: []string{"машины"}},
+		}, nil
+	} else if userId == 7 {
+		return []entities.Recommendation{
+			{ProductId: 1, Score: 3, Explanation: entities.KeyWordsExplanation(3, []string{"рыба", "удочки"})},
+		}, nil
+	} else if userId == 4 {
+		return nil, repository.ErrNotFound
 	} else {
-		return []int{}, nil
+		return []entities.Recommendation{}, nil
 	}
 }
 
+func (m *MockRepository) GetPopularRecommendations(ctx context.Context, userId int) ([]entities.Recommendation, error) {
+	if userId == 3 {
+		return []entities.Recommendation{}, nil
+	} else if userId == 6 {
+		return nil, errors.New("some error")
+	}
+	return []entities.Recommendation{{ProductId: 5, Score: 12.5}, {ProductId: 2, Score: 3.25}}, nil
+}
+
 func (m *MockRepository) AddProductUpdate(ctx context.Context, product *myproto.ProductAction) error {
 	if product.Action == "error" {
 		return errors.New("some error")
 	}
 	return nil
 }
+func (m *MockRepository) DeleteProduct(ctx context.Context, productId int) error {
+	if productId == 2 {
+		return errors.New("some error")
+	}
+	return nil
+}
 func (m *MockRepository) AddUserUpdate(ctx context.Context, user *myproto.UserUpdate) error {
 	if user.UserInterests[0] == "error" {
 		return errors.New("some error")
@@ -45,15 +75,30 @@ func (m *MockRepository) AddUserUpdate(ctx context.Context, user *myproto.UserUp
 	return nil
 }
 
+func (m *MockRepository) AddInteraction(ctx context.Context, interaction *myproto.Interaction, eventId string) error {
+	if interaction.SessionId == "error" || eventId == "error" {
+		return errors.New("some error")
+	}
+	return nil
+}
+
+func (m *MockRepository) AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error {
+	if userId == 1 {
+		return errors.New("some error")
+	}
+	return nil
+}
+
 func TestRecommendationService_GetRecommendations_Correct(t *testing.T) {
 	service := NewRecommendationService(
 		&MockRepository{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
-	res, err := service.GetRecommendations(context.Background(), 2)
+	res, err := service.GetRecommendations(context.Background(), 2, &entities.RecommendationQuery{Limit: 20})
 
 	assert.NotNil(t, res)
 	assert.NoError(t, err)
@@ -65,9 +110,10 @@ func TestRecommendationService_GetRecommendations_Incorrect(t *testing.T) {
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
-	res, err := service.GetRecommendations(context.Background(), 1)
+	res, err := service.GetRecommendations(context.Background(), 1, &entities.RecommendationQuery{Limit: 20})
 
 	assert.Nil(t, res)
 	assert.Error(t, err)
@@ -79,20 +125,177 @@ func TestRecommendationService_GetRecommendations_CorrectButNoRecommendation(t *
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
-	res, err := service.GetRecommendations(context.Background(), 3)
+	res, err := service.GetRecommendations(context.Background(), 3, &entities.RecommendationQuery{Limit: 20})
 
 	assert.Nil(t, res)
 	assert.NoError(t, err)
 }
 
+func TestRecommendationService_GetRecommendations_Source(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	popular := []entities.Recommendation{{ProductId: 5, Score: 12.5}, {ProductId: 2, Score: 3.25}}
+
+	tests := []struct {
+		name            string
+		userId          int
+		source          string
+		recommendations []entities.Recommendation
+		wantErr         bool
+	}{
+		{"Personal", 2, entities.SourcePersonal,
+			[]entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}}, false},
+		// интересы пользователя ни с чем не совпали
+		{"PopularNoMatches", 5, entities.SourcePopular, popular, false},
+		// пользователь еще неизвестен сервису
+		{"PopularUnknownUser", 4, entities.SourcePopular, popular, false},
+		{"PopularError", 6, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.GetRecommendations(context.Background(), tt.userId, &entities.RecommendationQuery{Limit: 20})
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, res)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.source, res.Source)
+			assert.Equal(t, tt.recommendations, res.Recommendations)
+		})
+	}
+}
+
+func TestRecommendationService_GetRecommendations_Diversity(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{Factor: 0.5},
+	)
+
+	diversity := func(value float64) *float64 { return &value }
+
+	tests := []struct {
+		name       string
+		userId     int
+		diversity  *float64
+		productIds []int
+	}{
+		// продукт 2 с теми же ключевыми словами, что и продукт 1, опускается ниже продукта 3
+		{"FromConfig", 2, nil, []int{1, 3, 2}},
+		{"FromQuery", 2, diversity(1), []int{1, 3, 2}},
+		{"DisabledInQuery", 2, diversity(0), []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.GetRecommendations(context.Background(), tt.userId,
+				&entities.RecommendationQuery{Limit: 20, Diversity: tt.diversity})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.productIds, entities.ProductIds(res.Recommendations))
+			// ключевые слова из кэша в ответ не попадают
+			for _, recommendation := range res.Recommendations {
+				assert.Nil(t, recommendation.KeyWords)
+			}
+		})
+	}
+}
+
+func TestRecommendationService_GetRecommendations_CursorDiversity(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	// первая страница с разнообразием из запроса, следующая - с разнообразием из курсора
+	diversity := 1.0
+	res, err := service.GetRecommendations(context.Background(), 2,
+		&entities.RecommendationQuery{Limit: 2, Diversity: &diversity})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, entities.ProductIds(res.Recommendations))
+
+	query := &entities.RecommendationQuery{Limit: 2, Cursor: res.NextCursor}
+	assert.NoError(t, query.ValidateRecommendationQuery(2, 2))
+	res, err = service.GetRecommendations(context.Background(), 2, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, entities.ProductIds(res.Recommendations))
+}
+
+func TestDecodeRecommendationCursor(t *testing.T) {
+	diversity := 0.5
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    *entities.RecommendationCursor
+		wantErr bool
+	}{
+		{"Correct", entities.EncodeRecommendationCursor(entities.Recommendation{ProductId: 3, Score: 1.5}, 4, 0.5),
+			&entities.RecommendationCursor{Last: entities.Recommendation{ProductId: 3, Score: 1.5}, Position: 4, Diversity: &diversity}, false},
+		// курсор прежнего формата - оценка и id
+		{"CorrectOld", "MjoyMw", &entities.RecommendationCursor{Last: entities.Recommendation{ProductId: 23, Score: 2}}, false},
+		{"IncorrectPosition", "MjoyMzowOjA", nil, true},
+		{"IncorrectDiversity", "MjoyMzoyOjI", nil, true},
+		{"Incorrect", "kot", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, err := entities.DecodeRecommendationCursor(tt.cursor)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, after)
+		})
+	}
+}
+
+func TestRecommendationService_GetRecommendations_Explain(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	res, err := service.GetRecommendations(context.Background(), 7, &entities.RecommendationQuery{Limit: 20, Explain: true})
+	assert.NoError(t, err)
+	assert.Equal(t, entities.KeyWordsExplanation(3, []string{"рыба", "удочки"}), res.Recommendations[0].Explanation)
+
+	// без explain объяснения не возвращаются
+	res, err = service.GetRecommendations(context.Background(), 7, &entities.RecommendationQuery{Limit: 20})
+	assert.NoError(t, err)
+	assert.Nil(t, res.Recommendations[0].Explanation)
+}
+
 func TestRecommendationService_AddProductUpdate_Correct(t *testing.T) {
 	service := NewRecommendationService(
 		&MockRepository{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
 	var message myproto.ProductAction = myproto.ProductAction{
@@ -118,6 +321,7 @@ func TestRecommendationService_AddProductUpdate_Incorrect(t *testing.T) {
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
 	var message myproto.ProductAction = myproto.ProductAction{
@@ -143,6 +347,7 @@ func TestRecommendationService_AddProductUpdate_IncorrectErrorONRepoLevel(t *tes
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
 	var message myproto.ProductAction = myproto.ProductAction{
@@ -162,12 +367,66 @@ func TestRecommendationService_AddProductUpdate_IncorrectErrorONRepoLevel(t *tes
 	assert.Error(t, err1)
 }
 
+func TestRecommendationService_AddProductUpdate_CorrectDelete(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	// удаление определяется и по actionType, и по старому action, в repo.AddProductUpdate не попадает
+	for _, message := range []*myproto.ProductAction{
+		{ProductId: 1, ActionType: myproto.Action_DELETED, Action: "error"},
+		{ProductId: 1, Action: "delete"},
+	} {
+		marshalledMessage, err := proto.Marshal(message)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err1 := service.AddProductData(context.Background(), &sarama.ConsumerMessage{
+			Topic: "test",
+			Value: sarama.ByteEncoder(marshalledMessage),
+		})
+
+		assert.NoError(t, err1)
+	}
+}
+
+func TestRecommendationService_AddProductUpdate_IncorrectDelete(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	marshalledMessage, err := proto.Marshal(&myproto.ProductAction{
+		ProductId:  2,
+		ActionType: myproto.Action_DELETED,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err1 := service.AddProductData(context.Background(), &sarama.ConsumerMessage{
+		Topic: "test",
+		Value: sarama.ByteEncoder(marshalledMessage),
+	})
+
+	assert.Error(t, err1)
+}
+
 func TestRecommendationService_AddUserUpdate_Correct(t *testing.T) {
 	service := NewRecommendationService(
 		&MockRepository{},
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
 	var message myproto.UserUpdate = myproto.UserUpdate{
@@ -194,6 +453,7 @@ func TestRecommendationService_AddUserUpdate_Incorrect(t *testing.T) {
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
 	var message myproto.UserUpdate = myproto.UserUpdate{
@@ -220,6 +480,7 @@ func TestRecommendationService_AddUserUpdate_IncorrectErrorONRepoLevel(t *testin
 		slog.New(
 			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		),
+		config.DiversityConfig{},
 	)
 
 	var message myproto.UserUpdate = myproto.UserUpdate{
@@ -238,3 +499,114 @@ func TestRecommendationService_AddUserUpdate_IncorrectErrorONRepoLevel(t *testin
 
 	assert.Error(t, err1)
 }
+
+func TestPageRecommendations(t *testing.T) {
+	recommendations := []entities.Recommendation{
+		{ProductId: 4, Score: 3}, {ProductId: 1, Score: 2}, {ProductId: 5, Score: 2}, {ProductId: 2, Score: 1},
+	}
+
+	//первая страница
+	page := pageRecommendations(recommendations, &entities.RecommendationQuery{Limit: 2}, 0)
+	assert.Equal(t, recommendations[:2], page.Recommendations)
+	assert.NotEqual(t, "", page.NextCursor)
+
+	//следующая страница по курсору - последняя
+	after, err := entities.DecodeRecommendationCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, after.Position)
+	page = pageRecommendations(recommendations, &entities.RecommendationQuery{Limit: 2, After: after}, 0)
+	assert.Equal(t, recommendations[2:], page.Recommendations)
+	assert.Equal(t, "", page.NextCursor)
+
+	//продукта из курсора уже нет в выдаче - страница с первого продукта после него по оценке
+	page = pageRecommendations(recommendations, &entities.RecommendationQuery{
+		Limit: 2, After: &entities.RecommendationCursor{Last: entities.Recommendation{ProductId: 3, Score: 2}, Position: 1},
+	}, 0)
+	assert.Equal(t, recommendations[2:], page.Recommendations)
+
+	//в переранжированной выдаче порядок не по оценке - страница с позиции из курсора
+	page = pageRecommendations(recommendations, &entities.RecommendationQuery{
+		Limit: 2, After: &entities.RecommendationCursor{Last: entities.Recommendation{ProductId: 3, Score: 2}, Position: 1},
+	}, 0.5)
+	assert.Equal(t, recommendations[1:3], page.Recommendations)
+
+	//без ограничения размера - весь список одной страницей
+	page = pageRecommendations(recommendations, &entities.RecommendationQuery{Limit: math.MaxInt}, 0)
+	assert.Equal(t, recommendations, page.Recommendations)
+	assert.Equal(t, "", page.NextCursor)
+
+	//смещение за концом выдачи - пустая страница
+	page = pageRecommendations(recommendations, &entities.RecommendationQuery{Limit: 2, Offset: 10}, 0)
+	assert.Equal(t, 0, len(page.Recommendations))
+	assert.Equal(t, "", page.NextCursor)
+}
+
+func TestRecommendationService_AddInteractionData(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	tests := []struct {
+		name        string
+		interaction *myproto.Interaction
+		wantErr     bool
+	}{
+		{"Correct", &myproto.Interaction{UserId: 1, ProductId: 2, Type: myproto.InteractionType_CLICK}, false},
+		// некорректное событие пропускается без ошибки
+		{"CorrectSkipInvalid", &myproto.Interaction{UserId: 1, ProductId: 2}, false},
+		{"IncorrectErrorONRepoLevel", &myproto.Interaction{UserId: 1, ProductId: 2, Type: myproto.InteractionType_VIEW, SessionId: "error"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marshalledMessage, err := proto.Marshal(tt.interaction)
+			if err != nil {
+				t.Error(err)
+			}
+
+			err = service.AddInteractionData(context.Background(), &sarama.ConsumerMessage{
+				Topic: "user_interactions",
+				Value: sarama.ByteEncoder(marshalledMessage),
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	err := service.AddInteractionData(context.Background(), &sarama.ConsumerMessage{
+		Topic: "user_interactions",
+		Value: []byte("not a proto"),
+	})
+	assert.Error(t, err)
+
+	// id события из заголовка конверта передается в репозиторий
+	marshalledMessage, err := proto.Marshal(&myproto.Interaction{UserId: 1, ProductId: 2, Type: myproto.InteractionType_CLICK})
+	assert.NoError(t, err)
+	err = service.AddInteractionData(context.Background(), &sarama.ConsumerMessage{
+		Topic:   "user_interactions",
+		Value:   marshalledMessage,
+		Headers: []*sarama.RecordHeader{{Key: []byte(envelope.HeaderEventId), Value: []byte("error")}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRecommendationService_AddFeedback(t *testing.T) {
+	service := NewRecommendationService(
+		&MockRepository{},
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		config.DiversityConfig{},
+	)
+
+	feedback := &entities.Feedback{ProductId: 1, Reason: entities.FeedbackNotInterested}
+	assert.NoError(t, service.AddFeedback(context.Background(), 2, feedback))
+	assert.Error(t, service.AddFeedback(context.Background(), 1, feedback))
+}
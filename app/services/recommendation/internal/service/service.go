@@ -3,19 +3,26 @@ package service
 import (
 	"context"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	"github.com/IBM/sarama"
 )
 
 type Service interface {
 	RecomGetter
+	FeedbackSaver
 	KafkaHandler
 }
 
 type RecomGetter interface {
-	GetRecommendations(ctx context.Context, userId int) ([]int, error)
+	GetRecommendations(ctx context.Context, userId int, query *entities.RecommendationQuery) (*entities.RecommendationPage, error)
+}
+
+type FeedbackSaver interface {
+	AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error
 }
 
 type KafkaHandler interface {
 	AddProductData(ctx context.Context, msg *sarama.ConsumerMessage) error
 	AddUserData(ctx context.Context, msg *sarama.ConsumerMessage) error
+	AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error
 }
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// отзыв пользователя на рекомендованный продукт (not_interested, already_owned, offensive),
+// продукт больше не рекомендуется пользователю, кэш его рекомендаций сбрасывается
+func (h *Handler) addFeedback(c *gin.Context) {
+	var feedback entities.Feedback
+	fi := "api.Handler.addFeedback"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	// ошибка 400 - Некорректный параметр userID
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, "userId parameter incorrect in path")
+		return
+	}
+
+	// ошибка 400 - ошибка валидации userId
+	if err := entities.ValidateUserId(userId); err != nil {
+		logMassage(fi, h.log, "userId validation failed"+": "+err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, "userId validation failed"+": "+err.Error())
+		return
+	}
+
+	// ошибка 400 - ошибка десериализации данных
+	if err := c.BindJSON(&feedback); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// ошибка 400 - ошибка валидации данных
+	if err := feedback.ValidateFeedback(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//404 и 500 - пользователь не найден или внутряняя ошибка сервера
+	err = h.service.AddFeedback(ctx, userId, &feedback)
+	if errors.Is(err, repository.ErrNotFound) {
+		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// успешное завершение 204
+	c.AbortWithStatus(http.StatusNoContent)
+}
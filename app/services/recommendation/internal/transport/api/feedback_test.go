@@ -0,0 +1,56 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_AddFeedback(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{},
+	)
+
+	tests := []struct {
+		name   string
+		userId string
+		body   string
+		code   int
+	}{
+		{"Correct", "1", `{"productId": 3, "reason": "not_interested"}`, http.StatusNoContent},
+		{"CorrectAlreadyOwned", "1", `{"productId": 3, "reason": "already_owned"}`, http.StatusNoContent},
+		{"INcorrectParam", "one", `{"productId": 3, "reason": "offensive"}`, http.StatusBadRequest},
+		{"INcorrectValidationParam", "-1", `{"productId": 3, "reason": "offensive"}`, http.StatusBadRequest},
+		{"INcorrectJson", "1", `{"productId": "three"}`, http.StatusBadRequest},
+		{"INcorrectMissingReason", "1", `{"productId": 3}`, http.StatusBadRequest},
+		{"INcorrectReason", "1", `{"productId": 3, "reason": "boring"}`, http.StatusBadRequest},
+		{"INcorrectProductId", "1", `{"productId": -3, "reason": "offensive"}`, http.StatusBadRequest},
+		{"CorrectButNotFoundUser", "2", `{"productId": 3, "reason": "offensive"}`, http.StatusNotFound},
+		{"CorrectButINternalServerError", "3", `{"productId": 3, "reason": "offensive"}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest("POST", "/recommendation/"+tt.userId+"/feedback", strings.NewReader(tt.body))
+			c.Params = gin.Params{
+				gin.Param{Key: "userId", Value: tt.userId},
+			}
+
+			handler.addFeedback(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
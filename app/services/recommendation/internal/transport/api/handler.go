@@ -3,25 +3,38 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/service"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
 	service service.Service
 	log     *slog.Logger
+	paging  config.PageConfig
 }
 
-func NewHandler(service service.Service, log *slog.Logger) *Handler {
+// размеры страниц, не заданные в конфигурации, берутся по умолчанию
+func NewHandler(service service.Service, log *slog.Logger, paging config.PageConfig) *Handler {
+	if paging.MaxLimit <= 0 {
+		paging.MaxLimit = entities.MaxPageLimit
+	}
+	if paging.DefaultLimit <= 0 || paging.DefaultLimit > paging.MaxLimit {
+		paging.DefaultLimit = min(entities.DefaultPageLimit, paging.MaxLimit)
+	}
+
 	return &Handler{
 		service: service,
 		log:     log,
+		paging:  paging,
 	}
 }
 
@@ -55,8 +68,56 @@ func (h *Handler) getUserRecommendations(c *gin.Context) {
 		return
 	}
 
+	// ошибка 400 - неизвестный формат ответа
+	format := c.Query("format")
+	if err := entities.ValidateFormat(format); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// ошибка 400 - некорректные параметры страницы
+	query := entities.RecommendationQuery{Cursor: c.Query("cursor")}
+	if query.Offset, err = queryInt(c, "offset"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Limit, err = queryInt(c, "limit"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Diversity, err = queryFloat(c, "diversity"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// ошибка 400 - некорректный параметр explain или explain в формате ids
+	if query.Explain, err = queryBool(c, "explain"); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.Explain && format == entities.FormatIds {
+		logMassage(fi, h.log, "explain can`t be used with format=ids", http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, "invalid query: explain can`t be used with format=ids")
+		return
+	}
+	// format=ids без limit, cursor и offset - весь список, как в прежнем формате ответа
+	unpaged := format == entities.FormatIds && query.Limit == 0 && query.Cursor == "" && query.Offset == 0
+	if err := query.ValidateRecommendationQuery(h.paging.DefaultLimit, h.paging.MaxLimit); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if unpaged {
+		query.Limit = math.MaxInt
+	}
+
 	//404 и 500 - рекомендаций нет или внутряняя ошибка сервера
-	productIds, err := h.service.GetRecommendations(ctx, userId)
+	page, err := h.service.GetRecommendations(ctx, userId, &query)
 	if errors.Is(err, repository.ErrNotFound) {
 		logMassage(fi, h.log, err.Error(), http.StatusNotFound)
 		newErrorResponse(c, http.StatusNotFound, err.Error())
@@ -65,17 +126,72 @@ func (h *Handler) getUserRecommendations(c *gin.Context) {
 		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
-	} else if productIds == nil {
+	} else if page == nil {
 		logMassage(fi, h.log, "recommendations for this user not found", http.StatusNotFound)
 		newErrorResponse(c, http.StatusNotFound, "recommendations for this user not found")
 		return
 	}
 
-	// успешное завершение 200
-	c.AbortWithStatusJSON(http.StatusOK, productIds)
+	// успешное завершение 200, format=ids - только id продуктов страницы (без параметров страницы -
+	// всего списка) в порядке убывания оценки, курсор следующей страницы и источник выдачи в этом
+	// формате передаются только в заголовках
+	if page.NextCursor != "" {
+		c.Header("X-Next-Cursor", page.NextCursor)
+	}
+	c.Header("X-Recommendation-Source", page.Source)
+	if format == entities.FormatIds {
+		c.AbortWithStatusJSON(http.StatusOK, entities.ProductIds(page.Recommendations))
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusOK, page)
 
 }
 
+// целочисленный параметр запроса, 0 если параметра нет
+func queryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s parametr incorrect: %w", name, err)
+	}
+
+	return result, nil
+}
+
 func logMassage(fi string, log *slog.Logger, msg string, code int) {
 	log.Error("Transport Level Error: " + fi + ": " + msg + "   Code : " + strconv.Itoa(code))
 }
+
+// дробный параметр запроса, nil если параметра нет
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s parametr incorrect: %w", name, err)
+	}
+
+	return &result, nil
+}
+
+// логический параметр запроса, false если параметра нет
+func queryBool(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s parametr incorrect: %w", name, err)
+	}
+
+	return result, nil
+}
@@ -10,7 +10,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/IBM/sarama"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -26,21 +28,66 @@ func (m *MockService) AddUserData(ctx context.Context, msg *sarama.ConsumerMessa
 	return nil
 }
 
-func (m *MockService) GetRecommendations(ctx context.Context, userId int) ([]int, error) {
+func (m *MockService) AddInteractionData(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	return nil
+}
+
+func (m *MockService) AddFeedback(ctx context.Context, userId int, feedback *entities.Feedback) error {
+	if userId == 2 {
+		return repository.ErrNotFound
+	} else if userId == 3 {
+		return errors.New("Ошибка сервера")
+	}
+	return nil
+}
+
+func (m *MockService) GetRecommendations(ctx context.Context, userId int, query *entities.RecommendationQuery) (*entities.RecommendationPage, error) {
 	if userId == 2 {
 		return nil, repository.ErrNotFound
 	} else if userId == 3 {
 		return nil, errors.New("Ошибка сервера")
 	} else if userId == 4 {
 		return nil, nil
+	} else if userId == 5 {
+		return &entities.RecommendationPage{
+			Recommendations: []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}},
+			NextCursor:      entities.EncodeRecommendationCursor(entities.Recommendation{ProductId: 2, Score: 2}, 2, 0),
+			Source:          entities.SourcePersonal,
+		}, nil
+	} else if userId == 7 {
+		//объяснения возвращаются только по запросу
+		var explanation *entities.Explanation
+		if query.Explain {
+			explanation = entities.KeyWordsExplanation(3, []string{"рыба"})
+		}
+		return &entities.RecommendationPage{
+			Recommendations: []entities.Recommendation{{ProductId: 1, Score: 3, Explanation: explanation}},
+			Source:          entities.SourcePersonal,
+		}, nil
+	} else if userId == 8 {
+		//страница размером с запрошенный limit, но не больше пяти продуктов
+		recommendations := make([]entities.Recommendation, 0)
+		for i := 1; i <= min(query.Limit, 5); i++ {
+			recommendations = append(recommendations, entities.Recommendation{ProductId: i, Score: float64(10 - i)})
+		}
+		return &entities.RecommendationPage{Recommendations: recommendations, Source: entities.SourcePersonal}, nil
+	} else if userId == 6 {
+		return &entities.RecommendationPage{
+			Recommendations: []entities.Recommendation{{ProductId: 5, Score: 12.5}, {ProductId: 2, Score: 3.25}},
+			Source:          entities.SourcePopular,
+		}, nil
 	}
-	return []int{1, 2, 3}, nil
+	return &entities.RecommendationPage{
+		Recommendations: []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}},
+		Source:          entities.SourcePersonal,
+	}, nil
 }
 
 func TestHandler_GetRecommendations_Correct(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -55,16 +102,72 @@ func TestHandler_GetRecommendations_Correct(t *testing.T) {
 
 	handler.getUserRecommendations(c)
 
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.RecommendationPage
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []entities.Recommendation{{ProductId: 1, Score: 3}, {ProductId: 2, Score: 2}, {ProductId: 3, Score: 1}}, response.Recommendations)
+	assert.Equal(t, "", response.NextCursor)
+	assert.Equal(t, entities.SourcePersonal, response.Source)
+}
+
+func TestHandler_GetRecommendations_CorrectPopular(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/6
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/6", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "6"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response map[string]any
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "popular", response["source"])
+	assert.Equal(t, entities.SourcePopular, w.Header().Get("X-Recommendation-Source"))
+}
+
+func TestHandler_GetRecommendations_CorrectPopularFormatIds(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/6?format=ids
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/6?format=ids", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "6"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	// в формате ids источник выдачи передается только в заголовке
 	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
 	var response []int
 	json.Unmarshal(w.Body.Bytes(), &response)
-	assert.Equal(t, []int{1, 2, 3}, response)
+	assert.Equal(t, []int{5, 2}, response)
+	assert.Equal(t, entities.SourcePopular, w.Header().Get("X-Recommendation-Source"))
 }
 
 func TestHandler_GetRecommendations_INcorrectMIssingParam(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -86,6 +189,7 @@ func TestHandler_GetRecommendations_INcorrectIncorrectParam(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -110,6 +214,7 @@ func TestHandler_GetRecommendations_INcorrectValidationParam(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -134,6 +239,7 @@ func TestHandler_GetRecommendations_CorrectButNotFoundUser(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -158,6 +264,7 @@ func TestHandler_GetRecommendations_CorrectButINternalServerError(t *testing.T)
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -182,6 +289,7 @@ func TestHandler_GetRecommendations_CorrectButNotFountRecom(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
 	)
 	// /recommendation/:userId
 
@@ -201,3 +309,309 @@ func TestHandler_GetRecommendations_CorrectButNotFountRecom(t *testing.T) {
 	json.Unmarshal(w.Body.Bytes(), &response)
 	assert.Equal(t, "recommendations for this user not found", response["reason"])
 }
+
+func TestHandler_GetRecommendations_CorrectFormatIds(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/:userId?format=ids
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/1?format=ids", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "1"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response []int
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []int{1, 2, 3}, response)
+}
+
+func TestHandler_GetRecommendations_INcorrectFormat(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/:userId?format=xml
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/1?format=xml", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "1"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid format: must be scored or ids", response["reason"])
+}
+
+func TestHandler_GetRecommendations_CorrectNextPage(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/5?limit=2
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/5?limit=2", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "5"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var response entities.RecommendationPage
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 2, len(response.Recommendations))
+	assert.NotEqual(t, "", response.NextCursor)
+	assert.Equal(t, response.NextCursor, w.Header().Get("X-Next-Cursor"))
+}
+
+func TestHandler_GetRecommendations_INcorrectLimit(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/1?limit=4
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/1?limit=4", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "1"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid limit: must be between 1 and 3", response["reason"])
+}
+
+func TestHandler_GetRecommendations_INcorrectCursor(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/1?cursor=kot
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/1?cursor=kot", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "1"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid cursor", response["reason"])
+}
+
+func TestHandler_GetRecommendations_INcorrectCursorWithOffset(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/1?cursor=MjoyMw&offset=2
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/1?cursor=MjoyMw&offset=2", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "1"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid query: cursor and offset can`t be used together", response["reason"])
+}
+
+func TestHandler_GetRecommendations_INcorrectCursorDiversity(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+	// /recommendation/1?cursor={курсор с разнообразием 0.5}&diversity=1
+	cursor := entities.EncodeRecommendationCursor(entities.Recommendation{ProductId: 23, Score: 2}, 2, 0.5)
+
+	// формируем тестовый запрос
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	c.Request = httptest.NewRequest("GET", "/recommendation/1?cursor="+cursor+"&diversity=1", nil)
+	c.Params = gin.Params{
+		gin.Param{Key: "userId", Value: "1"},
+	}
+
+	handler.getUserRecommendations(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	var response map[string]string
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "invalid query: diversity differs from the cursor", response["reason"])
+}
+
+func TestHandler_GetRecommendations_Diversity(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+
+	tests := []struct {
+		name   string
+		query  string
+		code   int
+		reason string
+	}{
+		{"Correct", "diversity=0.3", http.StatusOK, ""},
+		{"CorrectDisabled", "diversity=0", http.StatusOK, ""},
+		{"INcorrectParam", "diversity=kot", http.StatusBadRequest, ""},
+		{"INcorrectRange", "diversity=1.5", http.StatusBadRequest, "invalid diversity: must be between 0 and 1"},
+		{"INcorrectNegative", "diversity=-0.1", http.StatusBadRequest, "invalid diversity: must be between 0 and 1"},
+		{"INcorrectNaN", "diversity=NaN", http.StatusBadRequest, "invalid diversity: must be between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest("GET", "/recommendation/1?"+tt.query, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "userId", Value: "1"},
+			}
+
+			handler.getUserRecommendations(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.reason != "" {
+				var response map[string]string
+				json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, tt.reason, response["reason"])
+			}
+		})
+	}
+}
+
+func TestHandler_GetRecommendations_Explain(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+
+	tests := []struct {
+		name      string
+		query     string
+		code      int
+		explained bool
+	}{
+		{"Correct", "explain=true", http.StatusOK, true},
+		{"CorrectFalse", "explain=false", http.StatusOK, false},
+		{"CorrectMissing", "", http.StatusOK, false},
+		{"INcorrectParam", "explain=kot", http.StatusBadRequest, false},
+		{"INcorrectFormatIds", "explain=true&format=ids", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request = httptest.NewRequest("GET", "/recommendation/7?"+tt.query, nil)
+			c.Params = gin.Params{
+				gin.Param{Key: "userId", Value: "7"},
+			}
+
+			handler.getUserRecommendations(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var response entities.RecommendationPage
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if tt.explained {
+				assert.Equal(t, entities.KeyWordsExplanation(3, []string{"рыба"}), response.Recommendations[0].Explanation)
+			} else {
+				assert.Nil(t, response.Recommendations[0].Explanation)
+				assert.NotContains(t, w.Body.String(), "explanation")
+			}
+		})
+	}
+}
+
+func TestHandler_GetRecommendations_CorrectIdsUnpaged(t *testing.T) {
+	handler := NewHandler(
+		&MockService{},
+		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{DefaultLimit: 2, MaxLimit: 3},
+	)
+
+	// format=ids без параметров страницы - весь список, с limit - страница
+	for url, expected := range map[string][]int{
+		"/recommendation/8?format=ids":         {1, 2, 3, 4, 5},
+		"/recommendation/8?format=ids&limit=3": {1, 2, 3},
+		"/recommendation/8":                    nil,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Request = httptest.NewRequest("GET", url, nil)
+		c.Params = gin.Params{
+			gin.Param{Key: "userId", Value: "8"},
+		}
+
+		handler.getUserRecommendations(c)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		if expected == nil {
+			var response entities.RecommendationPage
+			json.Unmarshal(w.Body.Bytes(), &response)
+			assert.Equal(t, 2, len(response.Recommendations))
+			continue
+		}
+		var response []int
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, expected, response)
+	}
+}
@@ -4,6 +4,8 @@ import "github.com/gin-gonic/gin"
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	//контекст обработчика отменяется вместе с запросом клиента, это прерывает и запросы к базе
+	router.ContextWithFallback = true
 
 	//recommendation
 	recommendation := router.Group("/recommendation")
@@ -12,6 +14,9 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		userId := recommendation.Group("/:userId")
 		{
 			userId.GET("", h.getUserRecommendations)
+
+			//recommendation/{userId}/feedback
+			userId.POST("/feedback", h.addFeedback)
 		}
 	}
 
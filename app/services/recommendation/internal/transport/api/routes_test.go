@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/pkg/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,6 +15,7 @@ func TestUserHandler_InitRoutes_Correct(t *testing.T) {
 	handler := NewHandler(
 		&MockService{},
 		slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		config.PageConfig{},
 	)
 
 	// инициализируем маршруты
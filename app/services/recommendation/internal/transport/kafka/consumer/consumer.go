@@ -6,15 +6,21 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/service"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/recommendation/internal/transport/kafka/envelope"
 	"github.com/IBM/sarama"
 )
 
+// сколько последних id событий хранится для отбрасывания повторов
+const seenEventsLimit = 10000
+
 type Consumer struct {
 	Consumer sarama.Consumer
 	topics   []string
 	log      *slog.Logger
+	seen     *seenEvents
 }
 
 func NewConsumer(addrs []string, topics []string, log *slog.Logger) (*Consumer, error) {
@@ -29,6 +35,7 @@ func NewConsumer(addrs []string, topics []string, log *slog.Logger) (*Consumer,
 		Consumer: c,
 		topics:   topics,
 		log:      log,
+		seen:     newSeenEvents(seenEventsLimit),
 	}, nil
 }
 
@@ -45,19 +52,9 @@ func (c *Consumer) Consume(handler service.KafkaHandler, ctx context.Context) er
 		select {
 		case msg, ok := <-responseCh:
 			if ok {
-				switch msg.Topic {
-				case "user_updates":
-					c.log.Info(fi + ": " + "Message about user_updates received from topic")
-					if err := handler.AddUserData(ctx, msg); err != nil {
-						c.log.Error("Error adding user data", "err", err)
-						return err
-					}
-				case "product_updates":
-					c.log.Info(fi + ": " + "Message about product_updates received from topic")
-					if err := handler.AddProductData(ctx, msg); err != nil {
-						c.log.Error("Error adding user data", "err", err)
-						return err
-					}
+				if err := c.handleMessage(ctx, handler, msg); err != nil {
+					c.log.Error(fi+": error handling message", "err", err)
+					return err
 				}
 			}
 		case <-ctx.Done():
@@ -68,13 +65,141 @@ func (c *Consumer) Consume(handler service.KafkaHandler, ctx context.Context) er
 
 }
 
+// обработка сообщения: повторно доставленные события отбрасываются по event-id,
+// маршрут выбирается по типу события, у старых сообщений без заголовков - по топику
+func (c *Consumer) handleMessage(ctx context.Context, handler service.KafkaHandler, msg *sarama.ConsumerMessage) error {
+	fi := "kafka.Consumer.handleMessage"
+	env := envelope.FromMessage(msg)
+
+	if env.EventId != "" && c.seen.Contains(env.EventId) {
+		c.log.Info(fi+": duplicate event skipped", "eventId", env.EventId, "eventType", env.EventType)
+		return nil
+	}
+
+	switch messageRoute(env, msg.Topic) {
+	case routeUser:
+		c.log.Info(fi+": user event received", "eventType", env.EventType, "key", env.Key, "traceId", env.TraceId)
+		if err := handler.AddUserData(ctx, msg); err != nil {
+			return err
+		}
+	case routeProduct:
+		c.log.Info(fi+": product event received", "eventType", env.EventType, "key", env.Key, "traceId", env.TraceId)
+		if err := handler.AddProductData(ctx, msg); err != nil {
+			return err
+		}
+	case routeInteraction:
+		c.log.Info(fi+": interaction event received", "eventType", env.EventType, "key", env.Key, "traceId", env.TraceId)
+		if err := handler.AddInteractionData(ctx, msg); err != nil {
+			return err
+		}
+	default:
+		c.log.Info(fi+": unknown event skipped", "topic", msg.Topic, "eventType", env.EventType)
+		return nil
+	}
+
+	if env.EventId != "" {
+		c.seen.Add(env.EventId)
+	}
+
+	return nil
+}
+
+const (
+	routeUnknown = iota
+	routeUser
+	routeProduct
+	routeInteraction
+)
+
+func messageRoute(env *envelope.Envelope, topic string) int {
+	switch {
+	case strings.HasPrefix(env.EventType, "user."):
+		return routeUser
+	case strings.HasPrefix(env.EventType, "product."):
+		return routeProduct
+	case strings.HasPrefix(env.EventType, "interaction."):
+		return routeInteraction
+	case env.EventType != "":
+		return routeUnknown
+	}
+
+	switch topic {
+	case "user_updates":
+		return routeUser
+	case "product_updates":
+		return routeProduct
+	case "user_interactions":
+		return routeInteraction
+	}
+
+	return routeUnknown
+}
+
+// ограниченное множество id обработанных событий, при переполнении вытесняются самые старые
+type seenEvents struct {
+	mu    sync.Mutex
+	limit int
+	ids   map[string]struct{}
+	order []string
+}
+
+func newSeenEvents(limit int) *seenEvents {
+	return &seenEvents{
+		limit: limit,
+		ids:   make(map[string]struct{}, limit),
+		order: make([]string, 0, limit),
+	}
+}
+
+func (s *seenEvents) Contains(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.ids[id]
+	return ok
+}
+
+func (s *seenEvents) Add(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return
+	}
+
+	if len(s.order) == s.limit {
+		delete(s.ids, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	s.ids[id] = struct{}{}
+	s.order = append(s.order, id)
+}
+
+// сообщения с ключом распределяются по всем партициям топика, читаем каждую
 func ConsumeTopic(
 	c *Consumer, topic string,
 	ctx context.Context, responseCh chan<- *sarama.ConsumerMessage,
 ) {
-	pc, err := c.Consumer.ConsumePartition(topic, 0, sarama.OffsetOldest)
+	partitions, err := c.Consumer.Partitions(topic)
+	if err != nil {
+		c.log.Error("Error getting partitions", "topic", topic, "err", err)
+		return
+	}
+
+	for _, partition := range partitions {
+		go consumePartition(c, topic, partition, ctx, responseCh)
+	}
+}
+
+func consumePartition(
+	c *Consumer, topic string, partition int32,
+	ctx context.Context, responseCh chan<- *sarama.ConsumerMessage,
+) {
+	pc, err := c.Consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
 	if err != nil {
-		c.log.Error("Error consuming partition", "err", err)
+		c.log.Error("Error consuming partition", "topic", topic, "partition", partition, "err", err)
+		return
 	}
 	defer pc.AsyncClose()
 
@@ -86,7 +211,7 @@ func ConsumeTopic(
 		case err := <-pc.Errors():
 			c.log.Error("Error from consumer", err.Error(), err)
 		case <-ctx.Done():
-			c.log.Info("Closing consumer by reason from server, topic", topic, 0)
+			c.log.Info("Closing consumer by reason from server, topic", topic, partition)
 			return
 		}
 
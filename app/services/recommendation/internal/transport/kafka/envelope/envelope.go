@@ -0,0 +1,145 @@
+// общий конверт сообщений кафки: ключ сообщения - id сущности (порядок событий одной
+// сущности сохраняется в пределах партиции), метаданные события передаются в заголовках.
+// Файл одинаковый во всех сервисах
+
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// заголовки сообщения
+const (
+	HeaderEventId       = "event-id"
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderProducer      = "producer"
+	HeaderOccurredAt    = "occurred-at"
+	HeaderTraceId       = "trace-id"
+)
+
+// типы событий, snapshot - текущее состояние сущности при повторной публикации
+const (
+	EventProductCreated  = "product.created"
+	EventProductUpdated  = "product.updated"
+	EventProductDeleted  = "product.deleted"
+	EventProductSnapshot = "product.snapshot"
+	EventUserUpdated     = "user.updated"
+	EventUserSnapshot    = "user.snapshot"
+	//взаимодействие пользователя с продуктом (просмотр, клик, покупка)
+	EventInteractionCreated = "interaction.created"
+)
+
+// версии схем сообщений, увеличиваются при изменении proto
+const (
+	ProductSchemaVersion     = 5
+	UserSchemaVersion        = 1
+	InteractionSchemaVersion = 1
+)
+
+// ключ trace id в контексте запроса (gin.Context.Set), он же HTTP заголовок X-Trace-Id
+const (
+	TraceIdKey    = "traceId"
+	TraceIdHeader = "X-Trace-Id"
+)
+
+type Envelope struct {
+	Key           string
+	EventId       string
+	EventType     string
+	SchemaVersion int
+	Producer      string
+	OccurredAt    time.Time
+	TraceId       string
+}
+
+// новый конверт события, trace id берется из контекста или генерируется
+func New(ctx context.Context, key string, eventType string, schemaVersion int, producer string) *Envelope {
+	traceId := TraceId(ctx)
+	if traceId == "" {
+		traceId = NewId()
+	}
+
+	return &Envelope{
+		Key:           key,
+		EventId:       NewId(),
+		EventType:     eventType,
+		SchemaVersion: schemaVersion,
+		Producer:      producer,
+		OccurredAt:    time.Now().UTC(),
+		TraceId:       traceId,
+	}
+}
+
+// сообщение для отправки в топик
+func (e *Envelope) Message(topic string, value []byte) *sarama.ProducerMessage {
+	return &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(e.Key),
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(HeaderEventId), Value: []byte(e.EventId)},
+			{Key: []byte(HeaderEventType), Value: []byte(e.EventType)},
+			{Key: []byte(HeaderSchemaVersion), Value: []byte(strconv.Itoa(e.SchemaVersion))},
+			{Key: []byte(HeaderProducer), Value: []byte(e.Producer)},
+			{Key: []byte(HeaderOccurredAt), Value: []byte(e.OccurredAt.Format(time.RFC3339Nano))},
+			{Key: []byte(HeaderTraceId), Value: []byte(e.TraceId)},
+		},
+	}
+}
+
+// конверт полученного сообщения, у старых сообщений без заголовков поля остаются пустыми
+func FromMessage(msg *sarama.ConsumerMessage) *Envelope {
+	e := &Envelope{Key: string(msg.Key)}
+
+	for _, header := range msg.Headers {
+		if header == nil {
+			continue
+		}
+
+		value := string(header.Value)
+		switch string(header.Key) {
+		case HeaderEventId:
+			e.EventId = value
+		case HeaderEventType:
+			e.EventType = value
+		case HeaderSchemaVersion:
+			e.SchemaVersion, _ = strconv.Atoi(value)
+		case HeaderProducer:
+			e.Producer = value
+		case HeaderOccurredAt:
+			e.OccurredAt, _ = time.Parse(time.RFC3339Nano, value)
+		case HeaderTraceId:
+			e.TraceId = value
+		}
+	}
+
+	return e
+}
+
+// trace id текущего запроса, пустая строка если его нет
+func TraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	traceId, _ := ctx.Value(TraceIdKey).(string)
+	return traceId
+}
+
+// случайный UUID версии 4
+func NewId() string {
+	var b [16]byte
+	rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,2 @@
+-- рекомендуются только продукты в статусе active, ранее сохраненные продукты считаются активными
+ALTER TABLE products ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
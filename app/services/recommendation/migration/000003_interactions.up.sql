@@ -0,0 +1,15 @@
+-- поведенческие сигналы пользователей: просмотры, клики и покупки.
+-- Без внешних ключей - событие может прийти раньше данных пользователя или продукта
+CREATE TABLE IF NOT EXISTS interactions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    interaction_type VARCHAR(32) NOT NULL,
+    weight DOUBLE PRECISION NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    session_id VARCHAR(64),
+    CHECK (user_id > 0 AND product_id > 0)
+);
+
+CREATE INDEX IF NOT EXISTS interactions_user_id_idx ON interactions (user_id);
+CREATE INDEX IF NOT EXISTS interactions_product_id_idx ON interactions (product_id);
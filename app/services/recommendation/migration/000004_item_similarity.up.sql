@@ -0,0 +1,9 @@
+-- похожие продукты (соседи) по взаимодействиям пользователей, пересчитываются задачей целиком:
+-- score - косинусная близость векторов весов взаимодействий, support - число общих пользователей
+CREATE TABLE IF NOT EXISTS item_similarity (
+    product_id INTEGER NOT NULL,
+    neighbour_id INTEGER NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    support INTEGER NOT NULL,
+    PRIMARY KEY (product_id, neighbour_id)
+);
@@ -0,0 +1,10 @@
+-- отзывы пользователей: продукт исключается из выдачи пользователя, вклад его ключевых слов
+-- в оценку других продуктов уменьшается (кроме причины already_owned)
+CREATE TABLE IF NOT EXISTS feedback (
+    user_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
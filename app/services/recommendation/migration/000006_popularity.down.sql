@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS popular_products;
+ALTER TABLE products DROP COLUMN IF EXISTS category_id;
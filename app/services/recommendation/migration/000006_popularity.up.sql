@@ -0,0 +1,12 @@
+-- category_id 0 - категория не указана, категории ранее сохраненных продуктов приходят со следующим событием продукта
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id INTEGER NOT NULL DEFAULT 0;
+
+-- рейтинги популярности активных продуктов для пользователей без персональных рекомендаций,
+-- пересчитываются задачей целиком: category_id 0 - общий рейтинг, иначе рейтинг категории.
+-- score - сумма весов взаимодействий с продуктом, дробная часть - частота его ключевых слов
+CREATE TABLE IF NOT EXISTS popular_products (
+    category_id INTEGER NOT NULL,
+    product_id INTEGER NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (category_id, product_id)
+);
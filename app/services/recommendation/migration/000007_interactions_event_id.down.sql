@@ -0,0 +1 @@
+ALTER TABLE interactions DROP COLUMN IF EXISTS event_id;
@@ -0,0 +1,4 @@
+-- id события из конверта kafka: событие, повторно прочитанное из топика после перезапуска,
+-- не сохраняется второй раз. У сообщений без заголовков и ранее сохраненных взаимодействий id нет,
+-- NULL уникальность не нарушает
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS event_id VARCHAR(64) UNIQUE;
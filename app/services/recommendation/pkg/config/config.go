@@ -15,9 +15,50 @@ type ServiceConfig struct {
 	SrvConf ServerConfig
 	DBConf  DBConfig
 	KVConf  KeyValueConfig
+	PgConf  PageConfig
+	SimConf SimilarityConfig
+	PopConf PopularityConfig
+	DivConf DiversityConfig
 	Env     string `yaml:"env" env-default:"local"`
 }
 
+// конфигурация постраничной выдачи рекомендаций: размер страницы по умолчанию
+// и максимальный, 0 - значения по умолчанию сервиса
+type PageConfig struct {
+	DefaultLimit int `yaml:"defaultLimit"`
+	MaxLimit     int `yaml:"maxLimit"`
+}
+
+// конфигурация коллаборативной фильтрации: матрица похожих продуктов пересчитывается по
+// взаимодействиям раз в Interval (0 - не пересчитывается), у продукта хранится TopK соседей,
+// с которыми взаимодействовали не меньше MinSupport общих пользователей. Соседи RecentItems
+// последних продуктов пользователя добавляются к выдаче по ключевым словам с весом Weight.
+// Timeout - таймаут одного пересчета вместо таймаута запроса к базе, 0 - без таймаута
+type SimilarityConfig struct {
+	Interval    time.Duration `yaml:"interval"`
+	Timeout     time.Duration `yaml:"timeout"`
+	MinSupport  int           `yaml:"minSupport"`
+	TopK        int           `yaml:"topK"`
+	RecentItems int           `yaml:"recentItems"`
+	Weight      float64       `yaml:"weight"`
+}
+
+// конфигурация рейтингов популярности для пользователей без персональных рекомендаций:
+// рейтинги пересчитываются раз в Interval (0 - не пересчитываются), в общем рейтинге
+// и в рейтинге каждой категории хранится TopK продуктов. Timeout - таймаут одного пересчета, 0 - без таймаута
+type PopularityConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	TopK     int           `yaml:"topK"`
+}
+
+// конфигурация переранжирования выдачи по разнообразию: Factor от 0 (порядок по оценке) до 1,
+// используется если в запросе не передан параметр diversity. Переранжируются Window первых продуктов
+type DiversityConfig struct {
+	Factor float64 `yaml:"factor"`
+	Window int     `yaml:"window"`
+}
+
 // кофигурация базы данных
 type DBConfig struct {
 	Username string `yaml:"username"`
@@ -26,6 +67,8 @@ type DBConfig struct {
 	Port     string `yaml:"port"`
 	Dbname   string `yaml:"dbname"`
 	Sslmode  string `yaml:"sslmode"`
+	//таймаут одного обращения к базе (запроса или транзакции), 0 - без таймаута
+	QueryTimeout time.Duration `yaml:"queryTimeout"`
 }
 
 // конфигурация сервера
@@ -135,6 +178,10 @@ func LoadConfig(path string, name string) (*ServiceConfig, error) {
 		dbConf  DBConfig
 		srvConf ServerConfig
 		kvConf  KeyValueConfig
+		pgConf  PageConfig
+		simConf SimilarityConfig
+		popConf PopularityConfig
+		divConf DiversityConfig
 	)
 
 	//инициализируем имя, папку и тип конфига
@@ -169,10 +216,34 @@ func LoadConfig(path string, name string) (*ServiceConfig, error) {
 		kvConf.Addr = kvConf.Host + ":" + kvConf.Port
 	}
 
+	//заполняем структуру постраничной выдачи
+	if err := viper.UnmarshalKey("pagination", &pgConf); err != nil {
+		return nil, err
+	}
+
+	//заполняем структуру коллаборативной фильтрации
+	if err := viper.UnmarshalKey("similarity", &simConf); err != nil {
+		return nil, err
+	}
+
+	//заполняем структуру рейтингов популярности
+	if err := viper.UnmarshalKey("popularity", &popConf); err != nil {
+		return nil, err
+	}
+
+	//заполняем структуру переранжирования по разнообразию
+	if err := viper.UnmarshalKey("diversity", &divConf); err != nil {
+		return nil, err
+	}
+
 	return &ServiceConfig{
 		SrvConf: srvConf,
 		DBConf:  dbConf,
 		KVConf:  kvConf,
+		PgConf:  pgConf,
+		SimConf: simConf,
+		PopConf: popConf,
+		DivConf: divConf,
 	}, nil
 
 }
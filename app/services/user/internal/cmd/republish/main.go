@@ -0,0 +1,91 @@
+// повторная публикация снимков всех пользователей в кафку (событие user.snapshot)
+// для восстановления состояния сервисов рекомендаций и аналитики.
+// При обрыве публикацию можно продолжить с флагом -from, равным последнему lastId
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/repository"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/service"
+	kafka "github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/transport/kafka/producer"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/pkg/config"
+	mylog "github.com/AndroSaal/RecommendationsForUsers/app/services/user/pkg/log"
+)
+
+func main() {
+	var republish entities.Republish
+
+	//флаги разбираются вместе с флагами конфигурации в config.MustLoadConfig
+	flag.IntVar(&republish.FromId, "from", 0, "id of last published user, publishing continues after it")
+	flag.IntVar(&republish.Rate, "rate", entities.DefaultRepublishRate, "max users per second")
+
+	//загрузка переменных окружения
+	env := config.MustLoadEnv()
+
+	// логгер
+	logger := mylog.MustNewLogger(env)
+
+	// конфига
+	cfg := config.MustLoadConfig()
+
+	if err := republish.ValidateRepublish(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	//код выхода выставляется после закрытия соединений (defer выполняется последним)
+	exitCode := 0
+	defer func() { os.Exit(exitCode) }()
+
+	// коннект к бд (Маст)
+	dbConn := repository.NewPostgresDB(cfg.DBConf)
+	defer func() {
+		if err := dbConn.DB.Close(); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+
+	// почта при публикации не используется, но нужна слою сервиса
+	mail := service.NewMailSender(cfg.MailConf, logger)
+	service := service.NewUserService(mail, repository.NewUserRepository(dbConn, logger), logger)
+
+	//коннект к кафке
+	kafkaConn := kafka.ConnectToKafka(logger)
+	defer func() {
+		if err := kafkaConn.Close(); err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+
+	// остановка по сигналу, прогресс до остановки сохраняется в выводе
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM,
+	)
+	defer stop()
+
+	progress := entities.RepublishProgress{LastId: republish.FromId}
+	err := service.RepublishUsers(ctx, &republish, func(users []entities.UserInfo) error {
+		if err := kafkaConn.SendSnapshots(ctx, users); err != nil {
+			return err
+		}
+
+		progress.Published += len(users)
+		progress.LastId = users[len(users)-1].UsrId
+		fmt.Printf("published %d, last id %d\n", progress.Published, progress.LastId)
+		return nil
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "republish stopped: %v, resume with -from=%d\n", err, progress.LastId)
+		exitCode = 1
+		return
+	}
+	fmt.Printf("done: published %d users\n", progress.Published)
+}
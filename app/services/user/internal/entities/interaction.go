@@ -0,0 +1,81 @@
+// события взаимодействия пользователя с продуктами для сбора поведенческих сигналов
+
+package entities
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// типы взаимодействий
+const (
+	InteractionView     = "view"
+	InteractionClick    = "click"
+	InteractionPurchase = "purchase"
+)
+
+const (
+	sessionIdMaxLenth = 64
+
+	maxInteractionWeight = 100
+	//допустимое расхождение часов клиента и сервера
+	interactionClockSkew = 5 * time.Minute
+)
+
+// вес сигнала по умолчанию: покупка говорит об интересе больше, чем просмотр
+var interactionWeights = map[string]float64{
+	InteractionView:     1,
+	InteractionClick:    2,
+	InteractionPurchase: 5,
+}
+
+// взаимодействие пользователя с продуктом, Weight и Timestamp необязательны
+type Interaction struct {
+	UserId    int       `json:"userId" binding:"required"`
+	ProductId int       `json:"productId" binding:"required"`
+	Type      string    `json:"type" binding:"required"`
+	Weight    float64   `json:"weight"`
+	Timestamp time.Time `json:"timestamp"`
+	SessionId string    `json:"sessionId"`
+}
+
+// проверка события, пустые вес и время заменяются весом типа и текущим временем
+func (i *Interaction) ValidateInteraction() error {
+
+	if err := ValidateUserId(i.UserId); err != nil {
+		return err
+	}
+
+	if i.ProductId <= 0 {
+		return errors.New("invalid product id: can`t be less or equel 0")
+	}
+
+	defaultWeight, ok := interactionWeights[i.Type]
+	if !ok {
+		return errors.New("invalid type: must be view, click or purchase")
+	}
+
+	if i.Weight == 0 {
+		i.Weight = defaultWeight
+	}
+
+	if i.Weight < 0 || i.Weight > maxInteractionWeight {
+		return errors.New("invalid weight: must be between 0 and " + strconv.Itoa(maxInteractionWeight))
+	}
+
+	now := time.Now().UTC()
+	if i.Timestamp.IsZero() {
+		i.Timestamp = now
+	}
+
+	if i.Timestamp.After(now.Add(interactionClockSkew)) {
+		return errors.New("invalid timestamp: can`t be in the future")
+	}
+
+	if len(i.SessionId) > sessionIdMaxLenth {
+		return errors.New("invalid sessionId: too long, max length is " + strconv.Itoa(sessionIdMaxLenth))
+	}
+
+	return nil
+}
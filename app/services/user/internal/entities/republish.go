@@ -0,0 +1,50 @@
+// повторная публикация снимков всех пользователей для восстановления состояния подписчиков
+
+package entities
+
+import (
+	"errors"
+	"strconv"
+)
+
+const (
+	// пользователей в секунду, если скорость не задана
+	DefaultRepublishRate = 200
+	MaxRepublishRate     = 5000
+
+	// пользователей в одной пачке публикации
+	RepublishBatchSize = 100
+)
+
+// параметры повторной публикации: FromId - id последнего уже опубликованного пользователя
+// (публикация продолжается после него), Rate - максимальное число пользователей в секунду
+type Republish struct {
+	FromId int
+	Rate   int
+}
+
+// прогресс повторной публикации, при ошибке публикацию можно продолжить с LastId
+type RepublishProgress struct {
+	Published int    `json:"published"`
+	LastId    int    `json:"lastId"`
+	Done      bool   `json:"done"`
+	Error     string `json:"error,omitempty"`
+}
+
+// проверка параметров, пустая скорость заменяется скоростью по умолчанию
+func (r *Republish) ValidateRepublish() error {
+
+	if r.FromId < 0 {
+		return errors.New("invalid fromId: can`t be less than 0")
+	}
+
+	if r.Rate == 0 {
+		r.Rate = DefaultRepublishRate
+	}
+
+	if r.Rate < 0 || r.Rate > MaxRepublishRate {
+		return errors.New("invalid rate: must be between 1 and " + strconv.Itoa(MaxRepublishRate))
+	}
+
+	return nil
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/pkg/config"
@@ -18,11 +20,13 @@ type RelationalDataBase interface {
 	GetUserByEmail(ctx context.Context, email string) (*entities.UserInfo, error)
 	VerifyCode(ctx context.Context, userId int, code string) (bool, error)
 	UpdateUser(ctx context.Context, userId int, user *entities.UserInfo) error
+	GetUsers(ctx context.Context, afterId int, limit int) ([]entities.UserInfo, error)
 }
 
 // имплементация RelationalDataBase интерфейса
 type PostgresDB struct {
-	DB *sqlx.DB
+	DB      *sqlx.DB
+	timeout time.Duration
 }
 
 // установка соединения с базой, паника в случае ошибки
@@ -36,59 +40,56 @@ func NewPostgresDB(cfg config.DBConfig) *PostgresDB {
 		log.Panic("Error connecting to database: " + err.Error())
 	}
 	return &PostgresDB{
-		DB: DB,
+		DB:      DB,
+		timeout: cfg.QueryTimeout,
 	}
 }
 
 func (p *PostgresDB) AddNewUser(ctx context.Context, user *entities.UserInfo, code string) (int, error) {
 
 	var userId int
-	//начинаем транзакцию
-	trx, err := p.DB.Begin()
-	if err != nil {
-		return 0, err
-	}
 
-	//формируем запрос для добавления новой записи в таблицу users
-	queryAddUser := fmt.Sprintf(
-		`INSERT INTO %s 
-		 (%s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6) 
-		 RETURNING %s`,
-		usersTable,
-		emailPole, usernamePole, passwordPole, describtionPole, isEmailVerifiedPole, agePole,
-		id,
-	)
-	//выполняем запрос по добавлению
-	row := trx.QueryRow(queryAddUser,
-		user.Email, user.Usrname, user.Password, user.UsrDesc, false, user.UsrAge)
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
 
-	//вычитывает полученный id
-	if err := row.Scan(&userId); err != nil {
-		trx.Rollback()
-		if err.(*pq.Error).Code == "23505" {
-			return 0, ErrAlreadyExists
-		} else {
-			return 0, err
+	err := WithTx(ctx, p.DB, func(trx *sql.Tx) error {
+		//формируем запрос для добавления новой записи в таблицу users
+		queryAddUser := fmt.Sprintf(
+			`INSERT INTO %s 
+			 (%s, %s, %s, %s, %s, %s) VALUES ($1, $2, $3, $4, $5, $6) 
+			 RETURNING %s`,
+			usersTable,
+			emailPole, usernamePole, passwordPole, describtionPole, isEmailVerifiedPole, agePole,
+			id,
+		)
+		//выполняем запрос по добавлению
+		row := trx.QueryRowContext(ctx, queryAddUser,
+			user.Email, user.Usrname, user.Password, user.UsrDesc, false, user.UsrAge)
+
+		//вычитывает полученный id
+		if err := row.Scan(&userId); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+		//формируем запрос для добавления новой записи в таблицу codes
+		queryAddCode := fmt.Sprintf(`INSERT INTO %s (%s, %s) VALUES ($1, $2)`,
+			codesTable,
+			codePole, userIdPole,
+		)
+		//выполняем запрос
+		if _, err := trx.ExecContext(ctx, queryAddCode, code, userId); err != nil {
+			return err
 		}
-	}
-	//формируем запрос для добавления новой записи в таблицу codes
-	queryAddCode := fmt.Sprintf(`INSERT INTO %s (%s, %s) VALUES ($1, $2)`,
-		codesTable,
-		codePole, userIdPole,
-	)
-	//выполняем запрос
-	if _, err = trx.Exec(queryAddCode, code, userId); err != nil {
-		trx.Rollback()
-		return 0, err
-	}
 
-	//добавление интересов пользователя
-	if _, err = addUserInterests(user, trx, userId); err != nil {
-		trx.Rollback()
+		//добавление интересов пользователя
+		return addUserInterests(ctx, user, trx, userId)
+	})
+	if err != nil {
 		return 0, err
 	}
-	//ураа все получилось, коммит
-	trx.Commit()
 
 	return userId, nil
 }
@@ -96,65 +97,55 @@ func (p *PostgresDB) AddNewUser(ctx context.Context, user *entities.UserInfo, co
 func (p *PostgresDB) GetUserById(ctx context.Context, userId int) (*entities.UserInfo, error) {
 	var userDB UserInfoForDB
 
-	tgx, err := p.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
 
 	querySelectUser := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`,
 		all, usersTable, id,
 	)
-	row := tgx.QueryRow(querySelectUser, userId)
+	row := p.DB.QueryRowContext(ctx, querySelectUser, userId)
 
 	if err := row.Scan(
 		&userDB.UsrId, &userDB.Email, &userDB.Usrname, &userDB.Password,
 		&userDB.UsrDesc, &userDB.UsrAge, &userDB.IsEmailValid,
 	); err != nil {
-		tgx.Rollback()
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			err = ErrNotFound
 		}
 
 		return nil, err
 	}
 
-	queryOfAllInterests := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`,
-		interestIdPole, userInterestsTable, userIdPole,
+	//интересы пользователя в порядке добавления
+	queryOfAllInterests := fmt.Sprintf(
+		`SELECT i.%s FROM %s ui 
+		 JOIN %s i ON i.%s = ui.%s 
+		 WHERE ui.%s = $1 
+		 ORDER BY ui.%s`,
+		intersestPole, userInterestsTable,
+		interestsTable, id, interestIdPole,
+		userIdPole,
+		id,
 	)
 
-	rows, err := tgx.Query(queryOfAllInterests, userId)
+	rows, err := p.DB.QueryContext(ctx, queryOfAllInterests, userId)
 	if err != nil {
-		tgx.Rollback()
 		return nil, err
 	}
-
-	queryToSelectInterestName := fmt.Sprintf(
-		`SELECT %s FROM %s WHERE %s = $1`,
-		intersestPole, interestsTable, id,
-	)
+	defer rows.Close()
 
 	interests := make([]entities.UserInterest, 0)
 	for rows.Next() {
-		var (
-			interest   entities.UserInterest
-			interestId int
-		)
-
-		if err := rows.Scan(&interestId); err != nil {
-			tgx.Rollback()
-			return nil, err
-		}
-
-		interestRow := p.DB.QueryRow(queryToSelectInterestName, interestId)
-		if err := interestRow.Scan(&interest); err != nil {
-			tgx.Rollback()
+		var interest entities.UserInterest
+		if err := rows.Scan(&interest); err != nil {
 			return nil, err
 		}
 
 		interests = append(interests, interest)
 	}
-
-	tgx.Commit()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return &entities.UserInfo{
 		UsrId:           userId,
@@ -171,13 +162,16 @@ func (p *PostgresDB) GetUserById(ctx context.Context, userId int) (*entities.Use
 func (p *PostgresDB) GetUserByEmail(ctx context.Context, email string) (*entities.UserInfo, error) {
 	var userId int
 
+	queryCtx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
 	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`,
 		id, usersTable, emailPole,
 	)
-	row := p.DB.QueryRow(query, email)
+	row := p.DB.QueryRowContext(queryCtx, query, email)
 
 	if err := row.Scan(&userId); err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			err = ErrNotFound
 		}
 		return nil, err
@@ -187,33 +181,31 @@ func (p *PostgresDB) GetUserByEmail(ctx context.Context, email string) (*entitie
 }
 
 func (p *PostgresDB) VerifyCode(ctx context.Context, userId int, code string) (bool, error) {
-	var (
-		codeFromDB string
-	)
+	var verified bool
 
-	tgx, err := p.DB.Begin()
-	if err != nil {
-		return false, err
-	}
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
 
-	//формирование запроса к базе
-	querySelectCode := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`,
-		codePole, codesTable, userIdPole,
-	)
+	err := WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		var codeFromDB string
+
+		//формирование запроса к базе
+		querySelectCode := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1`,
+			codePole, codesTable, userIdPole,
+		)
 
-	//выполняем запрос,
-	row := tgx.QueryRow(querySelectCode, userId)
+		//выполняем запрос, получаем запись
+		if err := tgx.QueryRowContext(ctx, querySelectCode, userId).Scan(&codeFromDB); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
 
-	//получаем запись
-	if err := row.Scan(&codeFromDB); err != nil {
-		tgx.Rollback()
-		if err == sql.ErrNoRows {
-			err = ErrNotFound
+		if codeFromDB != code {
+			return nil
 		}
-		return false, err
-	}
 
-	if codeFromDB == code {
 		//формируем текст запроса
 		queryToAddVerification := fmt.Sprintf(
 			`UPDATE %s
@@ -225,75 +217,134 @@ func (p *PostgresDB) VerifyCode(ctx context.Context, userId int, code string) (b
 		)
 
 		//выполняем запрос
-		if _, err := tgx.Exec(queryToAddVerification, userId); err != nil {
-			tgx.Rollback()
-			return false, err
+		if _, err := tgx.ExecContext(ctx, queryToAddVerification, userId); err != nil {
+			return err
 		}
-	} else {
-		tgx.Rollback()
-		return false, nil
+		verified = true
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
-	tgx.Commit()
-	return true, nil
+
+	return verified, nil
 }
 
 func (p *PostgresDB) UpdateUser(ctx context.Context, userId int, user *entities.UserInfo) error {
 
-	tgx, err := p.DB.Begin()
-	if err != nil {
-		return err
-	}
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return WithTx(ctx, p.DB, func(tgx *sql.Tx) error {
+		//проверка что пользователь существует, строка блокируется до конца транзакции
+		queryCheck := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 FOR UPDATE`, id, usersTable, id)
+		if err := tgx.QueryRowContext(ctx, queryCheck, userId).Scan(&userId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrNotFound
+			}
+			return err
+		}
 
-	rowCheck := tgx.QueryRow(`SELECT id FROM users WHERE id = $1`, userId)
+		query := fmt.Sprintf(
+			`UPDATE %s 
+			 SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5 
+			 WHERE %s = $6`,
+			usersTable,
+			usernamePole, emailPole, passwordPole, describtionPole, agePole,
+			id,
+		)
 
-	//проверка что пользователь существует
-	if err := rowCheck.Scan(&userId); err != nil {
-		tgx.Rollback()
-		if err == sql.ErrNoRows {
-			err = ErrNotFound
+		if _, err := tgx.ExecContext(ctx, query,
+			user.Usrname, user.Email, user.Password, user.UsrDesc, user.UsrAge, userId,
+		); err != nil {
+			return err
 		}
-		return err
 
-	}
+		//удание старых интересов пользователя
+		queryDeleteInterests := fmt.Sprintf(
+			`DELETE FROM %s WHERE %s = $1`,
+			userInterestsTable, userIdPole,
+		)
+		if _, err := tgx.ExecContext(ctx, queryDeleteInterests, userId); err != nil {
+			return err
+		}
+
+		//добавление новых интересов пользователя
+		return addUserInterests(ctx, user, tgx, userId)
+	})
+}
+
+// страница пользователей с id больше afterId в порядке id вместе с интересами,
+// хэш пароля не читается
+func (p *PostgresDB) GetUsers(ctx context.Context, afterId int, limit int) ([]entities.UserInfo, error) {
 
 	query := fmt.Sprintf(
-		`UPDATE %s 
-		 SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5 
-		 WHERE %s = $6`,
+		`SELECT u.%s, u.%s, u.%s, u.%s, u.%s, u.%s, 
+		 COALESCE(array_agg(i.%s ORDER BY ui.%s) FILTER (WHERE i.%s IS NOT NULL), '{}') 
+		 FROM %s u 
+		 LEFT JOIN %s ui ON ui.%s = u.%s 
+		 LEFT JOIN %s i ON i.%s = ui.%s 
+		 WHERE u.%s > $1 
+		 GROUP BY u.%s 
+		 ORDER BY u.%s 
+		 LIMIT $2`,
+		id, emailPole, usernamePole, describtionPole, agePole, isEmailVerifiedPole,
+		intersestPole, id, intersestPole,
 		usersTable,
-		usernamePole, emailPole, passwordPole, describtionPole, agePole,
+		userInterestsTable, userIdPole, id,
+		interestsTable, id, interestIdPole,
+		id,
+		id,
 		id,
 	)
 
-	if _, err := tgx.Exec(query,
-		user.Usrname, user.Email, user.Password, user.UsrDesc, user.UsrAge, userId,
-	); err != nil {
-		tgx.Rollback()
-		return err
+	ctx, cancel := withQueryTimeout(ctx, p.timeout)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, afterId, limit)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	//удание старых интересов пользователя
-	queryDeleteInterests := fmt.Sprintf(
-		`DELETE FROM %s WHERE %s = $1`,
-		userInterestsTable, userIdPole,
-	)
-	if _, err := tgx.Exec(queryDeleteInterests, userId); err != nil {
-		tgx.Rollback()
-		return err
+	users := make([]entities.UserInfo, 0, limit)
+	for rows.Next() {
+		var (
+			userDB    UserInfoForDB
+			interests []string
+		)
+
+		if err := rows.Scan(
+			&userDB.UsrId, &userDB.Email, &userDB.Usrname, &userDB.UsrDesc,
+			&userDB.UsrAge, &userDB.IsEmailValid, pq.Array(&interests),
+		); err != nil {
+			return nil, err
+		}
+
+		userInterests := make(entities.UserInterests, 0, len(interests))
+		for _, interest := range interests {
+			userInterests = append(userInterests, entities.UserInterest(interest))
+		}
 
+		users = append(users, entities.UserInfo{
+			UsrId:           userDB.UsrId,
+			Usrname:         userDB.Usrname,
+			Email:           userDB.Email,
+			UsrDesc:         entities.UserDiscription(userDB.UsrDesc),
+			UserInterests:   userInterests,
+			UsrAge:          entities.UserAge(userDB.UsrAge),
+			IsEmailVerified: userDB.IsEmailValid,
+		})
 	}
 
-	//добавление новых интересов пользователя
-	if _, err := addUserInterests(user, tgx, userId); err != nil {
-		tgx.Rollback()
-		return err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	tgx.Commit()
-	return nil
+	return users, nil
 }
 
-func addUserInterests(user *entities.UserInfo, trx *sql.Tx, userId int) (int, error) {
+func addUserInterests(ctx context.Context, user *entities.UserInfo, trx *sql.Tx, userId int) error {
 	for _, interest := range user.UserInterests {
 
 		var interestId int
@@ -303,11 +354,11 @@ func addUserInterests(user *entities.UserInfo, trx *sql.Tx, userId int) (int, er
 			intersestPole, id,
 		)
 		//выполняем запрос
-		row := trx.QueryRow(queryAddInterest, interest)
+		row := trx.QueryRowContext(ctx, queryAddInterest, interest)
 
 		//получем id интереса
 		if err := row.Scan(&interestId); err != nil {
-			return 0, fmt.Errorf("can't get interest id: %v", err)
+			return fmt.Errorf("can't get interest id: %w", err)
 		}
 
 		//формируем запрос для добавления новой записи в таблицу user_interests
@@ -317,9 +368,9 @@ func addUserInterests(user *entities.UserInfo, trx *sql.Tx, userId int) (int, er
 			userIdPole, interestIdPole,
 		)
 		//добавляем ид юзера и его интерес в таблицу user_interests
-		if _, err := trx.Exec(querryInterestAndUser, userId, interestId); err != nil {
-			return 0, err
+		if _, err := trx.ExecContext(ctx, querryInterestAndUser, userId, interestId); err != nil {
+			return err
 		}
 	}
-	return userId, nil
+	return nil
 }
@@ -13,6 +13,7 @@ type Repository interface {
 	GetUserByEmail(ctx context.Context, email string) (*entities.UserInfo, error)
 	VerifyCode(ctx context.Context, userId int, code string) (bool, error)
 	UpdateUser(ctx context.Context, userId int, user *entities.UserInfo) error
+	GetUsers(ctx context.Context, afterId int, limit int) ([]entities.UserInfo, error)
 }
 
 // имплементация Repository интерфейса
@@ -88,3 +89,15 @@ func (r *UserRepository) UpdateUser(ctx context.Context, userId int, user *entit
 
 	return nil
 }
+
+func (r *UserRepository) GetUsers(ctx context.Context, afterId int, limit int) ([]entities.UserInfo, error) {
+	fi := "repository.UserRepository.GetUsers"
+
+	users, err := r.relDB.GetUsers(ctx, afterId, limit)
+	if err != nil {
+		r.log.Error(fi + ": " + err.Error())
+		return nil, err
+	}
+
+	return users, nil
+}
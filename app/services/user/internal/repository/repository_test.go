@@ -65,6 +65,13 @@ func (m MockRelationDB) UpdateUser(ctx context.Context, userId int, user *entiti
 	return nil
 }
 
+func (m MockRelationDB) GetUsers(ctx context.Context, afterId int, limit int) ([]entities.UserInfo, error) {
+	if afterId < 0 {
+		return nil, errors.New("incorrect Id")
+	}
+	return []entities.UserInfo{{UsrId: afterId + 1}}, nil
+}
+
 func TestUserRepository_AddNewUser_CorrectCreditionals(t *testing.T) {
 
 	var logger *slog.Logger = slog.New(
@@ -179,3 +186,23 @@ func TestUserRepository_VerifyCode_IncorrectUserId(t *testing.T) {
 	assert.Error(t, err)
 	assert.False(t, isVErified)
 }
+
+func TestUserRepository_GetUsers_CorrectCreditionals(t *testing.T) {
+	var logger *slog.Logger = slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	repo := NewUserRepository(MockRelationDB{}, logger)
+	users, err := repo.GetUsers(context.Background(), 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.UserInfo{{UsrId: 2}}, users)
+}
+
+func TestUserRepository_GetUsers_IncorrectCreditionals(t *testing.T) {
+	var logger *slog.Logger = slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	repo := NewUserRepository(MockRelationDB{}, logger)
+	users, err := repo.GetUsers(context.Background(), -1, 10)
+	assert.Error(t, err)
+	assert.Nil(t, users)
+}
@@ -0,0 +1,45 @@
+// помощники транзакций и таймаутов запросов. Файл одинаковый во всех сервисах: сервисы - отдельные
+// go модули без общего пакета, поэтому изменения вносятся во все копии, тесты - в сервисе product
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// выполнение fn в транзакции, начатой с ctx: коммит, если fn вернула nil, иначе откат.
+// Транзакция откатывается и при панике в fn, отмена ctx прерывает запросы транзакции
+func WithTx(ctx context.Context, db *sqlx.DB, fn func(tx *sql.Tx) error) error {
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// контекст одного обращения к базе с таймаутом из конфигурации, 0 - без таймаута
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
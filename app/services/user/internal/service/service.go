@@ -11,6 +11,7 @@ type Service interface {
 	UserGetter
 	UserUpdator
 	CodeVerifactor
+	UserRepublisher
 }
 
 type UserCreator interface {
@@ -33,3 +34,7 @@ type MailSender interface {
 type CodeVerifactor interface {
 	VerifyCode(ctx context.Context, userId int, code string) (bool, error)
 }
+
+type UserRepublisher interface {
+	RepublishUsers(ctx context.Context, republish *entities.Republish, publish func(users []entities.UserInfo) error) error
+}
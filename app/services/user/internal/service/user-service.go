@@ -102,6 +102,42 @@ func (s *UserService) UpdateUser(ctx context.Context, userId int, user *entities
 	return nil
 }
 
+// функция передает в publish пачки пользователей после republish.FromId в порядке id,
+// не быстрее republish.Rate пользователей в секунду
+func (s *UserService) RepublishUsers(ctx context.Context, republish *entities.Republish, publish func(users []entities.UserInfo) error) error {
+	fi := "internal.User.RepublishUsers"
+
+	//пачка не больше числа пользователей в секунду, пауза между пачками выравнивает скорость
+	cursor, limit := republish.FromId, min(entities.RepublishBatchSize, republish.Rate)
+	ticker := time.NewTicker(time.Duration(limit) * time.Second / time.Duration(republish.Rate))
+	defer ticker.Stop()
+
+	for {
+		users, err := s.repo.GetUsers(ctx, cursor, limit)
+		if err != nil {
+			s.log.Error("%s: Error getting users: %v", fi, err)
+			return err
+		}
+
+		if len(users) != 0 {
+			if err := publish(users); err != nil {
+				return err
+			}
+		}
+
+		if len(users) < limit {
+			return nil
+		}
+		cursor = users[len(users)-1].UsrId
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 func generateCode() string {
 
 	rand.Seed(time.Now().UnixNano())
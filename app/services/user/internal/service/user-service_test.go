@@ -57,6 +57,18 @@ func (m *MockRepository) UpdateUser(ctx context.Context, userId int, user *entit
 	return nil
 }
 
+// мок отдает не больше трех пользователей после afterId
+func (m *MockRepository) GetUsers(ctx context.Context, afterId int, limit int) ([]entities.UserInfo, error) {
+	if afterId < 0 {
+		return nil, errors.New("incorrect Id")
+	}
+	users := make([]entities.UserInfo, 0)
+	for i := 1; i <= 3 && i <= limit; i++ {
+		users = append(users, entities.UserInfo{UsrId: afterId + i})
+	}
+	return users, nil
+}
+
 func TestUserService_CreateUser_Correct(t *testing.T) {
 	//Создаем сервис
 	service := NewUserService(&MockMailSender{}, &MockRepository{}, slog.New(
@@ -190,3 +202,45 @@ func TestUserService_UpdateUser_Incorrect(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestUserService_RepublishUsers_FromId(t *testing.T) {
+	//Создаем сервис
+	service := NewUserService(&MockMailSender{}, &MockRepository{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	ids := make([]int, 0)
+	err := service.RepublishUsers(context.Background(), &entities.Republish{FromId: 5, Rate: 1000},
+		func(users []entities.UserInfo) error {
+			for _, user := range users {
+				ids = append(ids, user.UsrId)
+			}
+			return nil
+		})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{6, 7, 8}, ids)
+}
+
+func TestUserService_RepublishUsers_Cancelled(t *testing.T) {
+	//Создаем сервис
+	service := NewUserService(&MockMailSender{}, &MockRepository{}, slog.New(
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	))
+
+	// при скорости 2 пользователя в секунду пачка - 2 пользователя, следующая пачка ждет паузы
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batches := 0
+	err := service.RepublishUsers(ctx, &entities.Republish{Rate: 2},
+		func(users []entities.UserInfo) error {
+			batches++
+			assert.Len(t, users, 2)
+			cancel()
+			return nil
+		})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, batches)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/gin-gonic/gin"
+)
+
+// прием события взаимодействия пользователя с продуктом (просмотр, клик, покупка),
+// событие только публикуется в топик взаимодействий, в базу сервиса оно не пишется
+func (h *UserHandler) collectEvent(c *gin.Context) {
+	var interaction entities.Interaction
+	fi := "api.Handler.collectEvent"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	//400 - ошибка десериализации данных
+	if err := c.BindJSON(&interaction); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400 - ошибка валидации данных
+	if err := interaction.ValidateInteraction(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//500 - ошибка публикации события
+	if err := h.kafka.SendInteraction(ctx, interaction); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	//202 - событие принято и будет обработано подписчиками асинхронно
+	c.AbortWithStatus(http.StatusAccepted)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserHandler_CollectEvent(t *testing.T) {
+	handler := NewHandler(
+		NewMockService(),
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		NewMockKafka(),
+	)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"Correct", `{"userId": 1, "productId": 2, "type": "view", "sessionId": "s1"}`, http.StatusAccepted},
+		{"CorrectWithWeightAndTime", `{"userId": 1, "productId": 2, "type": "purchase", "weight": 3.5, "timestamp": "2024-01-01T10:00:00Z"}`, http.StatusAccepted},
+		{"IncorrectJson", `{"userId": "one"}`, http.StatusBadRequest},
+		{"IncorrectMissingProduct", `{"userId": 1, "type": "view"}`, http.StatusBadRequest},
+		{"IncorrectType", `{"userId": 1, "productId": 2, "type": "like"}`, http.StatusBadRequest},
+		{"IncorrectWeight", `{"userId": 1, "productId": 2, "type": "click", "weight": -1}`, http.StatusBadRequest},
+		{"IncorrectFutureTime", `{"userId": 1, "productId": 2, "type": "click", "timestamp": "2999-01-01T10:00:00Z"}`, http.StatusBadRequest},
+		{"IncorrectKafkaError", `{"userId": 1, "productId": 2, "type": "click", "sessionId": "error"}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			var err error
+			c.Request, err = http.NewRequest("POST", "/events", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Error creating request: %v", err)
+			}
+
+			handler.collectEvent(c)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
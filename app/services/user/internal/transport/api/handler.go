@@ -61,7 +61,7 @@ func (h *UserHandler) signUpUser(c *gin.Context) {
 	}
 
 	usrInfo.UsrId = id
-	if err := h.kafka.SendMessage(usrInfo); err != nil {
+	if err := h.kafka.SendMessage(ctx, usrInfo); err != nil {
 		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -206,7 +206,7 @@ func (h *UserHandler) editUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.kafka.SendMessage(usrInfo); err != nil {
+	if err := h.kafka.SendMessage(ctx, usrInfo); err != nil {
 		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -71,6 +71,19 @@ func (m *MockService) VerifyCode(ctx context.Context, userId int, code string) (
 	return false, nil
 }
 
+// мок отдает две пачки по два пользователя после FromId, FromId 3 - ошибка сервера
+func (m *MockService) RepublishUsers(ctx context.Context, republish *entities.Republish, publish func(users []entities.UserInfo) error) error {
+	if republish.FromId == 3 {
+		return errors.New("внутренняя ошибка сервера")
+	}
+	for cursor := republish.FromId; cursor < republish.FromId+4; cursor += 2 {
+		if err := publish([]entities.UserInfo{{UsrId: cursor + 1}, {UsrId: cursor + 2}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func NewMockService() *MockService {
 	return &MockService{}
 }
@@ -78,11 +91,22 @@ func NewMockService() *MockService {
 // мок кафки
 type MockKafka struct{}
 
-func (m *MockKafka) SendMessage(usrInfo entities.UserInfo) error {
+func (m *MockKafka) SendMessage(ctx context.Context, usrInfo entities.UserInfo) error {
 	time.Sleep(5 * time.Microsecond)
 	return nil
 }
 
+func (m *MockKafka) SendSnapshots(ctx context.Context, usrInfos []entities.UserInfo) error {
+	return nil
+}
+
+func (m *MockKafka) SendInteraction(ctx context.Context, interaction entities.Interaction) error {
+	if interaction.SessionId == "error" {
+		return errors.New("some kafka error")
+	}
+	return nil
+}
+
 func (m *MockKafka) Close() error {
 	return nil
 }
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"os"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/transport/kafka/envelope"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,3 +20,36 @@ func newErrorResponse(c *gin.Context, statusCode int, message string) {
 		Reason: message,
 	})
 }
+
+// trace id запроса берется из заголовка X-Trace-Id или генерируется,
+// передается в заголовках событий кафки и возвращается клиенту
+func traceId(c *gin.Context) {
+	id := c.GetHeader(envelope.TraceIdHeader)
+	if id == "" {
+		id = envelope.NewId()
+	}
+
+	c.Set(envelope.TraceIdKey, id)
+	c.Header(envelope.TraceIdHeader, id)
+	c.Next()
+}
+
+// заголовок с токеном администратора, токен задается переменной окружения ADMIN_TOKEN
+const adminTokenHeader = "X-Admin-Token"
+
+// доступ только для администратора, если ADMIN_TOKEN не задан - админские запросы запрещены
+func (h *UserHandler) adminOnly(c *gin.Context) {
+	fi := "api.Handler.adminOnly"
+
+	token := os.Getenv("ADMIN_TOKEN")
+	given := c.GetHeader(adminTokenHeader)
+
+	//403 - токен не передан или неверен
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(given)) != 1 {
+		logMassage(fi, h.log, "admin token is missing or incorrect", http.StatusForbidden)
+		newErrorResponse(c, http.StatusForbidden, "admin token is missing or incorrect")
+		return
+	}
+
+	c.Next()
+}
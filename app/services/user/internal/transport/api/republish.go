@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/gin-gonic/gin"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// повторная публикация снимков всех пользователей, в ответ построчно (ndjson) возвращается
+// прогресс после каждой пачки, при обрыве публикацию можно продолжить с fromId = lastId
+func (h *UserHandler) republishUsers(c *gin.Context) {
+	fi := "api.Handler.republishUsers"
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	var (
+		republish entities.Republish
+		err       error
+	)
+
+	//400 - некорректный fromId (не число)
+	if republish.FromId, err = strconv.Atoi(c.DefaultQuery("fromId", "0")); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400 - некорректная скорость (не число)
+	if republish.Rate, err = strconv.Atoi(c.DefaultQuery("rate", "0")); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	//400 - ошибка валидации параметров
+	if err := republish.ValidateRepublish(); err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusBadRequest)
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//200 - дальше ошибка возвращается в последней строке прогресса
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	progress := entities.RepublishProgress{LastId: republish.FromId}
+	err = h.service.RepublishUsers(ctx, &republish, func(users []entities.UserInfo) error {
+		if err := h.kafka.SendSnapshots(ctx, users); err != nil {
+			return err
+		}
+
+		progress.Published += len(users)
+		progress.LastId = users[len(users)-1].UsrId
+		encoder.Encode(progress)
+		c.Writer.Flush()
+		return nil
+	})
+
+	if err != nil {
+		logMassage(fi, h.log, err.Error(), http.StatusInternalServerError)
+		progress.Error = err.Error()
+	} else {
+		progress.Done = true
+	}
+	encoder.Encode(progress)
+	c.Writer.Flush()
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeRepublishProgress(t *testing.T, body *bytes.Buffer) []entities.RepublishProgress {
+	progress := make([]entities.RepublishProgress, 0)
+	decoder := json.NewDecoder(body)
+	for decoder.More() {
+		var p entities.RepublishProgress
+		if err := decoder.Decode(&p); err != nil {
+			t.Fatalf("Error decoding progress: %v", err)
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
+
+func TestUserHandler_RepublishUsers_Correct(t *testing.T) {
+	handler := NewHandler(
+		NewMockService(),
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		NewMockKafka(),
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/user/republish?fromId=10&rate=100", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.republishUsers(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	progress := decodeRepublishProgress(t, w.Body)
+	assert.Equal(t, []entities.RepublishProgress{
+		{Published: 2, LastId: 12},
+		{Published: 4, LastId: 14},
+		{Published: 4, LastId: 14, Done: true},
+	}, progress)
+}
+
+func TestUserHandler_RepublishUsers_CorrectButInternalError(t *testing.T) {
+	handler := NewHandler(
+		NewMockService(),
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		NewMockKafka(),
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/user/republish?fromId=3", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.republishUsers(c)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	// последняя строка содержит ошибку и id, с которого можно продолжить
+	progress := decodeRepublishProgress(t, w.Body)
+	assert.Equal(t, 1, len(progress))
+	assert.Equal(t, 3, progress[0].LastId)
+	assert.Equal(t, false, progress[0].Done)
+	assert.NotEmpty(t, progress[0].Error)
+}
+
+func TestUserHandler_RepublishUsers_IncorrectRate(t *testing.T) {
+	handler := NewHandler(
+		NewMockService(),
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		NewMockKafka(),
+	)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	var err error
+	c.Request, err = http.NewRequest("POST", "/admin/user/republish?rate=-1", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	handler.republishUsers(c)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestUserHandler_RepublishUsers_WithoutAdminToken(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "secret")
+
+	handler := NewHandler(
+		NewMockService(),
+		slog.New(
+			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		),
+		NewMockKafka(),
+	)
+	router := handler.InitRoutes()
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/admin/user/republish", nil)
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	req.Header.Set(adminTokenHeader, "wrong")
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+}
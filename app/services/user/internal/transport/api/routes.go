@@ -4,6 +4,9 @@ import "github.com/gin-gonic/gin"
 
 func (h *UserHandler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	//контекст обработчика отменяется вместе с запросом клиента, это прерывает и запросы к базе
+	router.ContextWithFallback = true
+	router.Use(traceId)
 
 	user := router.Group("/user")
 
@@ -35,5 +38,15 @@ func (h *UserHandler) InitRoutes() *gin.Engine {
 		}
 	}
 
+	// POST events - события взаимодействия пользователей с продуктами
+	router.POST("/events", h.collectEvent)
+
+	// admin, запросы с заголовком X-Admin-Token
+	admin := router.Group("/admin", h.adminOnly)
+	{
+		// POST admin/user/republish
+		admin.POST("/user/republish", h.republishUsers)
+	}
+
 	return router
 }
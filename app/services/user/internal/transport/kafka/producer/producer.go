@@ -1,19 +1,31 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/transport/kafka/envelope"
 	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/transport/kafka/pb"
 	"github.com/IBM/sarama"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// имя сервиса в заголовке producer сообщений
+const producerName = "user"
+
+// топик взаимодействий пользователей с продуктами, если не задан KAFKA_INTERACTIONS_TOPIC
+const defaultInteractionsTopic = "user_interactions"
+
 type Producer interface {
-	SendMessage(usrInfo entities.UserInfo) error
+	SendMessage(ctx context.Context, usrInfo entities.UserInfo) error
+	SendSnapshots(ctx context.Context, usrInfos []entities.UserInfo) error
+	SendInteraction(ctx context.Context, interaction entities.Interaction) error
 	Close() error
 }
 
@@ -43,7 +55,35 @@ func InitConfig(brokerAdressses []string) *sarama.Config {
 	return config
 }
 
-func (p *KafkaProducer) SendMessage(usrInfo entities.UserInfo) error {
+func (p *KafkaProducer) SendMessage(ctx context.Context, usrInfo entities.UserInfo) error {
+	topic := os.Getenv("KAFKA_TOPIC")
+
+	if topic == "" {
+		p.log.Error("KAFKA_TOPIC not set")
+		return fmt.Errorf("environment KAFKA_TOPIC not set")
+	}
+
+	message, err := newUserMessage(ctx, topic, usrInfo, envelope.EventUserUpdated)
+	if err != nil {
+		return err
+	}
+
+	partition, offset, err := p.Producer.SendMessage(message)
+
+	if err != nil {
+		p.log.Error(err.Error())
+		return err
+	} else {
+		p.log.Info(fmt.Sprintf(
+			"Message is sent to topic %s, partition %d, offset %d", topic, partition, offset,
+		))
+	}
+
+	return nil
+}
+
+// отправка снимков пользователей (событие user.snapshot) одной пачкой
+func (p *KafkaProducer) SendSnapshots(ctx context.Context, usrInfos []entities.UserInfo) error {
 	topic := os.Getenv("KAFKA_TOPIC")
 
 	if topic == "" {
@@ -51,6 +91,26 @@ func (p *KafkaProducer) SendMessage(usrInfo entities.UserInfo) error {
 		return fmt.Errorf("environment KAFKA_TOPIC not set")
 	}
 
+	messages := make([]*sarama.ProducerMessage, 0, len(usrInfos))
+	for _, usrInfo := range usrInfos {
+		message, err := newUserMessage(ctx, topic, usrInfo, envelope.EventUserSnapshot)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := p.Producer.SendMessages(messages); err != nil {
+		p.log.Error(err.Error())
+		return err
+	}
+	p.log.Info(fmt.Sprintf("%d messages are sent to topic %s", len(messages), topic))
+
+	return nil
+}
+
+// сообщение о пользователе в общем конверте, ключ сообщения - id пользователя
+func newUserMessage(ctx context.Context, topic string, usrInfo entities.UserInfo, eventType string) (*sarama.ProducerMessage, error) {
 	uinterests := make([]string, len(usrInfo.UserInterests))
 
 	for _, elem := range usrInfo.UserInterests {
@@ -64,26 +124,69 @@ func (p *KafkaProducer) SendMessage(usrInfo entities.UserInfo) error {
 
 	data, err := proto.Marshal(&userMassage)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	partition, offset, err := p.Producer.SendMessage(&sarama.ProducerMessage{
-		Topic: topic,
-		Value: sarama.ByteEncoder(data),
-	})
+	env := envelope.New(ctx, strconv.Itoa(usrInfo.UsrId), eventType, envelope.UserSchemaVersion, producerName)
+	return env.Message(topic, data), nil
+}
+
+// отправка события взаимодействия, ключ сообщения - id пользователя,
+// так события одного пользователя читаются в порядке отправки
+func (p *KafkaProducer) SendInteraction(ctx context.Context, interaction entities.Interaction) error {
+	topic := os.Getenv("KAFKA_INTERACTIONS_TOPIC")
+	if topic == "" {
+		topic = defaultInteractionsTopic
+	}
+
+	message, err := newInteractionMessage(ctx, topic, interaction)
+	if err != nil {
+		return err
+	}
 
+	partition, offset, err := p.Producer.SendMessage(message)
 	if err != nil {
 		p.log.Error(err.Error())
 		return err
-	} else {
-		p.log.Info(fmt.Sprintf(
-			"Message is sent to topic %s, partition %d, offset %d", topic, partition, offset,
-		))
 	}
+	p.log.Info(fmt.Sprintf(
+		"Interaction is sent to topic %s, partition %d, offset %d", topic, partition, offset,
+	))
 
 	return nil
 }
 
+func newInteractionMessage(ctx context.Context, topic string, interaction entities.Interaction) (*sarama.ProducerMessage, error) {
+	interactionMessage := myproto.Interaction{
+		UserId:    int64(interaction.UserId),
+		ProductId: int64(interaction.ProductId),
+		Type:      interactionType(interaction.Type),
+		Weight:    interaction.Weight,
+		Timestamp: timestamppb.New(interaction.Timestamp),
+		SessionId: interaction.SessionId,
+	}
+
+	data, err := proto.Marshal(&interactionMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	env := envelope.New(ctx, strconv.Itoa(interaction.UserId), envelope.EventInteractionCreated, envelope.InteractionSchemaVersion, producerName)
+	return env.Message(topic, data), nil
+}
+
+func interactionType(interactionType string) myproto.InteractionType {
+	switch interactionType {
+	case entities.InteractionView:
+		return myproto.InteractionType_VIEW
+	case entities.InteractionClick:
+		return myproto.InteractionType_CLICK
+	case entities.InteractionPurchase:
+		return myproto.InteractionType_PURCHASE
+	}
+	return myproto.InteractionType_INTERACTION_UNSPECIFIED
+}
+
 func (p *KafkaProducer) Close() error {
 	err := p.Producer.Close()
 	if err != nil {
@@ -1,12 +1,17 @@
 package kafka
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/entities"
+	"github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/transport/kafka/envelope"
+	myproto "github.com/AndroSaal/RecommendationsForUsers/app/services/user/internal/transport/kafka/pb"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestKafka_ConnectToKafka_Correct(t *testing.T) {
@@ -68,7 +73,7 @@ func TestKafka_TestSendMessage_Correct(t *testing.T) {
 		}
 	}()
 
-	err := Producer.SendMessage(userInfo)
+	err := Producer.SendMessage(context.Background(), userInfo)
 	assert.NoError(t, err)
 }
 
@@ -94,6 +99,50 @@ func TestKafka_SendMessage_Incorrect(t *testing.T) {
 		}
 	}()
 
-	err := Producer.SendMessage(userInfo)
+	err := Producer.SendMessage(context.Background(), userInfo)
 	assert.Error(t, err)
 }
+
+func TestKafka_NewUserMessage_Snapshot(t *testing.T) {
+	message, err := newUserMessage(context.Background(), "user_updates", entities.UserInfo{UsrId: 7}, envelope.EventUserSnapshot)
+	assert.NoError(t, err)
+
+	key, err := message.Key.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, "7", string(key))
+
+	headers := make(map[string]string)
+	for _, header := range message.Headers {
+		headers[string(header.Key)] = string(header.Value)
+	}
+	assert.Equal(t, envelope.EventUserSnapshot, headers[envelope.HeaderEventType])
+	assert.Equal(t, "user", headers[envelope.HeaderProducer])
+}
+
+func TestKafka_NewInteractionMessage_Correct(t *testing.T) {
+	message, err := newInteractionMessage(context.Background(), "user_interactions", entities.Interaction{
+		UserId: 7, ProductId: 3, Type: entities.InteractionPurchase, Weight: 5,
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), SessionId: "s1",
+	})
+	assert.NoError(t, err)
+
+	key, err := message.Key.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, "7", string(key))
+
+	headers := make(map[string]string)
+	for _, header := range message.Headers {
+		headers[string(header.Key)] = string(header.Value)
+	}
+	assert.Equal(t, envelope.EventInteractionCreated, headers[envelope.HeaderEventType])
+
+	data, err := message.Value.Encode()
+	assert.NoError(t, err)
+	var interaction myproto.Interaction
+	assert.NoError(t, proto.Unmarshal(data, &interaction))
+	assert.Equal(t, int64(3), interaction.ProductId)
+	assert.Equal(t, myproto.InteractionType_PURCHASE, interaction.Type)
+	assert.Equal(t, float64(5), interaction.Weight)
+	assert.Equal(t, "s1", interaction.SessionId)
+	assert.Equal(t, int64(1704067200), interaction.Timestamp.Seconds)
+}
@@ -26,6 +26,8 @@ type DBConfig struct {
 	Port     string `yaml:"port"`
 	Dbname   string `yaml:"dbname"`
 	Sslmode  string `yaml:"sslmode"`
+	//таймаут одного обращения к базе (запроса или транзакции), 0 - без таймаута
+	QueryTimeout time.Duration `yaml:"queryTimeout"`
 }
 
 // конфигурация сервера